@@ -7,10 +7,10 @@ import (
 )
 
 func main() {
-	// 初始化 TransactionHandler
-	transactionHandler, err := di.InitializeTransactionHandler()
+	// 初始化 API 路由（包含所有 API 處理層）
+	r, err := di.InitializeRouter()
 	if err != nil {
-		log.Fatalf("Failed to initialize transaction handler: %v", err)
+		log.Fatalf("Failed to initialize router: %v", err)
 	}
 
 	// 初始化 MessageHandler
@@ -19,14 +19,22 @@ func main() {
 		log.Fatalf("Failed to initialize message handler: %v", err)
 	}
 
+	// 初始化報表定期寄送排程
+	reportScheduler, err := di.InitializeReportScheduler()
+	if err != nil {
+		log.Fatalf("Failed to initialize report scheduler: %v", err)
+	}
+
 	// 啟動 HTTP 伺服器
-	r := transactionHandler.SetupRouter() // 根據 transactionHandler 設定路由
 	go func() {
 		if err := r.Run(":8080"); err != nil {
 			log.Fatalf("Failed to run server: %v", err)
 		}
 	}()
 
+	// 啟動報表寄送排程，每分鐘檢查到期的訂閱與待重試的寄送
+	reportScheduler.Start()
+
 	// 啟動 RabbitMQ 消費者，由 handler 負責整個消費和處理流程
 	messageHandler.Start()
 
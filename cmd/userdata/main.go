@@ -0,0 +1,78 @@
+// cmd/userdata/main.go
+package main
+
+import (
+	"fintrack/internal/di"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+const usage = `用法:
+  userdata export -user <user_id> [-out <file.zip>]
+  userdata import -user <user_id> -in <file.zip>`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	userID := flags.String("user", "", "用戶 ID")
+	out := flags.String("out", "", "匯出的封存檔路徑，預設為 <user_id>.zip")
+	in := flags.String("in", "", "要匯入的封存檔路徑")
+	_ = flags.Parse(os.Args[2:])
+	if *userID == "" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	svc, err := di.InitializePortabilityService()
+	if err != nil {
+		log.Fatalf("Failed to initialize portability service: %v", err)
+	}
+
+	switch os.Args[1] {
+	case "export":
+		path := *out
+		if path == "" {
+			path = *userID + ".zip"
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", path, err)
+		}
+		defer f.Close()
+
+		manifest, err := svc.ExportUser(*userID, f)
+		if err != nil {
+			log.Fatalf("Failed to export user data: %v", err)
+		}
+		log.Printf("Exported %s to %s: %v", *userID, path, manifest.Files)
+	case "import":
+		if *in == "" {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		f, err := os.Open(*in)
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", *in, err)
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			log.Fatalf("Failed to stat %s: %v", *in, err)
+		}
+
+		summary, err := svc.ImportUser(f, info.Size(), *userID)
+		if err != nil {
+			log.Fatalf("Failed to import user data: %v", err)
+		}
+		log.Printf("Imported %s into %s (remapped ids: %t): %v", summary.SourceUserID, summary.UserID, summary.RemappedIDs, summary.Files)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
@@ -13,6 +13,7 @@ type Config struct {
 	DSN            string
 	RabbitMQConfig RabbitMQConfig
 	RedisConfig    RedisConfig
+	SMTPConfig     SMTPConfig
 }
 
 // RabbitMQConfig 包含 RabbitMQ 的相關配置
@@ -28,6 +29,15 @@ type RedisConfig struct {
 	DB       int
 }
 
+// SMTPConfig 包含寄送報表郵件的 SMTP 配置，未設定帳號時不進行認證
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
 // init 函數用於設置環境變量和讀取配置文件
 func init() {
 	// 設置默認時區
@@ -64,5 +74,12 @@ func NewConfig() *Config {
 			Password: viper.GetString("REDIS_PASS"),
 			DB:       viper.GetInt("REDIS_DB"),
 		},
+		SMTPConfig: SMTPConfig{
+			Host:     viper.GetString("SMTP_HOST"),
+			Port:     viper.GetInt("SMTP_PORT"),
+			Username: viper.GetString("SMTP_USER"),
+			Password: viper.GetString("SMTP_PASS"),
+			From:     viper.GetString("SMTP_FROM"),
+		},
 	}
 }
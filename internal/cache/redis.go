@@ -2,17 +2,23 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fintrack/config"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// ErrNotFound 表示快取中沒有該鍵
+var ErrNotFound = errors.New("cache: key not found")
+
 // 定義緩存接口
 type Cache interface {
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
 	Get(ctx context.Context, key string) (string, error)
 	Delete(ctx context.Context, key string) error
+	Incr(ctx context.Context, key string) (int64, error)
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error)
 	Close() error
 }
 
@@ -40,14 +46,29 @@ func (r *Redis) Set(ctx context.Context, key string, value interface{}, expirati
 	return r.client.Set(ctx, key, value, expiration).Err()
 }
 
+// Get 讀取快取，鍵不存在時返回 ErrNotFound
 func (r *Redis) Get(ctx context.Context, key string) (string, error) {
-	return r.client.Get(ctx, key).Result()
+	value, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	return value, err
 }
 
 func (r *Redis) Delete(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
 }
 
+// Incr 將計數器加一並返回新值，鍵不存在時從 0 開始
+func (r *Redis) Incr(ctx context.Context, key string) (int64, error) {
+	return r.client.Incr(ctx, key).Result()
+}
+
+// SetNX 只在鍵不存在時寫入，返回是否寫入成功
+func (r *Redis) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, expiration).Result()
+}
+
 func (r *Redis) Close() error {
 	return r.client.Close()
 }
@@ -43,6 +43,18 @@ func TestRedisGet(t *testing.T) {
 	mock.ExpectationsWereMet()
 }
 
+func TestRedisGetMissing(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	redisCache := &Redis{client: db}
+
+	// 設置模擬行為，當調用 GET 時返回鍵不存在
+	mock.ExpectGet("missing_key").RedisNil()
+
+	_, err := redisCache.Get(context.Background(), "missing_key")
+	assert.ErrorIs(t, err, ErrNotFound)
+	mock.ExpectationsWereMet()
+}
+
 func TestRedisDelete(t *testing.T) {
 	db, mock := redismock.NewClientMock()
 	redisCache := &Redis{client: db}
@@ -58,6 +70,42 @@ func TestRedisDelete(t *testing.T) {
 	mock.ExpectationsWereMet()
 }
 
+func TestRedisIncr(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	redisCache := &Redis{client: db}
+
+	ctx := context.Background()
+	key := "test_counter"
+
+	// 設置模擬行為，當調用 INCR 時返回加一後的值
+	mock.ExpectIncr(key).SetVal(3)
+
+	value, err := redisCache.Incr(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), value)
+	mock.ExpectationsWereMet()
+}
+
+func TestRedisSetNX(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	redisCache := &Redis{client: db}
+
+	ctx := context.Background()
+	key := "test_lock"
+
+	// 第一次寫入成功，鍵已存在時不覆寫
+	mock.ExpectSetNX(key, "a", time.Minute).SetVal(true)
+	mock.ExpectSetNX(key, "b", time.Minute).SetVal(false)
+
+	ok, err := redisCache.SetNX(ctx, key, "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = redisCache.SetNX(ctx, key, "b", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestRedisClose(t *testing.T) {
 	db, mock := redismock.NewClientMock()
 	redisCache := &Redis{client: db}
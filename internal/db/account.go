@@ -0,0 +1,98 @@
+package db
+
+import (
+	"errors"
+	"fintrack/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+// ErrAccountNotFound 表示查詢的帳戶不存在
+var ErrAccountNotFound = errors.New("account not found")
+
+// incomeCondition 判斷交易是否為收入（未指定類型時依分類判斷）
+const incomeCondition = "type = 'INCOME' OR (type = '' AND category = 'INCOME')"
+
+// signedAmountExpr 將交易金額轉為帶正負號的值，收入為正、支出為負
+const signedAmountExpr = "CASE WHEN " + incomeCondition + " THEN amount ELSE -amount END"
+
+// SaveAccount 保存帳戶
+func (c *MySQLClient) SaveAccount(account entity.Account) error {
+	return c.DB.Create(&account).Error
+}
+
+// GetAccounts 查詢用戶的所有帳戶
+func (c *MySQLClient) GetAccounts(userID string) ([]entity.Account, error) {
+	var accounts []entity.Account
+	err := c.DB.Where("user_id = ?", userID).Order("created_at").Find(&accounts).Error
+	return accounts, err
+}
+
+// GetAccountByID 根據帳戶 ID 查詢帳戶
+func (c *MySQLClient) GetAccountByID(accountID string) (*entity.Account, error) {
+	var account entity.Account
+	err := c.DB.Where("id = ?", accountID).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// GetAccountBalances 彙總指定日期之前各帳戶的交易淨額（不含期初餘額），未指定帳戶的交易歸於空字串
+func (c *MySQLClient) GetAccountBalances(userID, before string) (map[string]float64, error) {
+	return c.sumByAccount(c.DB.Where("user_id = ? AND date < ?", userID, before))
+}
+
+// GetAccountActivity 彙總期間 [from, before) 內各帳戶的交易淨額，未指定帳戶的交易歸於空字串
+func (c *MySQLClient) GetAccountActivity(userID, from, before string) (map[string]float64, error) {
+	return c.sumByAccount(c.DB.Where("user_id = ? AND date >= ? AND date < ?", userID, from, before))
+}
+
+func (c *MySQLClient) sumByAccount(query *gorm.DB) (map[string]float64, error) {
+	var rows []struct {
+		AccountID string
+		Balance   float64
+	}
+	err := query.Model(&entity.Transaction{}).
+		Select("account_id, SUM(" + signedAmountExpr + ") AS balance").
+		Group("account_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		balances[row.AccountID] = row.Balance
+	}
+	return balances, nil
+}
+
+// SaveInstallmentPlan 保存分期付款計畫
+func (c *MySQLClient) SaveInstallmentPlan(plan entity.InstallmentPlan) error {
+	return c.DB.Create(&plan).Error
+}
+
+// GetInstallmentPlans 查詢帳戶的分期付款計畫
+func (c *MySQLClient) GetInstallmentPlans(accountID string) ([]entity.InstallmentPlan, error) {
+	var plans []entity.InstallmentPlan
+	err := c.DB.Where("account_id = ?", accountID).Order("purchase_date").Find(&plans).Error
+	return plans, err
+}
+
+// DeleteInstallmentPlan 刪除分期付款計畫，用於子交易送出失敗時撤銷計畫
+func (c *MySQLClient) DeleteInstallmentPlan(planID string) error {
+	return c.DB.Delete(&entity.InstallmentPlan{}, "id = ?", planID).Error
+}
+
+// GetStatementTransactions 查詢某期帳單的交易，包含尚未指定帳單週期但落在消費期間內的舊交易
+func (c *MySQLClient) GetStatementTransactions(accountID, cycle, periodStart, periodEnd string) ([]entity.Transaction, error) {
+	var transactions []entity.Transaction
+	err := c.DB.Where("account_id = ? AND (statement_cycle = ? OR (statement_cycle = '' AND date >= ? AND date < ?))", accountID, cycle, periodStart, periodEnd).
+		Order("date").
+		Find(&transactions).Error
+	return transactions, err
+}
@@ -3,17 +3,82 @@ package db
 import (
 	"errors"
 	"fintrack/internal/entity"
+	"strings"
+	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
+// ErrTransactionNotFound 表示查詢的交易不存在
+var ErrTransactionNotFound = errors.New("transaction not found")
+
 // DBClient 定義資料庫客戶端接口
 type DBClient interface {
 	SaveTransaction(tx entity.Transaction) error
-	GetFilteredTransactions(userID, category, startDate, endDate string, page, pageSize int) ([]entity.Transaction, error)
+	GetFilteredTransactions(query TransactionQuery) ([]entity.Transaction, error)
+	CountFilteredTransactions(query TransactionQuery) (int64, error)
+	SearchTransactions(query TransactionQuery, text string) ([]TransactionMatch, error)
 	GetTransactions(userID, startDate, endDate string) ([]entity.Transaction, error)
+	GetTransactionByID(txID string) (*entity.Transaction, error)
+	UpdateTransaction(tx entity.Transaction) error
 	DeleteTransactionByID(txID string) error
+	GetCategoryTotals(userID, startDate, endDate string, bucket Bucket) ([]CategoryTotal, error)
+	GetTopPayees(userID, startDate, endDate string, bucket Bucket, limit int) ([]PayeeTotal, error)
+	GetLargestTransactions(userID, startDate, endDate string, bucket Bucket, limit int) ([]entity.Transaction, error)
+
+	SaveRecurringSchedule(schedule entity.RecurringSchedule) error
+	GetRecurringSchedules(userID string) ([]entity.RecurringSchedule, error)
+
+	SaveAccount(account entity.Account) error
+	GetAccounts(userID string) ([]entity.Account, error)
+	GetAccountByID(accountID string) (*entity.Account, error)
+	GetAccountBalances(userID, before string) (map[string]float64, error)
+	GetAccountActivity(userID, from, before string) (map[string]float64, error)
+	SaveInstallmentPlan(plan entity.InstallmentPlan) error
+	GetInstallmentPlans(accountID string) ([]entity.InstallmentPlan, error)
+	DeleteInstallmentPlan(planID string) error
+	GetStatementTransactions(accountID, cycle, periodStart, periodEnd string) ([]entity.Transaction, error)
+
+	SaveLoan(loan entity.Loan) error
+	GetLoans(userID string) ([]entity.Loan, error)
+	GetLoanByID(loanID string) (*entity.Loan, error)
+	SaveLoanPayment(payment entity.LoanPayment) error
+	DeleteLoanPayment(paymentID string) error
+	GetLoanPayments(loanID string) ([]entity.LoanPayment, error)
+
+	SaveSecurity(security entity.Security) error
+	GetSecurities() ([]entity.Security, error)
+	GetSecurity(symbol string) (*entity.Security, error)
+	SaveTrade(trade entity.Trade) error
+	GetTrades(userID, before string) ([]entity.Trade, error)
+	GetTradeUserIDs(symbols []string) ([]string, error)
+	SavePrices(prices []entity.SecurityPrice) error
+	GetLatestPrices(symbols []string, asOf string) (map[string]entity.SecurityPrice, error)
+
+	SaveNetWorthSnapshots(snapshots []entity.NetWorthSnapshot) error
+	GetNetWorthSnapshots(userID, from, to string) ([]entity.NetWorthSnapshot, error)
+	GetLatestNetWorthSnapshot(userID, before string) ([]entity.NetWorthSnapshot, error)
+	DeleteNetWorthSnapshots(userID, from string) error
+
+	SaveSubscription(subscription entity.ReportSubscription) error
+	GetSubscriptions(userID string) ([]entity.ReportSubscription, error)
+	GetSubscriptionByID(subscriptionID string) (*entity.ReportSubscription, error)
+	DeleteSubscription(subscriptionID string) error
+	GetDueSubscriptions(now time.Time) ([]entity.ReportSubscription, error)
+	CreateDeliveries(deliveries []entity.ReportDelivery) error
+	SaveDelivery(delivery entity.ReportDelivery) error
+	GetPendingDeliveries(now time.Time, limit int) ([]entity.ReportDelivery, error)
+	GetDeliveries(subscriptionID string) ([]entity.ReportDelivery, error)
+
+	GetTaxMappings(userID string) ([]entity.TaxMapping, error)
+	ReplaceTaxMappings(userID string, mappings []entity.TaxMapping) error
+
+	GetUserSettings(userID string) (*entity.UserSettings, error)
+	SaveUserSettings(settings entity.UserSettings) error
+
+	GetUserData(userID string) (*UserData, error)
+	RestoreUserData(userID string, data *UserData) error
 }
 
 // MySQLClient 實現 DBClient 接口
@@ -34,12 +99,16 @@ func NewMySQLClient(dsn string) (*MySQLClient, error) {
 	}
 
 	// 自動遷移數據庫模型
-	err = db.AutoMigrate(&entity.Transaction{})
+	err = db.AutoMigrate(&entity.Transaction{}, &entity.RecurringSchedule{}, &entity.Account{}, &entity.InstallmentPlan{}, &entity.Loan{}, &entity.LoanPayment{}, &entity.Security{}, &entity.Trade{}, &entity.SecurityPrice{}, &entity.NetWorthSnapshot{}, &entity.ReportSubscription{}, &entity.ReportDelivery{}, &entity.TaxMapping{}, &entity.UserSettings{})
 	if err != nil {
 		return nil, err
 	}
 
-	return &MySQLClient{DB: db}, nil
+	client := &MySQLClient{DB: db}
+	if err := client.CheckTimeZoneSupport(); err != nil {
+		return nil, err
+	}
+	return client, nil
 }
 
 // SaveTransactions 批量保存交易紀錄
@@ -47,23 +116,121 @@ func (c *MySQLClient) SaveTransaction(tx entity.Transaction) error {
 	return c.DB.Create(&tx).Error
 }
 
-// 查詢交易數據，支持分頁、篩選
-func (c *MySQLClient) GetFilteredTransactions(userID, category, startDate, endDate string, page, pageSize int) ([]entity.Transaction, error) {
-	var transactions []entity.Transaction
-	query := c.DB.Where("user_id = ?", userID)
+// 交易列表可排序的欄位
+const (
+	SortByDate     = "date"
+	SortByAmount   = "amount"
+	SortByCategory = "category"
+	SortByPayee    = "payee"
+)
+
+// TransactionQuery 表示交易列表的篩選、排序與 keyset 分頁條件，排序值相同時依 ID 排序。
+// 多值的篩選符合任一值即可，標籤須全部符合；空值或 nil 表示不篩選
+type TransactionQuery struct {
+	UserID      string
+	Categories  []string
+	StartDate   string // 只指定一端時為開放範圍
+	EndDate     string
+	MinAmount   *float64
+	MaxAmount   *float64
+	Sources     []string
+	Reconciled  *bool
+	AccountID   string
+	Payee       string
+	Tags        []string
+	Description string // 說明包含此字串
+
+	SortField  string          // SortByDate 等欄位，未指定時依日期
+	Descending bool            // 排序方向，ID 與排序欄位同方向
+	After      *TransactionKey // 上一頁最後一筆的位置，nil 時從第一筆開始
+	Limit      int
+}
+
+// TransactionKey 表示 keyset 分頁的位置：排序欄位的值與交易 ID
+type TransactionKey struct {
+	Value interface{}
+	ID    string
+}
+
+// likeEscaper 跳脫 LIKE 的萬用字元，使說明以字面比對
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
 
-	if category != "" {
-		query = query.Where("category = ?", category)
+// filter 套用篩選條件，不包含分頁位置
+func (q TransactionQuery) filter(db *gorm.DB) *gorm.DB {
+	db = db.Where("user_id = ?", q.UserID)
+	if len(q.Categories) > 0 {
+		db = db.Where("category IN ?", q.Categories)
+	}
+	if q.StartDate != "" {
+		db = db.Where("date >= ?", q.StartDate)
+	}
+	if q.EndDate != "" {
+		db = db.Where("date <= ?", q.EndDate)
+	}
+	if q.MinAmount != nil {
+		db = db.Where("amount >= ?", *q.MinAmount)
+	}
+	if q.MaxAmount != nil {
+		db = db.Where("amount <= ?", *q.MaxAmount)
 	}
-	if startDate != "" && endDate != "" {
-		query = query.Where("date BETWEEN ? AND ?", startDate, endDate)
+	if len(q.Sources) > 0 {
+		db = db.Where("source IN ?", q.Sources)
+	}
+	if q.Reconciled != nil {
+		db = db.Where("reconciled = ?", *q.Reconciled)
+	}
+	if q.AccountID != "" {
+		db = db.Where("account_id = ?", q.AccountID)
+	}
+	if q.Payee != "" {
+		db = db.Where("payee = ?", q.Payee)
+	}
+	for _, tag := range q.Tags {
+		// 標籤以逗號分隔，兩邊都去除空白後再比對
+		db = db.Where("FIND_IN_SET(?, REPLACE(tags, ' ', '')) > 0", strings.ReplaceAll(tag, " ", ""))
+	}
+	if q.Description != "" {
+		db = db.Where("description LIKE ?", "%"+likeEscaper.Replace(q.Description)+"%")
+	}
+	return db
+}
+
+// sortColumn 返回排序欄位，只允許固定的欄位名稱
+func (q TransactionQuery) sortColumn() string {
+	switch q.SortField {
+	case SortByAmount, SortByCategory:
+		return q.SortField
+	case SortByPayee:
+		// 舊資料的 payee 為 NULL，視為空字串才能與游標比較
+		return "COALESCE(payee, '')"
+	default:
+		return SortByDate
+	}
+}
+
+// 查詢交易數據，支持篩選、排序與 keyset 分頁，不使用 OFFSET 以避免深分頁變慢
+func (c *MySQLClient) GetFilteredTransactions(q TransactionQuery) ([]entity.Transaction, error) {
+	var transactions []entity.Transaction
+	column, op, direction := q.sortColumn(), ">", "ASC"
+	if q.Descending {
+		op, direction = "<", "DESC"
 	}
 
-	offset := (page - 1) * pageSize
-	err := query.Offset(offset).Limit(pageSize).Find(&transactions).Error
+	query := q.filter(c.DB)
+	if q.After != nil {
+		query = query.Where(column+" "+op+" ? OR ("+column+" = ? AND id "+op+" ?)", q.After.Value, q.After.Value, q.After.ID)
+	}
+	err := query.Order(column + " " + direction + ", id " + direction).Limit(q.Limit).Find(&transactions).Error
 	return transactions, err
 }
 
+// CountFilteredTransactions 返回符合篩選條件的交易總數
+func (c *MySQLClient) CountFilteredTransactions(q TransactionQuery) (int64, error) {
+	var count int64
+	err := q.filter(c.DB.Model(&entity.Transaction{})).Count(&count).Error
+	return count, err
+}
+
 // 查詢指定範圍內的交易
 func (c *MySQLClient) GetTransactions(userID, startDate, endDate string) ([]entity.Transaction, error) {
 	var transactions []entity.Transaction
@@ -71,6 +238,24 @@ func (c *MySQLClient) GetTransactions(userID, startDate, endDate string) ([]enti
 	return transactions, err
 }
 
+// GetTransactionByID 根據交易 ID 查詢交易紀錄
+func (c *MySQLClient) GetTransactionByID(txID string) (*entity.Transaction, error) {
+	var tx entity.Transaction
+	err := c.DB.Where("id = ?", txID).First(&tx).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// UpdateTransaction 以新內容覆蓋既有交易紀錄的所有欄位，包含零值
+func (c *MySQLClient) UpdateTransaction(tx entity.Transaction) error {
+	return c.DB.Model(&entity.Transaction{}).Where("id = ?", tx.ID).Select("*").Updates(&tx).Error
+}
+
 // DeleteTransactionByID 根據交易 ID 刪除交易紀錄
 func (c *MySQLClient) DeleteTransactionByID(txID string) error {
 	result := c.DB.Delete(&entity.Transaction{}, "id = ?", txID)
@@ -78,7 +263,7 @@ func (c *MySQLClient) DeleteTransactionByID(txID string) error {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("transaction not found")
+		return ErrTransactionNotFound
 	}
 	return nil
 }
@@ -39,12 +39,15 @@ func TestSaveTransaction(t *testing.T) {
 		Description: "Salary",
 		Source:      "MANUAL",
 		Reconciled:  false,
+		Type:        "INCOME",
+		Payee:       "ACME Corp",
+		AccountID:   "acc1",
 	}
 
 	// 設置預期的 INSERT SQL 行為
 	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO `transactions`").
-		WithArgs(transaction.ID, transaction.UserID, transaction.Date, transaction.Amount, transaction.Category, transaction.Description, transaction.Source, transaction.Reconciled).
+		WithArgs(transaction.ID, transaction.UserID, transaction.Date, transaction.Amount, transaction.Category, transaction.Description, transaction.Notes, transaction.Source, transaction.Reconciled, transaction.Type, transaction.Payee, transaction.AccountID, transaction.Tags, transaction.StatementCycle, transaction.InstallmentPlanID, transaction.InstallmentNo, transaction.ExternalID).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
@@ -62,8 +65,8 @@ func TestGetFilteredTransactions(t *testing.T) {
 		{ID: "2", UserID: "user123", Date: time.Now(), Amount: 50.0, Category: "EXPENSE", Description: "Groceries", Source: "CREDIT_CARD", Reconciled: true},
 	}
 
-	// 使用 regexp.QuoteMeta 包裹查詢語句，避免特殊字符被誤解，並匹配 LIMIT 子句
-	query := regexp.QuoteMeta("SELECT * FROM `transactions` WHERE user_id = ? LIMIT ?")
+	// 使用 regexp.QuoteMeta 包裹查詢語句，避免特殊字符被誤解，並匹配排序與 LIMIT 子句
+	query := regexp.QuoteMeta("SELECT * FROM `transactions` WHERE user_id = ? ORDER BY date DESC, id DESC LIMIT ?")
 
 	// 設置預期的 SELECT SQL 行為，包含 user_id 和 LIMIT 參數
 	mock.ExpectQuery(query).
@@ -73,12 +76,104 @@ func TestGetFilteredTransactions(t *testing.T) {
 			AddRow(mockTransactions[1].ID, mockTransactions[1].UserID, mockTransactions[1].Date, mockTransactions[1].Amount, mockTransactions[1].Category, mockTransactions[1].Description, mockTransactions[1].Source, mockTransactions[1].Reconciled))
 
 	// 調用 GetFilteredTransactions，包含分頁設置
-	transactions, err := client.GetFilteredTransactions("user123", "", "", "", 1, 10)
+	transactions, err := client.GetFilteredTransactions(db.TransactionQuery{UserID: "user123", Descending: true, Limit: 10})
 	assert.NoError(t, err)
 	assert.Equal(t, len(mockTransactions), len(transactions))
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestGetFilteredTransactionsAfterCursor(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	client := &db.MySQLClient{DB: gormDB}
+
+	// 從上一頁最後一筆之後繼續，金額相同時依 ID 排序
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `transactions` WHERE user_id = ? AND category IN (?) AND (amount > ? OR (amount = ? AND id > ?)) ORDER BY amount ASC, id ASC LIMIT ?")).
+		WithArgs("user123", "FOOD", 120.0, 120.0, "tx9", 21).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "amount"}).AddRow("tx10", 120.0))
+
+	query := db.TransactionQuery{
+		UserID:     "user123",
+		Categories: []string{"FOOD"},
+		SortField:  db.SortByAmount,
+		After:      &db.TransactionKey{Value: 120.0, ID: "tx9"},
+		Limit:      21,
+	}
+	transactions, err := client.GetFilteredTransactions(query)
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `transactions` WHERE user_id = ? AND category IN (?)")).
+		WithArgs("user123", "FOOD").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+	count, err := client.CountFilteredTransactions(query)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetFilteredTransactionsPayeeCursorInNullGroup(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	client := &db.MySQLClient{DB: gormDB}
+
+	// 上一頁停在 payee 為 NULL 的資料中間，後續的 NULL 資料不能被跳過
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `transactions` WHERE user_id = ? AND (COALESCE(payee, '') > ? OR (COALESCE(payee, '') = ? AND id > ?)) ORDER BY COALESCE(payee, '') ASC, id ASC LIMIT ?")).
+		WithArgs("user123", "", "", "tx5", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "payee"}).AddRow("tx6", nil).AddRow("tx7", nil).AddRow("tx2", "IKEA"))
+
+	transactions, err := client.GetFilteredTransactions(db.TransactionQuery{
+		UserID:    "user123",
+		SortField: db.SortByPayee,
+		After:     &db.TransactionKey{Value: "", ID: "tx5"},
+		Limit:     3,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tx6", "tx7", "tx2"}, []string{transactions[0].ID, transactions[1].ID, transactions[2].ID})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetFilteredTransactionsWithFilters(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	client := &db.MySQLClient{DB: gormDB}
+
+	minAmount, reconciled := 100.0, false
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `transactions` WHERE user_id = ? AND category IN (?,?) AND date >= ? AND amount >= ? AND source IN (?) AND reconciled = ? AND account_id = ? AND payee = ? AND FIND_IN_SET(?, REPLACE(tags, ' ', '')) > 0 AND FIND_IN_SET(?, REPLACE(tags, ' ', '')) > 0 AND description LIKE ? ORDER BY date ASC, id ASC LIMIT ?")).
+		WithArgs("user123", "FOOD", "HOME", "2024-03-01 00:00:00", 100.0, "CREDIT_CARD", false, "acc1", "IKEA", "roadtrip", "taxable", `%50\% off%`, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := client.GetFilteredTransactions(db.TransactionQuery{
+		UserID:      "user123",
+		Categories:  []string{"FOOD", "HOME"},
+		StartDate:   "2024-03-01 00:00:00",
+		MinAmount:   &minAmount,
+		Sources:     []string{"CREDIT_CARD"},
+		Reconciled:  &reconciled,
+		AccountID:   "acc1",
+		Payee:       "IKEA",
+		Tags:        []string{"road trip", "taxable"},
+		Description: "50% off",
+		Limit:       10,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchTransactions(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	client := &db.MySQLClient{DB: gormDB}
+
+	match := "MATCH(description, payee, notes) AGAINST(? IN NATURAL LANGUAGE MODE)"
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT *, "+match+" AS score FROM `transactions` WHERE user_id = ? AND category IN (?) AND "+match+" ORDER BY score DESC, date DESC, id DESC LIMIT ?")).
+		WithArgs("IKEA", "user123", "HOME", "IKEA", 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "payee", "score"}).AddRow("tx1", "IKEA", 2.5))
+
+	matches, err := client.SearchTransactions(db.TransactionQuery{UserID: "user123", Categories: []string{"HOME"}, Limit: 20}, "IKEA")
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+	assert.Equal(t, "tx1", matches[0].ID)
+	assert.Equal(t, 2.5, matches[0].Score)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetTransactions(t *testing.T) {
 	gormDB, mock := setupMockDB(t)
 	client := &db.MySQLClient{DB: gormDB}
@@ -117,3 +212,150 @@ func TestDeleteTransactionByID(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestGetTransactionByIDNotFound(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	client := &db.MySQLClient{DB: gormDB}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `transactions` WHERE id = ?")).
+		WithArgs("missing", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := client.GetTransactionByID("missing")
+	assert.ErrorIs(t, err, db.ErrTransactionNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateTransaction(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	client := &db.MySQLClient{DB: gormDB}
+
+	// 零值欄位（如清除的標籤）也需寫入
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `transactions` SET `id`=?,`user_id`=?,`date`=?,`amount`=?,`category`=?,`description`=?,`notes`=?,`source`=?,`reconciled`=?,`type`=?,`payee`=?,`account_id`=?,`tags`=?")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := client.UpdateTransaction(entity.Transaction{ID: "1", UserID: "user123", Date: time.Now(), Amount: 250})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRecurringSchedules(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	client := &db.MySQLClient{DB: gormDB}
+
+	query := regexp.QuoteMeta("SELECT * FROM `recurring_schedules` WHERE user_id = ? ORDER BY next_date")
+
+	mock.ExpectQuery(query).
+		WithArgs("user123").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "payee", "cadence", "amount", "next_date", "active"}).
+			AddRow("s1", "user123", "Netflix", "MONTHLY", 390.0, time.Now(), true))
+
+	schedules, err := client.GetRecurringSchedules("user123")
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 1)
+	assert.Equal(t, entity.CadenceMonthly, schedules[0].Cadence)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAccountBalances(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	client := &db.MySQLClient{DB: gormDB}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT account_id, SUM(CASE WHEN type = 'INCOME'")).
+		WithArgs("user123", "2024-07-01").
+		WillReturnRows(sqlmock.NewRows([]string{"account_id", "balance"}).
+			AddRow("acc1", 1200.5).
+			AddRow("", -30.0))
+
+	balances, err := client.GetAccountBalances("user123", "2024-07-01")
+	assert.NoError(t, err)
+	assert.Equal(t, 1200.5, balances["acc1"])
+	assert.Equal(t, -30.0, balances[""])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCategoryTotals(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	client := &db.MySQLClient{DB: gormDB}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DATE_FORMAT(date, '%Y-%m') AS period, category, CASE WHEN")).
+		WithArgs("user123", "2024-01-01", "2024-03-01").
+		WillReturnRows(sqlmock.NewRows([]string{"period", "category", "direction", "total", "count"}).
+			AddRow("2024-01", "FOOD", "EXPENSE", 3200.5, 12).
+			AddRow("2024-02", "SALARY", "INCOME", 50000.0, 1))
+
+	totals, err := client.GetCategoryTotals("user123", "2024-01-01", "2024-03-01", db.Bucket{Period: db.PeriodMonth})
+	assert.NoError(t, err)
+	assert.Equal(t, []db.CategoryTotal{
+		{Period: "2024-01", Category: "FOOD", Direction: "EXPENSE", Total: 3200.5, Count: 12},
+		{Period: "2024-02", Category: "SALARY", Direction: "INCOME", Total: 50000, Count: 1},
+	}, totals)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCategoryTotalsInUserTimeZone(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	client := &db.MySQLClient{DB: gormDB}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DATE_FORMAT(DATE_SUB(CONVERT_TZ(date, '+00:00', ?), INTERVAL 6 MONTH), '%Y') AS period")).
+		WithArgs("Asia/Taipei", "user123", "2023-06-30 16:00:00", "2024-06-30 16:00:00").
+		WillReturnRows(sqlmock.NewRows([]string{"period", "category", "direction", "total", "count"}).
+			AddRow("2023", "FOOD", "EXPENSE", 1200.0, 3))
+
+	bucket := db.Bucket{Period: db.PeriodYear, TimeZone: "Asia/Taipei", FiscalStartMonth: 7}
+	totals, err := client.GetCategoryTotals("user123", "2023-06-30 16:00:00", "2024-06-30 16:00:00", bucket)
+	assert.NoError(t, err)
+	assert.Len(t, totals, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckTimeZoneSupport(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	client := &db.MySQLClient{DB: gormDB}
+	probe := regexp.QuoteMeta("SELECT CONVERT_TZ(NOW(), '+00:00', 'Asia/Taipei') IS NULL")
+
+	mock.ExpectQuery(probe).WillReturnRows(sqlmock.NewRows([]string{"missing"}).AddRow(0))
+	assert.NoError(t, client.CheckTimeZoneSupport())
+
+	// 未載入時區資料表時 CONVERT_TZ 返回 NULL
+	mock.ExpectQuery(probe).WillReturnRows(sqlmock.NewRows([]string{"missing"}).AddRow(1))
+	assert.ErrorIs(t, client.CheckTimeZoneSupport(), db.ErrTimeZoneTablesMissing)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTopPayeesAcrossDaylightSaving(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	client := &db.MySQLClient{DB: gormDB}
+
+	// 以時區名稱換算，紐約 7 月 31 日 23:30 的交易依當時的夏令時間歸入 7 月
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DATE_FORMAT(CONVERT_TZ(date, '+00:00', ?), '%Y-%m') AS period")).
+		WithArgs("America/New_York", "user123", "2024-01-01 05:00:00", "2025-01-01 05:00:00", 5).
+		WillReturnRows(sqlmock.NewRows([]string{"period", "payee_name", "total", "count"}).
+			AddRow("2024-07", "Late dinner", 80.0, 1))
+
+	bucket := db.Bucket{Period: db.PeriodMonth, TimeZone: "America/New_York"}
+	payees, err := client.GetTopPayees("user123", "2024-01-01 05:00:00", "2025-01-01 05:00:00", bucket, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []db.PayeeTotal{{Period: "2024-07", Payee: "Late dinner", Total: 80, Count: 1}}, payees)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreUserDataRejectsForeignIDs(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	client := &db.MySQLClient{DB: gormDB}
+
+	// 封存檔沿用了另一位用戶的交易 ID，不得覆蓋該紀錄
+	data := &db.UserData{Transactions: []entity.Transaction{{ID: "tx-of-user456", UserID: "user123", Amount: 1}}}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `transactions` WHERE id IN (?) AND user_id <> ? LIMIT ?")).
+		WithArgs("tx-of-user456", "user123", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("tx-of-user456"))
+	mock.ExpectRollback()
+
+	err := client.RestoreUserData("user123", data)
+	assert.ErrorIs(t, err, db.ErrForeignRecord)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
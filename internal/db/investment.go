@@ -0,0 +1,93 @@
+package db
+
+import (
+	"errors"
+	"fintrack/internal/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSecurityNotFound 表示查詢的證券不存在
+var ErrSecurityNotFound = errors.New("security not found")
+
+// SaveSecurity 保存證券資料，已存在時更新名稱與幣別
+func (c *MySQLClient) SaveSecurity(security entity.Security) error {
+	return c.DB.Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns([]string{"name", "currency"})}).Create(&security).Error
+}
+
+// GetSecurities 查詢所有證券
+func (c *MySQLClient) GetSecurities() ([]entity.Security, error) {
+	var securities []entity.Security
+	err := c.DB.Order("symbol").Find(&securities).Error
+	return securities, err
+}
+
+// GetSecurity 根據代號查詢證券
+func (c *MySQLClient) GetSecurity(symbol string) (*entity.Security, error) {
+	var security entity.Security
+	err := c.DB.Where("symbol = ?", symbol).First(&security).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSecurityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &security, nil
+}
+
+// SaveTrade 保存投資交易
+func (c *MySQLClient) SaveTrade(trade entity.Trade) error {
+	return c.DB.Create(&trade).Error
+}
+
+// GetTrades 查詢用戶在指定日期之前的所有投資交易，依交易日期與建立順序排序以便重播
+func (c *MySQLClient) GetTrades(userID, before string) ([]entity.Trade, error) {
+	var trades []entity.Trade
+	err := c.DB.Where("user_id = ? AND date < ?", userID, before).Order("date, created_at").Find(&trades).Error
+	return trades, err
+}
+
+// GetTradeUserIDs 查詢曾交易指定證券的所有用戶
+func (c *MySQLClient) GetTradeUserIDs(symbols []string) ([]string, error) {
+	var userIDs []string
+	if len(symbols) == 0 {
+		return userIDs, nil
+	}
+	err := c.DB.Model(&entity.Trade{}).Distinct("user_id").Where("symbol IN ?", symbols).Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// SavePrices 批量保存收盤價，同一證券同一日期的價格會被覆蓋
+func (c *MySQLClient) SavePrices(prices []entity.SecurityPrice) error {
+	if len(prices) == 0 {
+		return nil
+	}
+	return c.DB.Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns([]string{"close"})}).CreateInBatches(prices, 500).Error
+}
+
+// GetLatestPrices 查詢各證券在指定日期（含）之前的最新收盤價
+func (c *MySQLClient) GetLatestPrices(symbols []string, asOf string) (map[string]entity.SecurityPrice, error) {
+	prices := make(map[string]entity.SecurityPrice, len(symbols))
+	if len(symbols) == 0 {
+		return prices, nil
+	}
+
+	var rows []entity.SecurityPrice
+	latest := c.DB.Model(&entity.SecurityPrice{}).
+		Select("symbol, MAX(date) AS date").
+		Where("symbol IN ? AND date <= ?", symbols, asOf).
+		Group("symbol")
+	err := c.DB.Table("security_prices AS p").
+		Select("p.*").
+		Joins("JOIN (?) AS m ON p.symbol = m.symbol AND p.date = m.date", latest).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		prices[row.Symbol] = row
+	}
+	return prices, nil
+}
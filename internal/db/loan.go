@@ -0,0 +1,53 @@
+package db
+
+import (
+	"errors"
+	"fintrack/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+// ErrLoanNotFound 表示查詢的貸款不存在
+var ErrLoanNotFound = errors.New("loan not found")
+
+// SaveLoan 保存貸款
+func (c *MySQLClient) SaveLoan(loan entity.Loan) error {
+	return c.DB.Create(&loan).Error
+}
+
+// GetLoans 查詢用戶的所有貸款
+func (c *MySQLClient) GetLoans(userID string) ([]entity.Loan, error) {
+	var loans []entity.Loan
+	err := c.DB.Where("user_id = ?", userID).Order("start_date").Find(&loans).Error
+	return loans, err
+}
+
+// GetLoanByID 根據貸款 ID 查詢貸款
+func (c *MySQLClient) GetLoanByID(loanID string) (*entity.Loan, error) {
+	var loan entity.Loan
+	err := c.DB.Where("id = ?", loanID).First(&loan).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrLoanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &loan, nil
+}
+
+// SaveLoanPayment 保存貸款還款紀錄
+func (c *MySQLClient) SaveLoanPayment(payment entity.LoanPayment) error {
+	return c.DB.Create(&payment).Error
+}
+
+// DeleteLoanPayment 刪除還款紀錄，用於交易送出失敗時撤銷還款
+func (c *MySQLClient) DeleteLoanPayment(paymentID string) error {
+	return c.DB.Delete(&entity.LoanPayment{}, "id = ?", paymentID).Error
+}
+
+// GetLoanPayments 查詢貸款的所有還款紀錄，依還款日期排序
+func (c *MySQLClient) GetLoanPayments(loanID string) ([]entity.LoanPayment, error) {
+	var payments []entity.LoanPayment
+	err := c.DB.Where("loan_id = ?", loanID).Order("date, created_at").Find(&payments).Error
+	return payments, err
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"fintrack/internal/entity"
+
+	"gorm.io/gorm/clause"
+)
+
+// SaveNetWorthSnapshots 批量保存月底淨值快照，同一用戶、月份與帳戶的快照會被覆蓋
+func (c *MySQLClient) SaveNetWorthSnapshots(snapshots []entity.NetWorthSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	return c.DB.Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns([]string{"balance", "holdings", "created_at"})}).Create(&snapshots).Error
+}
+
+// GetNetWorthSnapshots 查詢期間內（含首尾）的月底快照
+func (c *MySQLClient) GetNetWorthSnapshots(userID, from, to string) ([]entity.NetWorthSnapshot, error) {
+	var snapshots []entity.NetWorthSnapshot
+	err := c.DB.Where("user_id = ? AND month BETWEEN ? AND ?", userID, from, to).Order("month, account_id").Find(&snapshots).Error
+	return snapshots, err
+}
+
+// GetLatestNetWorthSnapshot 查詢指定日期之前最近一個月底的快照，沒有快照時返回空切片
+func (c *MySQLClient) GetLatestNetWorthSnapshot(userID, before string) ([]entity.NetWorthSnapshot, error) {
+	var snapshots []entity.NetWorthSnapshot
+	latest := c.DB.Model(&entity.NetWorthSnapshot{}).
+		Select("MAX(month)").
+		Where("user_id = ? AND month < ?", userID, before)
+	err := c.DB.Where("user_id = ? AND month = (?)", userID, latest).Order("account_id").Find(&snapshots).Error
+	return snapshots, err
+}
+
+// DeleteNetWorthSnapshots 刪除指定日期（含）之後的月底快照，userID 為空時刪除所有用戶的快照
+func (c *MySQLClient) DeleteNetWorthSnapshots(userID, from string) error {
+	query := c.DB.Where("month >= ?", from)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	return query.Delete(&entity.NetWorthSnapshot{}).Error
+}
@@ -0,0 +1,15 @@
+package db
+
+import "fintrack/internal/entity"
+
+// SaveRecurringSchedule 保存週期性排程
+func (c *MySQLClient) SaveRecurringSchedule(schedule entity.RecurringSchedule) error {
+	return c.DB.Create(&schedule).Error
+}
+
+// GetRecurringSchedules 查詢用戶的所有週期性排程，依下次發生日期排序
+func (c *MySQLClient) GetRecurringSchedules(userID string) ([]entity.RecurringSchedule, error) {
+	var schedules []entity.RecurringSchedule
+	err := c.DB.Where("user_id = ?", userID).Order("next_date").Find(&schedules).Error
+	return schedules, err
+}
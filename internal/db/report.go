@@ -0,0 +1,139 @@
+package db
+
+import (
+	"errors"
+	"fintrack/internal/entity"
+	"fmt"
+)
+
+// ErrTimeZoneTablesMissing 表示 MySQL 未載入時區資料表，CONVERT_TZ 無法以時區名稱換算
+var ErrTimeZoneTablesMissing = errors.New("MySQL time zone tables are not loaded, load them with mysql_tzinfo_to_sql")
+
+// Period 表示報表彙總的期間單位
+type Period string
+
+const (
+	PeriodMonth Period = "MONTH"
+	PeriodYear  Period = "YEAR"
+)
+
+// Bucket 表示報表彙總的期間劃分方式，交易日期以 UTC 儲存，
+// 先依用戶的 IANA 時區換算為當地時間再歸入期間，每筆交易使用當時的偏移，跨越日光節約時間亦正確。
+// 以時區名稱換算需要 MySQL 載入時區資料表
+type Bucket struct {
+	Period           Period
+	TimeZone         string // 用戶的 IANA 時區名稱，空字串或 UTC 時不換算
+	FiscalStartMonth int    // 會計年度起始月份，年度期間以起始年份為代碼；0 或 1 為曆年
+}
+
+// expr 返回將交易日期轉為期間代碼（YYYY-MM 或 YYYY）的 SQL 運算式與其參數
+func (b Bucket) expr() (string, []interface{}) {
+	date := "date"
+	var args []interface{}
+	if b.TimeZone != "" && b.TimeZone != "UTC" {
+		date = "CONVERT_TZ(date, '+00:00', ?)"
+		args = append(args, b.TimeZone)
+	}
+	if b.Period == PeriodYear {
+		if b.FiscalStartMonth > 1 {
+			date = fmt.Sprintf("DATE_SUB(%s, INTERVAL %d MONTH)", date, b.FiscalStartMonth-1)
+		}
+		return "DATE_FORMAT(" + date + ", '%Y')", args
+	}
+	return "DATE_FORMAT(" + date + ", '%Y-%m')", args
+}
+
+// CheckTimeZoneSupport 確認 CONVERT_TZ 可以使用時區名稱。未載入時區資料表時 CONVERT_TZ 返回 NULL，
+// 所有期間彙總都會變成 NULL 而使報表顯示為零，因此啟動時即返回錯誤
+func (c *MySQLClient) CheckTimeZoneSupport() error {
+	var missing bool
+	if err := c.DB.Raw("SELECT CONVERT_TZ(NOW(), '+00:00', 'Asia/Taipei') IS NULL").Scan(&missing).Error; err != nil {
+		return err
+	}
+	if missing {
+		return ErrTimeZoneTablesMissing
+	}
+	return nil
+}
+
+// directionExpr 將交易歸類為 INCOME 或 EXPENSE
+const directionExpr = "CASE WHEN " + incomeCondition + " THEN 'INCOME' ELSE 'EXPENSE' END"
+
+// payeeExpr 返回交易的收款方，未填寫時以描述代替
+const payeeExpr = "COALESCE(NULLIF(payee, ''), description)"
+
+// CategoryTotal 表示某期間某分類的收支彙總
+type CategoryTotal struct {
+	Period    string
+	Category  string
+	Direction string // INCOME 或 EXPENSE
+	Total     float64
+	Count     int
+}
+
+// PayeeTotal 表示某期間某收款方的支出彙總
+type PayeeTotal struct {
+	Period string
+	Payee  string
+	Total  float64
+	Count  int
+}
+
+// GetCategoryTotals 依期間與分類彙總 [startDate, endDate) 內的收支
+func (c *MySQLClient) GetCategoryTotals(userID, startDate, endDate string, bucket Bucket) ([]CategoryTotal, error) {
+	period, args := bucket.expr()
+	var totals []CategoryTotal
+	err := c.DB.Model(&entity.Transaction{}).
+		Select(period+" AS period, category, "+directionExpr+" AS direction, SUM(amount) AS total, COUNT(*) AS count", args...).
+		Where("user_id = ? AND date >= ? AND date < ?", userID, startDate, endDate).
+		Group("period, category, direction").
+		Order("period, category").
+		Scan(&totals).Error
+	return totals, err
+}
+
+// GetTopPayees 返回 [startDate, endDate) 內每個期間支出金額最高的前 limit 個收款方
+func (c *MySQLClient) GetTopPayees(userID, startDate, endDate string, bucket Bucket, limit int) ([]PayeeTotal, error) {
+	period, args := bucket.expr()
+	totals := c.DB.Model(&entity.Transaction{}).
+		Select(period+" AS period, "+payeeExpr+" AS payee_name, SUM(amount) AS total, COUNT(*) AS count", args...).
+		Where("user_id = ? AND date >= ? AND date < ? AND NOT ("+incomeCondition+")", userID, startDate, endDate).
+		Group("period, payee_name")
+	ranked := c.DB.Table("(?) AS t", totals).
+		Select("t.*, ROW_NUMBER() OVER (PARTITION BY period ORDER BY total DESC) AS row_rank")
+
+	var rows []struct {
+		Period    string
+		PayeeName string
+		Total     float64
+		Count     int
+	}
+	err := c.DB.Table("(?) AS r", ranked).
+		Where("row_rank <= ?", limit).
+		Order("period, total DESC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	payees := make([]PayeeTotal, len(rows))
+	for i, row := range rows {
+		payees[i] = PayeeTotal{Period: row.Period, Payee: row.PayeeName, Total: row.Total, Count: row.Count}
+	}
+	return payees, nil
+}
+
+// GetLargestTransactions 返回 [startDate, endDate) 內每個期間金額最高的前 limit 筆支出
+func (c *MySQLClient) GetLargestTransactions(userID, startDate, endDate string, bucket Bucket, limit int) ([]entity.Transaction, error) {
+	period, args := bucket.expr()
+	ranked := c.DB.Model(&entity.Transaction{}).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY "+period+" ORDER BY amount DESC) AS row_rank", args...).
+		Where("user_id = ? AND date >= ? AND date < ? AND NOT ("+incomeCondition+")", userID, startDate, endDate)
+
+	var transactions []entity.Transaction
+	err := c.DB.Table("(?) AS r", ranked).
+		Where("row_rank <= ?", limit).
+		Order("date, amount DESC").
+		Find(&transactions).Error
+	return transactions, err
+}
@@ -0,0 +1,24 @@
+package db
+
+import "fintrack/internal/entity"
+
+// searchMatch 為說明、收款方與備註的全文檢索條件，欄位須與 FULLTEXT 索引一致
+const searchMatch = "MATCH(description, payee, notes) AGAINST(? IN NATURAL LANGUAGE MODE)"
+
+// TransactionMatch 表示全文檢索命中的交易與相關度
+type TransactionMatch struct {
+	entity.Transaction
+	Score float64
+}
+
+// SearchTransactions 以全文索引搜尋符合篩選條件的交易，依相關度、日期與 ID 由高至低排序。
+// 排序與分頁位置不適用，只返回前 query.Limit 筆
+func (c *MySQLClient) SearchTransactions(query TransactionQuery, text string) ([]TransactionMatch, error) {
+	var matches []TransactionMatch
+	err := query.filter(c.DB.Model(&entity.Transaction{}).Select("*, "+searchMatch+" AS score", text)).
+		Where(searchMatch, text).
+		Order("score DESC, date DESC, id DESC").
+		Limit(query.Limit).
+		Scan(&matches).Error
+	return matches, err
+}
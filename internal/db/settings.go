@@ -0,0 +1,27 @@
+package db
+
+import (
+	"errors"
+	"fintrack/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+// GetUserSettings 查詢用戶的報表偏好，未設定時返回預設值
+func (c *MySQLClient) GetUserSettings(userID string) (*entity.UserSettings, error) {
+	var settings entity.UserSettings
+	err := c.DB.Where("user_id = ?", userID).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		settings = entity.DefaultUserSettings(userID)
+		return &settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SaveUserSettings 新增或更新用戶的報表偏好
+func (c *MySQLClient) SaveUserSettings(settings entity.UserSettings) error {
+	return c.DB.Save(&settings).Error
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"errors"
+	"fintrack/internal/entity"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSubscriptionNotFound 表示查詢的報表訂閱不存在
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
+// SaveSubscription 新增或更新報表訂閱
+func (c *MySQLClient) SaveSubscription(subscription entity.ReportSubscription) error {
+	return c.DB.Save(&subscription).Error
+}
+
+// GetSubscriptions 查詢用戶的所有報表訂閱
+func (c *MySQLClient) GetSubscriptions(userID string) ([]entity.ReportSubscription, error) {
+	var subscriptions []entity.ReportSubscription
+	err := c.DB.Where("user_id = ?", userID).Order("created_at").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// GetSubscriptionByID 根據 ID 查詢報表訂閱
+func (c *MySQLClient) GetSubscriptionByID(subscriptionID string) (*entity.ReportSubscription, error) {
+	var subscription entity.ReportSubscription
+	err := c.DB.Where("id = ?", subscriptionID).First(&subscription).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// DeleteSubscription 刪除報表訂閱，已建立的寄送紀錄保留
+func (c *MySQLClient) DeleteSubscription(subscriptionID string) error {
+	result := c.DB.Delete(&entity.ReportSubscription{}, "id = ?", subscriptionID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// GetDueSubscriptions 查詢已到寄送時間的有效訂閱
+func (c *MySQLClient) GetDueSubscriptions(now time.Time) ([]entity.ReportSubscription, error) {
+	var subscriptions []entity.ReportSubscription
+	err := c.DB.Where("active = ? AND next_run_at <= ?", true, now).Order("next_run_at").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// CreateDeliveries 建立寄送紀錄，已存在的期間會被略過
+func (c *MySQLClient) CreateDeliveries(deliveries []entity.ReportDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return c.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+}
+
+// SaveDelivery 更新寄送紀錄的狀態與重試資訊
+func (c *MySQLClient) SaveDelivery(delivery entity.ReportDelivery) error {
+	return c.DB.Save(&delivery).Error
+}
+
+// GetPendingDeliveries 查詢已到寄送或重試時間的寄送紀錄
+func (c *MySQLClient) GetPendingDeliveries(now time.Time, limit int) ([]entity.ReportDelivery, error) {
+	var deliveries []entity.ReportDelivery
+	err := c.DB.Where("status = ? AND next_attempt_at <= ?", entity.DeliveryPending, now).
+		Order("next_attempt_at").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// GetDeliveries 查詢訂閱的寄送紀錄，最新的在前
+func (c *MySQLClient) GetDeliveries(subscriptionID string) ([]entity.ReportDelivery, error) {
+	var deliveries []entity.ReportDelivery
+	err := c.DB.Where("subscription_id = ?", subscriptionID).Order("period_start DESC").Find(&deliveries).Error
+	return deliveries, err
+}
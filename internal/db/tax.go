@@ -0,0 +1,27 @@
+package db
+
+import (
+	"fintrack/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+// GetTaxMappings 查詢用戶的稅務類別對應
+func (c *MySQLClient) GetTaxMappings(userID string) ([]entity.TaxMapping, error) {
+	var mappings []entity.TaxMapping
+	err := c.DB.Where("user_id = ?", userID).Order("bucket, `match`, value").Find(&mappings).Error
+	return mappings, err
+}
+
+// ReplaceTaxMappings 以新的對應取代用戶現有的所有稅務類別對應
+func (c *MySQLClient) ReplaceTaxMappings(userID string, mappings []entity.TaxMapping) error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.TaxMapping{}).Error; err != nil {
+			return err
+		}
+		if len(mappings) == 0 {
+			return nil
+		}
+		return tx.Create(&mappings).Error
+	})
+}
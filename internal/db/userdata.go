@@ -0,0 +1,153 @@
+package db
+
+import (
+	"errors"
+	"fintrack/internal/entity"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrForeignRecord 表示匯入的紀錄 ID 已屬於其他用戶
+var ErrForeignRecord = errors.New("record belongs to another user")
+
+// UserData 表示單一用戶的完整資料，用於匯出與搬移
+type UserData struct {
+	Transactions       []entity.Transaction
+	Accounts           []entity.Account
+	RecurringSchedules []entity.RecurringSchedule
+	InstallmentPlans   []entity.InstallmentPlan
+	Loans              []entity.Loan
+	LoanPayments       []entity.LoanPayment
+	Trades             []entity.Trade
+	Securities         []entity.Security      // 用戶交易過的證券
+	SecurityPrices     []entity.SecurityPrice // 上述證券的收盤價
+	TaxMappings        []entity.TaxMapping
+	UserSettings       []entity.UserSettings // 未設定偏好時為空
+}
+
+// GetUserData 查詢用戶的所有資料，證券與收盤價僅包含用戶交易過的代號
+func (c *MySQLClient) GetUserData(userID string) (*UserData, error) {
+	data := &UserData{}
+	queries := []struct {
+		dest  interface{}
+		order string
+	}{
+		{&data.Transactions, "date, id"},
+		{&data.Accounts, "created_at, id"},
+		{&data.RecurringSchedules, "next_date, id"},
+		{&data.InstallmentPlans, "created_at, id"},
+		{&data.Loans, "created_at, id"},
+		{&data.LoanPayments, "date, id"},
+		{&data.Trades, "date, created_at"},
+		{&data.TaxMappings, "bucket, value"},
+		{&data.UserSettings, "user_id"},
+	}
+	for _, q := range queries {
+		if err := c.DB.Where("user_id = ?", userID).Order(q.order).Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	symbols := c.DB.Model(&entity.Trade{}).Distinct("symbol").Where("user_id = ?", userID)
+	if err := c.DB.Where("symbol IN (?)", symbols).Order("symbol").Find(&data.Securities).Error; err != nil {
+		return nil, err
+	}
+	if err := c.DB.Where("symbol IN (?)", symbols).Order("symbol, date").Find(&data.SecurityPrices).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// RestoreUserData 在單一資料庫交易中將資料寫入指定用戶，同一用戶相同 ID 的紀錄會被覆蓋；
+// 任一 ID 已屬於其他用戶時返回 ErrForeignRecord 且不寫入任何資料。
+// 證券與收盤價為所有用戶共用，已存在時保留現有資料
+func (c *MySQLClient) RestoreUserData(userID string, data *UserData) error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		for _, owned := range ownedRecordIDs(data) {
+			for start := 0; start < len(owned.ids); start += 500 {
+				end := min(start+500, len(owned.ids))
+				var foreign []string
+				err := tx.Model(owned.model).Where("id IN ? AND user_id <> ?", owned.ids[start:end], userID).Limit(1).Pluck("id", &foreign).Error
+				if err != nil {
+					return err
+				}
+				if len(foreign) > 0 {
+					return fmt.Errorf("%w: %s", ErrForeignRecord, foreign[0])
+				}
+			}
+		}
+
+		upsert := clause.OnConflict{UpdateAll: true}
+		keep := clause.OnConflict{DoNothing: true}
+		batches := []struct {
+			conflict clause.OnConflict
+			rows     interface{}
+			n        int
+		}{
+			{upsert, data.Accounts, len(data.Accounts)},
+			{upsert, data.InstallmentPlans, len(data.InstallmentPlans)},
+			{upsert, data.Loans, len(data.Loans)},
+			{upsert, data.RecurringSchedules, len(data.RecurringSchedules)},
+			{upsert, data.Transactions, len(data.Transactions)},
+			{upsert, data.LoanPayments, len(data.LoanPayments)},
+			{keep, data.Securities, len(data.Securities)},
+			{upsert, data.Trades, len(data.Trades)},
+			{keep, data.SecurityPrices, len(data.SecurityPrices)},
+			{upsert, data.TaxMappings, len(data.TaxMappings)},
+			{upsert, data.UserSettings, len(data.UserSettings)},
+		}
+		for _, b := range batches {
+			if b.n == 0 {
+				continue
+			}
+			if err := tx.Clauses(b.conflict).CreateInBatches(b.rows, 500).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ownedRecordIDs 返回以 ID 為主鍵的用戶資料表及匯入資料中的 ID；
+// 稅務對應與用戶設定的主鍵包含 user_id，不會與其他用戶衝突
+func ownedRecordIDs(data *UserData) []struct {
+	model interface{}
+	ids   []string
+} {
+	var accounts, plans, loans, schedules, transactions, payments, trades []string
+	for _, r := range data.Accounts {
+		accounts = append(accounts, r.ID)
+	}
+	for _, r := range data.InstallmentPlans {
+		plans = append(plans, r.ID)
+	}
+	for _, r := range data.Loans {
+		loans = append(loans, r.ID)
+	}
+	for _, r := range data.RecurringSchedules {
+		schedules = append(schedules, r.ID)
+	}
+	for _, r := range data.Transactions {
+		transactions = append(transactions, r.ID)
+	}
+	for _, r := range data.LoanPayments {
+		payments = append(payments, r.ID)
+	}
+	for _, r := range data.Trades {
+		trades = append(trades, r.ID)
+	}
+	return []struct {
+		model interface{}
+		ids   []string
+	}{
+		{&entity.Account{}, accounts},
+		{&entity.InstallmentPlan{}, plans},
+		{&entity.Loan{}, loans},
+		{&entity.RecurringSchedule{}, schedules},
+		{&entity.Transaction{}, transactions},
+		{&entity.LoanPayment{}, payments},
+		{&entity.Trade{}, trades},
+	}
+}
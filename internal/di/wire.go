@@ -8,10 +8,12 @@ import (
 	"fintrack/internal/cache"
 	"fintrack/internal/db"
 	"fintrack/internal/handler"
+	"fintrack/internal/mail"
 	"fintrack/internal/mq"
 	"fintrack/internal/service"
 	"sync"
 
+	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 )
 
@@ -28,6 +30,9 @@ var (
 
 	redisOnce  sync.Once
 	redisCache *cache.Redis
+
+	mailOnce   sync.Once
+	mailSender *mail.SMTP
 )
 
 func NewConfig() *config.Config {
@@ -52,6 +57,13 @@ func NewRedisCache(cfg *config.Config) cache.Cache {
 	return redisCache
 }
 
+func NewMailSender(cfg *config.Config) mail.Sender {
+	mailOnce.Do(func() {
+		mailSender = mail.NewSMTP(cfg.SMTPConfig)
+	})
+	return mailSender
+}
+
 // NewRabbitMQProducer 確保 RabbitMQ 生產者僅初始化一次
 func NewRabbitMQProducer(cfg *config.Config) mq.MQProducer {
 	rabbitMQOnce.Do(func() {
@@ -63,30 +75,60 @@ func NewRabbitMQProducer(cfg *config.Config) mq.MQProducer {
 // NewRabbitMQConsumer 初始化 RabbitMQ 消費者
 func NewRabbitMQConsumer(cfg *config.Config) mq.MQConsumer {
 	rabbitMQOnce.Do(func() {
-		rabbitMQConsumer,  _ = mq.NewRabbitMQClient(cfg.RabbitMQConfig)
+		rabbitMQConsumer, _ = mq.NewRabbitMQClient(cfg.RabbitMQConfig)
 	})
 	return rabbitMQConsumer
 }
 
 // ProviderSet 定義所有的依賴提供者
 var ProviderSet = wire.NewSet(
-	NewConfig,                     // 單例模式加載配置
-	NewDBClient,                   // 單例模式初始化資料庫
-	NewRedisCache,                 // 單例模式初始化 Redis
-	NewRabbitMQProducer,           // 單例模式初始化 RabbitMQ 生產者
-	NewRabbitMQConsumer,           // 單例模式初始化 RabbitMQ 消費者
-	service.NewTransactionService, // 初始化業務邏輯層
-	handler.NewTransactionHandler, // 初始化 API 處理層
-	service.NewMessageService,     // 初始化業務邏輯層
-	handler.NewMessageHandler,     // 初始化消息處理層
+	NewConfig,                      // 單例模式加載配置
+	NewDBClient,                    // 單例模式初始化資料庫
+	NewRedisCache,                  // 單例模式初始化 Redis
+	NewMailSender,                  // 單例模式初始化 SMTP 寄件
+	NewRabbitMQProducer,            // 單例模式初始化 RabbitMQ 生產者
+	NewRabbitMQConsumer,            // 單例模式初始化 RabbitMQ 消費者
+	service.NewTransactionService,  // 初始化業務邏輯層
+	handler.NewTransactionHandler,  // 初始化 API 處理層
+	service.NewMessageService,      // 初始化業務邏輯層
+	handler.NewMessageHandler,      // 初始化消息處理層
+	service.NewRecurringService,    // 初始化週期性收支業務邏輯
+	handler.NewRecurringHandler,    // 初始化週期性收支 API 處理層
+	service.NewAccountService,      // 初始化帳戶業務邏輯
+	handler.NewAccountHandler,      // 初始化帳戶 API 處理層
+	service.NewLoanService,         // 初始化貸款業務邏輯
+	handler.NewLoanHandler,         // 初始化貸款 API 處理層
+	service.NewInvestmentService,   // 初始化投資業務邏輯
+	handler.NewInvestmentHandler,   // 初始化投資 API 處理層
+	service.NewPortabilityService,  // 初始化用戶資料匯出與匯入業務邏輯
+	handler.NewPortabilityHandler,  // 初始化用戶資料匯出與匯入 API 處理層
+	service.NewSubscriptionService, // 初始化報表訂閱業務邏輯
+	handler.NewSubscriptionHandler, // 初始化報表訂閱 API 處理層
+	handler.NewReportScheduler,     // 初始化報表定期寄送排程
+	service.NewTaxService,          // 初始化稅務類別對應業務邏輯
+	handler.NewTaxHandler,          // 初始化稅務類別對應 API 處理層
+	service.NewSettingsService,     // 初始化用戶偏好設定業務邏輯
+	handler.NewSettingsHandler,     // 初始化用戶偏好設定 API 處理層
+	handler.NewRouter,              // 組合所有 API 路由
 )
 
-func InitializeTransactionHandler() (*handler.TransactionHandler, error) {
+func InitializeRouter() (*gin.Engine, error) {
 	wire.Build(ProviderSet)
-	return &handler.TransactionHandler{}, nil
+	return &gin.Engine{}, nil
 }
 
 func InitializeMessageHandler() (*handler.MessageHandler, error) {
 	wire.Build(ProviderSet)
 	return &handler.MessageHandler{}, nil
 }
+
+func InitializeReportScheduler() (*handler.ReportScheduler, error) {
+	wire.Build(ProviderSet)
+	return &handler.ReportScheduler{}, nil
+}
+
+// InitializePortabilityService 供命令列工具匯出與匯入用戶資料，不需要啟動 API 與消息佇列
+func InitializePortabilityService() (service.PortabilityService, error) {
+	wire.Build(ProviderSet)
+	return nil, nil
+}
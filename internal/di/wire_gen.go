@@ -11,15 +11,17 @@ import (
 	"fintrack/internal/cache"
 	"fintrack/internal/db"
 	"fintrack/internal/handler"
+	"fintrack/internal/mail"
 	"fintrack/internal/mq"
 	"fintrack/internal/service"
+	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 	"sync"
 )
 
 // Injectors from wire.go:
 
-func InitializeTransactionHandler() (*handler.TransactionHandler, error) {
+func InitializeRouter() (*gin.Engine, error) {
 	config := NewConfig()
 	dbClient, err := NewDBClient(config)
 	if err != nil {
@@ -29,7 +31,25 @@ func InitializeTransactionHandler() (*handler.TransactionHandler, error) {
 	mqProducer := NewRabbitMQProducer(config)
 	transactionService := service.NewTransactionService(dbClient, cache, mqProducer)
 	transactionHandler := handler.NewTransactionHandler(transactionService)
-	return transactionHandler, nil
+	recurringService := service.NewRecurringService(dbClient, cache)
+	recurringHandler := handler.NewRecurringHandler(recurringService)
+	accountService := service.NewAccountService(dbClient, cache, mqProducer)
+	accountHandler := handler.NewAccountHandler(accountService)
+	loanService := service.NewLoanService(dbClient, cache, mqProducer)
+	loanHandler := handler.NewLoanHandler(loanService)
+	investmentService := service.NewInvestmentService(dbClient, cache)
+	investmentHandler := handler.NewInvestmentHandler(investmentService)
+	portabilityService := service.NewPortabilityService(dbClient)
+	portabilityHandler := handler.NewPortabilityHandler(portabilityService)
+	sender := NewMailSender(config)
+	subscriptionService := service.NewSubscriptionService(dbClient, transactionService, sender)
+	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionService)
+	taxService := service.NewTaxService(dbClient)
+	taxHandler := handler.NewTaxHandler(taxService)
+	settingsService := service.NewSettingsService(dbClient)
+	settingsHandler := handler.NewSettingsHandler(settingsService)
+	engine := handler.NewRouter(transactionHandler, recurringHandler, accountHandler, loanHandler, investmentHandler, portabilityHandler, subscriptionHandler, taxHandler, settingsHandler)
+	return engine, nil
 }
 
 func InitializeMessageHandler() (*handler.MessageHandler, error) {
@@ -38,12 +58,39 @@ func InitializeMessageHandler() (*handler.MessageHandler, error) {
 	if err != nil {
 		return nil, err
 	}
-	messageService := service.NewMessageService(dbClient)
+	cache := NewRedisCache(config)
+	messageService := service.NewMessageService(dbClient, cache)
 	mqConsumer := NewRabbitMQConsumer(config)
 	messageHandler := handler.NewMessageHandler(messageService, mqConsumer)
 	return messageHandler, nil
 }
 
+func InitializeReportScheduler() (*handler.ReportScheduler, error) {
+	config := NewConfig()
+	dbClient, err := NewDBClient(config)
+	if err != nil {
+		return nil, err
+	}
+	cache := NewRedisCache(config)
+	mqProducer := NewRabbitMQProducer(config)
+	transactionService := service.NewTransactionService(dbClient, cache, mqProducer)
+	sender := NewMailSender(config)
+	subscriptionService := service.NewSubscriptionService(dbClient, transactionService, sender)
+	reportScheduler := handler.NewReportScheduler(subscriptionService)
+	return reportScheduler, nil
+}
+
+// InitializePortabilityService 供命令列工具匯出與匯入用戶資料，不需要啟動 API 與消息佇列
+func InitializePortabilityService() (service.PortabilityService, error) {
+	config := NewConfig()
+	dbClient, err := NewDBClient(config)
+	if err != nil {
+		return nil, err
+	}
+	portabilityService := service.NewPortabilityService(dbClient)
+	return portabilityService, nil
+}
+
 // wire.go:
 
 var (
@@ -59,6 +106,9 @@ var (
 
 	redisOnce  sync.Once
 	redisCache *cache.Redis
+
+	mailOnce   sync.Once
+	mailSender *mail.SMTP
 )
 
 func NewConfig() *config.Config {
@@ -83,6 +133,13 @@ func NewRedisCache(cfg2 *config.Config) cache.Cache {
 	return redisCache
 }
 
+func NewMailSender(cfg2 *config.Config) mail.Sender {
+	mailOnce.Do(func() {
+		mailSender = mail.NewSMTP(cfg2.SMTPConfig)
+	})
+	return mailSender
+}
+
 // NewRabbitMQProducer 確保 RabbitMQ 生產者僅初始化一次
 func NewRabbitMQProducer(cfg2 *config.Config) mq.MQProducer {
 	rabbitMQOnce.Do(func() {
@@ -104,6 +161,7 @@ var ProviderSet = wire.NewSet(
 	NewConfig,
 	NewDBClient,
 	NewRedisCache,
+	NewMailSender,
 	NewRabbitMQProducer,
-	NewRabbitMQConsumer, service.NewTransactionService, handler.NewTransactionHandler, service.NewMessageService, handler.NewMessageHandler,
+	NewRabbitMQConsumer, service.NewTransactionService, handler.NewTransactionHandler, service.NewMessageService, handler.NewMessageHandler, service.NewRecurringService, handler.NewRecurringHandler, service.NewAccountService, handler.NewAccountHandler, service.NewLoanService, handler.NewLoanHandler, service.NewInvestmentService, handler.NewInvestmentHandler, service.NewPortabilityService, handler.NewPortabilityHandler, service.NewSubscriptionService, handler.NewSubscriptionHandler, handler.NewReportScheduler, service.NewTaxService, handler.NewTaxHandler, service.NewSettingsService, handler.NewSettingsHandler, handler.NewRouter,
 )
@@ -0,0 +1,72 @@
+package entity
+
+import "time"
+
+// 帳戶類型
+const (
+	AccountTypeBank       = "BANK"
+	AccountTypeCreditCard = "CREDIT_CARD"
+	AccountTypeCash       = "CASH"
+	AccountTypeInvestment = "INVESTMENT"
+)
+
+// Account 表示用戶的銀行帳戶、信用卡、現金或證券帳戶
+type Account struct {
+	ID               string    `gorm:"primaryKey" json:"id"`
+	UserID           string    `gorm:"index" json:"user_id"`
+	Name             string    `gorm:"column:account_name;type:varchar(100)" json:"account_name"`
+	Type             string    `gorm:"column:account_type;type:enum('BANK', 'CREDIT_CARD', 'CASH', 'INVESTMENT')" json:"account_type"`
+	OpeningBalance   float64   `json:"opening_balance"`
+	ClosingDay       int       `json:"closing_day,omitempty"`                                // 信用卡結帳日 (1-31)，超過當月天數時以月底為準
+	DueDay           int       `json:"due_day,omitempty"`                                    // 信用卡繳款截止日 (1-31)
+	PaymentAccountID string    `gorm:"type:varchar(36)" json:"payment_account_id,omitempty"` // 信用卡自動扣繳的帳戶
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// IsLiability 判斷帳戶是否為負債（信用卡）
+func (a Account) IsLiability() bool {
+	return a.Type == AccountTypeCreditCard
+}
+
+// ClosingDate 返回指定月份的結帳日
+func (a Account) ClosingDate(year int, month time.Month, loc *time.Location) time.Time {
+	return clampDay(year, month, a.ClosingDay, loc)
+}
+
+// DueDate 返回某期帳單的繳款截止日，截止日不大於結帳日時落在次月
+func (a Account) DueDate(closing time.Time) time.Time {
+	if a.DueDay > a.ClosingDay {
+		return clampDay(closing.Year(), closing.Month(), a.DueDay, closing.Location())
+	}
+	next := closing.AddDate(0, 0, 1-closing.Day()).AddDate(0, 1, 0)
+	return clampDay(next.Year(), next.Month(), a.DueDay, closing.Location())
+}
+
+// StatementClosingFor 返回交易所屬帳單的結帳日，結帳日當天的消費計入當期
+func (a Account) StatementClosingFor(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	closing := a.ClosingDate(t.Year(), t.Month(), t.Location())
+	if day.After(closing) {
+		next := time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		closing = a.ClosingDate(next.Year(), next.Month(), t.Location())
+	}
+	return closing
+}
+
+// StatementPeriod 返回某期帳單的消費期間 (上期結帳日, 本期結帳日]
+func (a Account) StatementPeriod(closing time.Time) (time.Time, time.Time) {
+	prev := closing.AddDate(0, 0, 1-closing.Day()).AddDate(0, -1, 0)
+	return a.ClosingDate(prev.Year(), prev.Month(), closing.Location()), closing
+}
+
+// clampDay 建立指定日期，日數超過當月天數時以月底代替
+func clampDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	if day < 1 {
+		day = 1
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+// InstallmentPlan 表示信用卡分期付款計畫，每期產生一筆子交易
+type InstallmentPlan struct {
+	ID           string    `gorm:"primaryKey" json:"id"`
+	UserID       string    `gorm:"index" json:"user_id"`
+	AccountID    string    `gorm:"type:varchar(36);index" json:"account_id"`
+	Payee        string    `gorm:"type:varchar(100)" json:"payee"`
+	Category     string    `json:"category"`
+	Description  string    `json:"description"`
+	PurchaseDate time.Time `json:"purchase_date"`
+	TotalAmount  float64   `json:"total_amount"`
+	Installments int       `json:"installments"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Amounts 將總金額分攤至各期，以分為單位計算，無法整除的餘數併入第一期
+func (p InstallmentPlan) Amounts() []float64 {
+	if p.Installments <= 0 {
+		return nil
+	}
+	cents := int64(p.TotalAmount*100 + 0.5)
+	base := cents / int64(p.Installments)
+	amounts := make([]float64, p.Installments)
+	for i := range amounts {
+		amounts[i] = float64(base) / 100
+	}
+	amounts[0] = float64(cents-base*int64(p.Installments-1)) / 100
+	return amounts
+}
+
+// ChargeDate 返回第 n 期（從 1 開始）的扣款日期，每期間隔一個月，日數超過當月天數時以月底為準
+func (p InstallmentPlan) ChargeDate(n int) time.Time {
+	month := time.Date(p.PurchaseDate.Year(), p.PurchaseDate.Month()+time.Month(n-1), 1, 0, 0, 0, 0, p.PurchaseDate.Location())
+	return clampDay(month.Year(), month.Month(), p.PurchaseDate.Day(), month.Location())
+}
@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallmentPlanAmounts(t *testing.T) {
+	plan := InstallmentPlan{TotalAmount: 10000, Installments: 3}
+	assert.Equal(t, []float64{3333.34, 3333.33, 3333.33}, plan.Amounts())
+
+	plan = InstallmentPlan{TotalAmount: 36000, Installments: 12}
+	amounts := plan.Amounts()
+	assert.Len(t, amounts, 12)
+	assert.Equal(t, 3000.0, amounts[0])
+}
+
+func TestInstallmentPlanChargeDate(t *testing.T) {
+	plan := InstallmentPlan{PurchaseDate: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)}
+	assert.Equal(t, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), plan.ChargeDate(1))
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), plan.ChargeDate(2))
+	assert.Equal(t, time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), plan.ChargeDate(3))
+}
+
+func TestStatementClosingFor(t *testing.T) {
+	card := Account{Type: AccountTypeCreditCard, ClosingDay: 5, DueDay: 20}
+	// 結帳日當天的消費計入當期
+	assert.Equal(t, time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC), card.StatementClosingFor(time.Date(2024, 6, 5, 22, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2024, 7, 5, 0, 0, 0, 0, time.UTC), card.StatementClosingFor(time.Date(2024, 6, 6, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), card.StatementClosingFor(time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC)))
+
+	endOfMonth := Account{Type: AccountTypeCreditCard, ClosingDay: 31, DueDay: 15}
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), endOfMonth.StatementClosingFor(time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), endOfMonth.DueDate(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)))
+}
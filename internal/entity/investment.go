@@ -0,0 +1,42 @@
+package entity
+
+import "time"
+
+// 投資交易類型
+const (
+	TradeBuy      = "BUY"
+	TradeSell     = "SELL"
+	TradeDividend = "DIVIDEND"
+	TradeSplit    = "SPLIT"
+)
+
+// Security 表示股票、ETF 等有價證券
+type Security struct {
+	Symbol    string    `gorm:"primaryKey;type:varchar(20)" json:"symbol"`
+	Name      string    `gorm:"type:varchar(100)" json:"name"`
+	Currency  string    `gorm:"type:varchar(3)" json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Trade 表示投資帳戶中的一筆證券交易
+type Trade struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	UserID    string    `gorm:"index" json:"user_id"`
+	AccountID string    `gorm:"type:varchar(36);index" json:"account_id"`
+	Symbol    string    `gorm:"type:varchar(20);index" json:"symbol"`
+	Type      string    `gorm:"type:enum('BUY', 'SELL', 'DIVIDEND', 'SPLIT')" json:"type"`
+	Date      time.Time `gorm:"index" json:"date"`
+	Quantity  float64   `json:"quantity"` // 買賣股數
+	Price     float64   `json:"price"`    // 每股成交價
+	Fee       float64   `json:"fee"`      // 手續費與交易稅
+	Amount    float64   `json:"amount"`   // 現金股利金額
+	Ratio     float64   `json:"ratio"`    // 分割比例，每一股變為幾股
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SecurityPrice 表示證券某日的收盤價
+type SecurityPrice struct {
+	Symbol string    `gorm:"primaryKey;type:varchar(20)" json:"symbol"`
+	Date   time.Time `gorm:"primaryKey;type:date" json:"date"`
+	Close  float64   `json:"close"`
+}
@@ -0,0 +1,107 @@
+package entity
+
+import (
+	"math"
+	"time"
+)
+
+// 貸款還款拆分後使用的分類
+const (
+	CategoryLoanPrincipal = "LOAN_PRINCIPAL"
+	CategoryLoanInterest  = "LOAN_INTEREST"
+)
+
+// Loan 表示房貸、車貸等本息平均攤還的貸款
+type Loan struct {
+	ID         string    `gorm:"primaryKey" json:"id"`
+	UserID     string    `gorm:"index" json:"user_id"`
+	AccountID  string    `gorm:"type:varchar(36)" json:"account_id"` // 扣繳還款的帳戶
+	Name       string    `gorm:"type:varchar(100)" json:"name"`
+	Principal  float64   `json:"principal"`
+	AnnualRate float64   `json:"annual_rate"` // 年利率 (%)
+	TermMonths int       `json:"term_months"`
+	PaymentDay int       `json:"payment_day"` // 每月還款日 (1-31)
+	StartDate  time.Time `json:"start_date"`  // 撥款日，首期於次月還款
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// LoanPayment 表示一筆已記錄的還款及其本金、利息拆分
+type LoanPayment struct {
+	ID                 string    `gorm:"primaryKey" json:"id"`
+	LoanID             string    `gorm:"type:varchar(36);index" json:"loan_id"`
+	UserID             string    `gorm:"index" json:"user_id"`
+	Date               time.Time `json:"date"`
+	Amount             float64   `json:"amount"`
+	Principal          float64   `json:"principal"`
+	Interest           float64   `json:"interest"`
+	RemainingPrincipal float64   `json:"remaining_principal"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// AmortizationRow 表示攤還表中的一期
+type AmortizationRow struct {
+	No                 int       `json:"no"`
+	Date               time.Time `json:"date"`
+	Payment            float64   `json:"payment"`
+	Principal          float64   `json:"principal"`
+	Interest           float64   `json:"interest"`
+	RemainingPrincipal float64   `json:"remaining_principal"`
+}
+
+// MonthlyRate 返回月利率
+func (l Loan) MonthlyRate() float64 {
+	return l.AnnualRate / 100 / 12
+}
+
+// MonthlyPayment 返回本息平均攤還的每期應繳金額
+func (l Loan) MonthlyPayment() float64 {
+	if l.TermMonths <= 0 {
+		return 0
+	}
+	r := l.MonthlyRate()
+	if r == 0 {
+		return roundCents(l.Principal / float64(l.TermMonths))
+	}
+	return roundCents(l.Principal * r / (1 - math.Pow(1+r, -float64(l.TermMonths))))
+}
+
+// PaymentDate 返回第 n 期（從 1 開始）的還款日
+func (l Loan) PaymentDate(n int) time.Time {
+	month := time.Date(l.StartDate.Year(), l.StartDate.Month()+time.Month(n), 1, 0, 0, 0, 0, l.StartDate.Location())
+	return clampDay(month.Year(), month.Month(), l.PaymentDay, month.Location())
+}
+
+// Split 依剩餘本金將一筆還款拆分為利息與本金，還款不足利息時全數視為利息
+func (l Loan) Split(amount, remaining float64) (principal, interest float64) {
+	interest = math.Min(roundCents(remaining*l.MonthlyRate()), amount)
+	principal = math.Min(roundCents(amount-interest), remaining)
+	return principal, interest
+}
+
+// Schedule 產生完整的攤還表，最後一期會補足四捨五入造成的差額
+func (l Loan) Schedule() []AmortizationRow {
+	payment := l.MonthlyPayment()
+	remaining := l.Principal
+	rows := make([]AmortizationRow, 0, l.TermMonths)
+	for n := 1; n <= l.TermMonths; n++ {
+		amount := payment
+		if n == l.TermMonths {
+			amount = roundCents(remaining + remaining*l.MonthlyRate())
+		}
+		principal, interest := l.Split(amount, remaining)
+		remaining = roundCents(remaining - principal)
+		rows = append(rows, AmortizationRow{
+			No:                 n,
+			Date:               l.PaymentDate(n),
+			Payment:            amount,
+			Principal:          principal,
+			Interest:           interest,
+			RemainingPrincipal: remaining,
+		})
+	}
+	return rows
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
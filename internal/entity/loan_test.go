@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoanSchedule(t *testing.T) {
+	loan := Loan{Principal: 1000000, AnnualRate: 2.4, TermMonths: 240, PaymentDay: 10, StartDate: time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)}
+
+	assert.Equal(t, 5250.45, loan.MonthlyPayment())
+
+	schedule := loan.Schedule()
+	assert.Len(t, schedule, 240)
+	assert.Equal(t, time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC), schedule[0].Date)
+	assert.Equal(t, 2000.0, schedule[0].Interest)
+	assert.Equal(t, 3250.45, schedule[0].Principal)
+	assert.Equal(t, 996749.55, schedule[0].RemainingPrincipal)
+	assert.Equal(t, 0.0, schedule[239].RemainingPrincipal)
+}
+
+func TestLoanScheduleWithoutInterest(t *testing.T) {
+	loan := Loan{Principal: 1000, TermMonths: 3, PaymentDay: 31, StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	schedule := loan.Schedule()
+	assert.Equal(t, []float64{333.33, 333.33, 333.34}, []float64{schedule[0].Payment, schedule[1].Payment, schedule[2].Payment})
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), schedule[0].Date)
+	assert.Equal(t, 0.0, schedule[2].RemainingPrincipal)
+}
+
+func TestLoanSplit(t *testing.T) {
+	loan := Loan{AnnualRate: 12}
+
+	principal, interest := loan.Split(5000, 100000)
+	assert.Equal(t, 1000.0, interest)
+	assert.Equal(t, 4000.0, principal)
+
+	// 還款不足利息時全數視為利息
+	principal, interest = loan.Split(500, 100000)
+	assert.Equal(t, 500.0, interest)
+	assert.Equal(t, 0.0, principal)
+}
@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// NetWorthSnapshot 表示某用戶某月底單一帳戶的餘額快照，用於避免每次重新彙總所有歷史交易
+type NetWorthSnapshot struct {
+	UserID    string    `gorm:"primaryKey;type:varchar(36)" json:"user_id"`
+	Month     time.Time `gorm:"primaryKey;type:date" json:"month"`             // 月底日期
+	AccountID string    `gorm:"primaryKey;type:varchar(36)" json:"account_id"` // 未指定帳戶的交易為空字串
+	Balance   float64   `json:"balance"`                                       // 月底前的交易淨額累計，不含期初餘額
+	Holdings  float64   `json:"holdings"`                                      // 投資帳戶的持股市值
+	CreatedAt time.Time `json:"created_at"`
+}
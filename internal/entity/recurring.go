@@ -0,0 +1,96 @@
+package entity
+
+import "time"
+
+// Cadence 表示週期性交易的頻率
+type Cadence string
+
+const (
+	CadenceWeekly    Cadence = "WEEKLY"
+	CadenceBiweekly  Cadence = "BIWEEKLY"
+	CadenceMonthly   Cadence = "MONTHLY"
+	CadenceQuarterly Cadence = "QUARTERLY"
+	CadenceYearly    Cadence = "YEARLY"
+)
+
+// Cadences 依週期由短到長排列，供偵測時比對
+var Cadences = []Cadence{CadenceWeekly, CadenceBiweekly, CadenceMonthly, CadenceQuarterly, CadenceYearly}
+
+// Days 返回週期的近似天數
+func (c Cadence) Days() int {
+	switch c {
+	case CadenceWeekly:
+		return 7
+	case CadenceBiweekly:
+		return 14
+	case CadenceMonthly:
+		return 30
+	case CadenceQuarterly:
+		return 91
+	case CadenceYearly:
+		return 365
+	}
+	return 0
+}
+
+// Next 返回下一次發生的日期，月份類週期使用日曆月份計算
+func (c Cadence) Next(t time.Time) time.Time {
+	switch c {
+	case CadenceWeekly:
+		return t.AddDate(0, 0, 7)
+	case CadenceBiweekly:
+		return t.AddDate(0, 0, 14)
+	case CadenceMonthly:
+		return t.AddDate(0, 1, 0)
+	case CadenceQuarterly:
+		return t.AddDate(0, 3, 0)
+	case CadenceYearly:
+		return t.AddDate(1, 0, 0)
+	}
+	return t
+}
+
+// Valid 檢查是否為支援的週期
+func (c Cadence) Valid() bool {
+	return c.Days() > 0
+}
+
+// RecurringSchedule 表示受管理的週期性收支排程（如訂閱、薪資、房租）
+type RecurringSchedule struct {
+	ID          string     `gorm:"primaryKey" json:"id"`
+	UserID      string     `gorm:"index" json:"user_id"`
+	Payee       string     `gorm:"type:varchar(100)" json:"payee"`
+	Category    string     `json:"category"`
+	Type        string     `gorm:"type:varchar(10)" json:"type"`
+	Cadence     Cadence    `gorm:"type:enum('WEEKLY', 'BIWEEKLY', 'MONTHLY', 'QUARTERLY', 'YEARLY')" json:"cadence"`
+	Amount      float64    `json:"amount"`
+	Description string     `json:"description"`
+	StartDate   time.Time  `json:"start_date"`
+	NextDate    time.Time  `gorm:"index" json:"next_date"`
+	EndDate     *time.Time `json:"end_date,omitempty"`
+	Active      bool       `json:"active"`
+	AccountID   string     `gorm:"type:varchar(36)" json:"account_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// IsIncome 判斷排程是否為收入
+func (s RecurringSchedule) IsIncome() bool {
+	return s.Type == TypeIncome
+}
+
+// Occurrences 返回排程在 [from, to] 區間內的所有預期發生日期
+func (s RecurringSchedule) Occurrences(from, to time.Time) []time.Time {
+	var dates []time.Time
+	if !s.Active || !s.Cadence.Valid() {
+		return dates
+	}
+	for d := s.NextDate; !d.After(to); d = s.Cadence.Next(d) {
+		if s.EndDate != nil && d.After(*s.EndDate) {
+			break
+		}
+		if !d.Before(from) {
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"time"
+	_ "time/tzdata" // 執行環境可能沒有系統時區資料
+)
+
+// DefaultTimeZone 為未設定時區的用戶使用的時區
+const DefaultTimeZone = "UTC"
+
+// UserSettings 表示用戶的報表偏好，時區決定交易歸屬的日期與月份
+type UserSettings struct {
+	UserID          string    `gorm:"primaryKey;type:varchar(36)" json:"user_id"`
+	TimeZone        string    `gorm:"type:varchar(64)" json:"time_zone"` // IANA 時區名稱，如 Asia/Taipei
+	FiscalYearStart int       `json:"fiscal_year_start"`                 // 會計年度起始月份 (1-12)，1 為曆年
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// DefaultUserSettings 返回未設定偏好時的預設值
+func DefaultUserSettings(userID string) UserSettings {
+	return UserSettings{UserID: userID, TimeZone: DefaultTimeZone, FiscalYearStart: 1}
+}
+
+// Location 返回用戶的時區，無法辨識時使用 UTC
+func (s UserSettings) Location() *time.Location {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil || s.TimeZone == "" {
+		return time.UTC
+	}
+	return loc
+}
+
+// FiscalStartMonth 返回會計年度起始月份，未設定或超出範圍時為一月
+func (s UserSettings) FiscalStartMonth() time.Month {
+	if s.FiscalYearStart < 1 || s.FiscalYearStart > 12 {
+		return time.January
+	}
+	return time.Month(s.FiscalYearStart)
+}
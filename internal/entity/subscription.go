@@ -0,0 +1,56 @@
+package entity
+
+import "time"
+
+// 報表寄送狀態
+const (
+	DeliveryPending = "PENDING" // 等待寄送或重試
+	DeliverySent    = "SENT"
+	DeliveryFailed  = "FAILED" // 超過重試次數
+)
+
+// ReportSubscription 表示用戶訂閱的定期報表，於 NextRunAt 寄送上一個週期的報表
+type ReportSubscription struct {
+	ID         string    `gorm:"primaryKey" json:"id"`
+	UserID     string    `gorm:"index" json:"user_id"`
+	ReportType string    `gorm:"type:varchar(20)" json:"report_type"`
+	Format     string    `gorm:"type:varchar(10)" json:"format"` // json、csv、xlsx 或 pdf
+	Cadence    Cadence   `gorm:"type:enum('WEEKLY', 'BIWEEKLY', 'MONTHLY', 'QUARTERLY', 'YEARLY')" json:"cadence"`
+	Recipient  string    `gorm:"type:varchar(255)" json:"recipient"`
+	NextRunAt  time.Time `gorm:"index" json:"next_run_at"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ReportPeriod 返回某次寄送涵蓋的期間，為寄送日之前完整的一個週期 [start, end]
+func (s ReportSubscription) ReportPeriod(runAt time.Time) (time.Time, time.Time) {
+	end := time.Date(runAt.Year(), runAt.Month(), runAt.Day(), 0, 0, 0, 0, runAt.Location())
+	var start time.Time
+	switch s.Cadence {
+	case CadenceMonthly:
+		start = end.AddDate(0, -1, 0)
+	case CadenceQuarterly:
+		start = end.AddDate(0, -3, 0)
+	case CadenceYearly:
+		start = end.AddDate(-1, 0, 0)
+	default:
+		start = end.AddDate(0, 0, -s.Cadence.Days())
+	}
+	return start, end.AddDate(0, 0, -1)
+}
+
+// ReportDelivery 表示一次報表寄送，失敗時依 NextAttemptAt 重試
+type ReportDelivery struct {
+	ID             string     `gorm:"primaryKey" json:"id"` // 訂閱 ID 加上期間開始日，同一期間只會寄送一次
+	SubscriptionID string     `gorm:"type:varchar(36);index" json:"subscription_id"`
+	UserID         string     `gorm:"index" json:"user_id"`
+	Recipient      string     `gorm:"type:varchar(255)" json:"recipient"`
+	PeriodStart    time.Time  `gorm:"type:date" json:"period_start"`
+	PeriodEnd      time.Time  `gorm:"type:date" json:"period_end"`
+	Status         string     `gorm:"type:enum('PENDING', 'SENT', 'FAILED');index" json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt  time.Time  `gorm:"index" json:"next_attempt_at"`
+	SentAt         *time.Time `json:"sent_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,83 @@
+package entity
+
+// TaxBucket 表示綜合所得稅申報的所得類別或扣除項目
+type TaxBucket string
+
+// 所得類別
+const (
+	TaxSalary         TaxBucket = "SALARY"          // 薪資所得
+	TaxInterestIncome TaxBucket = "INTEREST_INCOME" // 利息所得
+	TaxDividendIncome TaxBucket = "DIVIDEND_INCOME" // 股利所得
+	TaxRentalIncome   TaxBucket = "RENTAL_INCOME"   // 租賃所得
+	TaxOtherIncome    TaxBucket = "OTHER_INCOME"    // 其他所得
+)
+
+// 扣除項目
+const (
+	TaxInsurance        TaxBucket = "INSURANCE"         // 保險費
+	TaxMedical          TaxBucket = "MEDICAL"           // 醫藥及生育費
+	TaxDonation         TaxBucket = "DONATION"          // 捐贈
+	TaxRent             TaxBucket = "RENT"              // 房屋租金支出
+	TaxMortgageInterest TaxBucket = "MORTGAGE_INTEREST" // 自用住宅購屋借款利息
+)
+
+// TaxBuckets 依申報順序列出所有類別與中文名稱
+var TaxBuckets = []struct {
+	Bucket TaxBucket
+	Name   string
+}{
+	{TaxSalary, "薪資所得"},
+	{TaxInterestIncome, "利息所得"},
+	{TaxDividendIncome, "股利所得"},
+	{TaxRentalIncome, "租賃所得"},
+	{TaxOtherIncome, "其他所得"},
+	{TaxInsurance, "保險費"},
+	{TaxMedical, "醫藥及生育費"},
+	{TaxDonation, "捐贈"},
+	{TaxRent, "房屋租金支出"},
+	{TaxMortgageInterest, "購屋借款利息"},
+}
+
+// Valid 檢查是否為支援的類別
+func (b TaxBucket) Valid() bool {
+	for _, t := range TaxBuckets {
+		if t.Bucket == b {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIncome 判斷類別是否為所得，其餘為扣除項目
+func (b TaxBucket) IsIncome() bool {
+	switch b {
+	case TaxSalary, TaxInterestIncome, TaxDividendIncome, TaxRentalIncome, TaxOtherIncome:
+		return true
+	}
+	return false
+}
+
+// 稅務對應的比對方式
+const (
+	TaxMatchCategory = "CATEGORY"
+	TaxMatchTag      = "TAG"
+)
+
+// TaxMapping 表示將交易分類或標籤對應至申報類別的規則，標籤優先於分類
+type TaxMapping struct {
+	UserID string    `gorm:"primaryKey;type:varchar(36)" json:"user_id"`
+	Match  string    `gorm:"primaryKey;type:varchar(10)" json:"match"` // CATEGORY 或 TAG
+	Value  string    `gorm:"primaryKey;type:varchar(100)" json:"value"`
+	Bucket TaxBucket `gorm:"type:varchar(30)" json:"bucket"`
+}
+
+// DefaultTaxMappings 為用戶未設定對應時使用的分類對應
+var DefaultTaxMappings = []TaxMapping{
+	{Match: TaxMatchCategory, Value: "SALARY", Bucket: TaxSalary},
+	{Match: TaxMatchCategory, Value: "INTEREST", Bucket: TaxInterestIncome},
+	{Match: TaxMatchCategory, Value: "DIVIDEND", Bucket: TaxDividendIncome},
+	{Match: TaxMatchCategory, Value: "INSURANCE", Bucket: TaxInsurance},
+	{Match: TaxMatchCategory, Value: "MEDICAL", Bucket: TaxMedical},
+	{Match: TaxMatchCategory, Value: "DONATION", Bucket: TaxDonation},
+	{Match: TaxMatchCategory, Value: "RENT", Bucket: TaxRent},
+}
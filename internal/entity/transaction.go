@@ -1,14 +1,61 @@
 package entity
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+// 交易類型
+const (
+	TypeIncome  = "INCOME"
+	TypeExpense = "EXPENSE"
+)
 
 type Transaction struct {
 	ID          string    `gorm:"primaryKey"`
-	UserID      string    `gorm:"index"`
-	Date        time.Time `gorm:"index"`
+	UserID      string    `gorm:"index;index:idx_transactions_user_date,priority:1"`
+	Date        time.Time `gorm:"index;index:idx_transactions_user_date,priority:2"` // 與 user_id 的複合索引供交易列表依日期分頁
 	Amount      float64
 	Category    string
-	Description string
+	Description string `gorm:"index:idx_transactions_search,class:FULLTEXT,option:WITH PARSER ngram,priority:1"` // 全文索引以 ngram 分詞，支援中文
+	Notes       string `gorm:"type:text;index:idx_transactions_search,priority:3"`                               // 用戶的備註
 	Source      string `gorm:"type:enum('MANUAL', 'BANK', 'CREDIT_CARD')"`
 	Reconciled  bool
+	Type        string `gorm:"type:varchar(10)"` // INCOME 或 EXPENSE，未指定時依分類判斷
+	Payee       string `gorm:"type:varchar(100);index;index:idx_transactions_search,priority:2"`
+	AccountID   string `gorm:"type:varchar(36);index"`
+	Tags        string `gorm:"type:varchar(255)"` // 以逗號分隔的標籤
+
+	StatementCycle    string `gorm:"type:varchar(10);index"` // 信用卡帳單結帳日 (YYYY-MM-DD)
+	InstallmentPlanID string `gorm:"type:varchar(36);index"` // 分期付款計畫
+	InstallmentNo     int    // 分期的第幾期
+
+	ExternalID string `gorm:"type:varchar(100)"` // 客戶端提供的 ID，僅作為外部參照
+}
+
+// IsIncome 判斷交易是否為收入，未指定類型時沿用分類 (INCOME) 判斷
+func (t Transaction) IsIncome() bool {
+	if t.Type != "" {
+		return t.Type == TypeIncome
+	}
+	return t.Category == TypeIncome
+}
+
+// SignedAmount 返回帶正負號的金額，收入為正、支出為負
+func (t Transaction) SignedAmount() float64 {
+	if t.IsIncome() {
+		return t.Amount
+	}
+	return -t.Amount
+}
+
+// TagList 返回去除空白後的標籤
+func (t Transaction) TagList() []string {
+	var tags []string
+	for _, tag := range strings.Split(t.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
 }
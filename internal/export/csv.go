@@ -0,0 +1,44 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// utf8BOM 讓 Excel 以 UTF-8 開啟檔案，避免中文分類與描述顯示為亂碼
+const utf8BOM = "\ufeff"
+
+// WriteCSV 依序輸出所有表格，每張表格前為表格名稱列，表格之間以空白列分隔
+func WriteCSV(w io.Writer, doc *Document) error {
+	if _, err := io.WriteString(w, utf8BOM); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	for i, table := range doc.Tables {
+		if i > 0 {
+			if err := writer.Write([]string{}); err != nil {
+				return err
+			}
+		}
+		if len(doc.Tables) > 1 {
+			if err := writer.Write([]string{table.Name}); err != nil {
+				return err
+			}
+		}
+		if err := writer.Write(table.Header); err != nil {
+			return err
+		}
+		for _, row := range table.Rows {
+			record := make([]string, len(row))
+			for j, cell := range row {
+				record[j] = formatCell(cell)
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,93 @@
+// Package export 將報表輸出為 CSV、XLSX 與 PDF 檔案
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// 支援的輸出格式
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	FormatPDF  = "pdf"
+)
+
+// ErrUnsupportedFormat 表示不支援的輸出格式
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+var contentTypes = map[string]string{
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatPDF:  "application/pdf",
+}
+
+// Document 表示與格式無關的報表內容
+type Document struct {
+	Title  string
+	Tables []Table
+	Charts []Chart
+}
+
+// Table 表示一張表格，在 XLSX 中為一個工作表
+type Table struct {
+	Name   string
+	Header []string
+	Rows   [][]interface{} // 儲存格為 string、float64 或 int
+}
+
+// Chart 表示一張橫條圖，只在 PDF 中繪製
+type Chart struct {
+	Title string
+	Bars  []Bar
+}
+
+// Bar 表示橫條圖中的一個項目
+type Bar struct {
+	Label string
+	Value float64
+}
+
+// Supported 判斷是否支援該輸出格式
+func Supported(format string) bool {
+	_, ok := contentTypes[format]
+	return ok
+}
+
+// ContentType 返回輸出格式的 MIME 類型
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Write 以指定格式輸出報表
+func Write(w io.Writer, doc *Document, format string) error {
+	switch format {
+	case FormatCSV:
+		return WriteCSV(w, doc)
+	case FormatXLSX:
+		return WriteXLSX(w, doc)
+	case FormatPDF:
+		return WritePDF(w, doc)
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// formatCell 將儲存格轉為文字，數字不使用科學記號
+func formatCell(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(value)
+	case time.Time:
+		return value.Format("2006-01-02")
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,76 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fintrack/internal/export"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleDocument() *export.Document {
+	return &export.Document{
+		Title: "Monthly report 2024-03-01 - 2024-03-31",
+		Tables: []export.Table{
+			{Name: "Summary", Header: []string{"Period", "Income", "Expense"}, Rows: [][]interface{}{{"2024-03", 50000.0, 12345.5}}},
+			{Name: "By Category", Header: []string{"Category", "Amount", "Count"}, Rows: [][]interface{}{{"餐飲", 8000.0, 25}, {"交通", 4345.5, 10}}},
+		},
+		Charts: []export.Chart{{Title: "Expense by category", Bars: []export.Bar{{Label: "餐飲", Value: 8000}, {Label: "交通", Value: 4345.5}}}},
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, export.Write(&buf, sampleDocument(), export.FormatCSV))
+
+	assert.Equal(t, "\ufeffSummary\nPeriod,Income,Expense\n2024-03,50000,12345.5\n\nBy Category\nCategory,Amount,Count\n餐飲,8000,25\n交通,4345.5,10\n", buf.String())
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, export.Write(&buf, sampleDocument(), export.FormatXLSX))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	files := make(map[string]string)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		assert.NoError(t, err)
+		content, _ := io.ReadAll(r)
+		files[f.Name] = string(content)
+	}
+
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="Summary" sheetId="1" r:id="rId1"/>`)
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="By Category" sheetId="2" r:id="rId2"/>`)
+	assert.Contains(t, files["xl/worksheets/sheet1.xml"], `<c r="B2"><v>50000</v></c>`)
+	assert.Contains(t, files["xl/worksheets/sheet2.xml"], `<c r="A2" t="inlineStr"><is><t xml:space="preserve">餐飲</t></is></c>`)
+	assert.Contains(t, files["[Content_Types].xml"], "/xl/worksheets/sheet2.xml")
+}
+
+func TestWritePDF(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, export.Write(&buf, sampleDocument(), export.FormatPDF))
+
+	pdf := buf.String()
+	assert.True(t, strings.HasPrefix(pdf, "%PDF-1.4"))
+	assert.Contains(t, pdf, "/BaseFont /MSung-Light /Encoding /UniCNS-UCS2-H")
+	assert.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+
+	start := strings.Index(pdf, "stream\n") + len("stream\n")
+	end := strings.Index(pdf, "\nendstream")
+	r, err := zlib.NewReader(strings.NewReader(pdf[start:end]))
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(r)
+
+	// 中文以 UCS-2 編碼輸出：餐 U+9910、飲 U+98F2
+	assert.Contains(t, string(content), "<991098F2> Tj")
+	assert.Contains(t, string(content), "re f")
+}
+
+func TestWriteUnsupportedFormat(t *testing.T) {
+	assert.ErrorIs(t, export.Write(io.Discard, sampleDocument(), "docx"), export.ErrUnsupportedFormat)
+}
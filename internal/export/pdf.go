@@ -0,0 +1,259 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// A4 版面（單位為點）
+const (
+	pageWidth    = 595.0
+	pageHeight   = 842.0
+	pageMargin   = 40.0
+	contentWidth = pageWidth - 2*pageMargin
+
+	titleSize   = 16.0
+	headingSize = 12.0
+	bodySize    = 9.0
+	rowHeight   = 16.0
+	cellPadding = 3.0
+
+	chartLabelWidth = 130.0
+	chartValueWidth = 80.0
+	chartBarHeight  = 12.0
+)
+
+// PDF 使用 Adobe-CNS1 的 MSung-Light 字型，搭配 UniCNS-UCS2-H 編碼直接以 Unicode 輸出中文，
+// 閱讀器會以內建或系統的繁體中文字型替代，因此不需要內嵌字型檔
+const (
+	pdfFontName = "MSung-Light"
+	pdfEncoding = "UniCNS-UCS2-H"
+)
+
+// WritePDF 輸出可列印的 PDF，依序繪製所有表格與橫條圖，內容超出頁面時自動換頁
+func WritePDF(w io.Writer, doc *Document) error {
+	layout := &pdfLayout{}
+	layout.newPage()
+	layout.heading(doc.Title, titleSize)
+
+	for _, table := range doc.Tables {
+		layout.table(table)
+	}
+	for _, chart := range doc.Charts {
+		layout.chart(chart)
+	}
+	return layout.write(w)
+}
+
+// pdfLayout 記錄各頁的內容串流與目前的垂直位置
+type pdfLayout struct {
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64
+}
+
+func (l *pdfLayout) newPage() {
+	l.page = &bytes.Buffer{}
+	l.pages = append(l.pages, l.page)
+	l.y = pageHeight - pageMargin
+}
+
+// ensure 剩餘高度不足時換頁
+func (l *pdfLayout) ensure(height float64) {
+	if l.y-height < pageMargin {
+		l.newPage()
+	}
+}
+
+func (l *pdfLayout) heading(text string, size float64) {
+	l.ensure(size * 2)
+	l.y -= size * 1.5
+	l.text(pageMargin, l.y, size, text)
+	l.y -= size * 0.5
+}
+
+func (l *pdfLayout) table(table Table) {
+	columns := len(table.Header)
+	if columns == 0 {
+		return
+	}
+	l.heading(table.Name, headingSize)
+	width := contentWidth / float64(columns)
+
+	drawRow := func(cells []interface{}, header bool) {
+		l.y -= rowHeight
+		if header {
+			l.rect(pageMargin, l.y, contentWidth, rowHeight, 0.85)
+		}
+		l.line(pageMargin, l.y, pageMargin+contentWidth, l.y)
+		for i := 0; i < columns && i < len(cells); i++ {
+			text := truncateText(formatCell(cells[i]), bodySize, width-2*cellPadding)
+			x := pageMargin + float64(i)*width + cellPadding
+			if isNumber(cells[i]) {
+				x = pageMargin + float64(i+1)*width - cellPadding - textWidth(text, bodySize)
+			}
+			l.text(x, l.y+(rowHeight-bodySize)/2+1, bodySize, text)
+		}
+	}
+
+	header := make([]interface{}, columns)
+	for i, h := range table.Header {
+		header[i] = h
+	}
+	l.ensure(rowHeight * 2)
+	drawRow(header, true)
+	for _, row := range table.Rows {
+		// 換頁時重複表頭
+		if l.y-rowHeight < pageMargin {
+			l.newPage()
+			drawRow(header, true)
+		}
+		drawRow(row, false)
+	}
+	l.y -= rowHeight / 2
+}
+
+// chart 繪製橫條圖，正值為藍色、負值為紅色，長度依絕對值的最大值等比例縮放
+func (l *pdfLayout) chart(chart Chart) {
+	if len(chart.Bars) == 0 {
+		return
+	}
+	l.heading(chart.Title, headingSize)
+
+	var max float64
+	for _, bar := range chart.Bars {
+		max = math.Max(max, math.Abs(bar.Value))
+	}
+	barArea := contentWidth - chartLabelWidth - chartValueWidth
+	for _, bar := range chart.Bars {
+		l.ensure(rowHeight)
+		l.y -= rowHeight
+		l.text(pageMargin, l.y+3, bodySize, truncateText(bar.Label, bodySize, chartLabelWidth-cellPadding))
+
+		length := 0.0
+		if max > 0 {
+			length = math.Abs(bar.Value) / max * barArea
+		}
+		color := "0.26 0.52 0.96"
+		if bar.Value < 0 {
+			color = "0.86 0.27 0.22"
+		}
+		fmt.Fprintf(l.page, "%s rg %.2f %.2f %.2f %.2f re f 0 g\n", color, pageMargin+chartLabelWidth, l.y+(rowHeight-chartBarHeight)/2, length, chartBarHeight)
+
+		value := formatCell(bar.Value)
+		l.text(pageMargin+chartLabelWidth+length+cellPadding, l.y+3, bodySize, value)
+	}
+	l.y -= rowHeight / 2
+}
+
+func (l *pdfLayout) text(x, y, size float64, s string) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(l.page, "BT /F1 %.1f Tf %.2f %.2f Td <%s> Tj ET\n", size, x, y, encodeUCS2(s))
+}
+
+func (l *pdfLayout) rect(x, y, w, h, gray float64) {
+	fmt.Fprintf(l.page, "%.2f g %.2f %.2f %.2f %.2f re f 0 g\n", gray, x, y, w, h)
+}
+
+func (l *pdfLayout) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(l.page, "0.7 G 0.5 w %.2f %.2f m %.2f %.2f l S 0 G\n", x1, y1, x2, y2)
+}
+
+// write 輸出 PDF 物件與交叉參照表，內容串流以 zlib 壓縮
+func (l *pdfLayout) write(w io.Writer) error {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	const firstPage = 6
+	kids := make([]string, len(l.pages))
+	for i := range l.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(l.pages)))
+	object(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /%s /DescendantFonts [4 0 R] >>", pdfFontName, pdfEncoding))
+	// CID 1-95 為半形 ASCII，寬度為全形字的一半
+	object(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (CNS1) /Supplement 0 >> /FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>", pdfFontName))
+	object(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 6 /FontBBox [0 -200 1000 900] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>", pdfFontName))
+
+	for i, page := range l.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, firstPage+2*i+1))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(page.Bytes()); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// encodeUCS2 將文字轉為 UCS-2 大端序的十六進位字串，BMP 以外的字元以問號代替
+func encodeUCS2(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 0xFFFF || r < 0x20 {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+// textWidth 估算文字寬度，ASCII 為半形、其餘為全形
+func textWidth(s string, size float64) float64 {
+	var width float64
+	for _, r := range s {
+		if r < 0x80 {
+			width += 0.5
+		} else {
+			width++
+		}
+	}
+	return width * size
+}
+
+// truncateText 截斷超出寬度的文字並加上省略號
+func truncateText(s string, size, max float64) string {
+	if textWidth(s, size) <= max {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(string(runes)+"...", size) > max {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case float64, int:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"errors"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fintrack/internal/service"
+	"fintrack/internal/validation"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountHandler struct {
+	Service service.AccountService
+}
+
+func NewAccountHandler(s service.AccountService) *AccountHandler {
+	return &AccountHandler{Service: s}
+}
+
+// RegisterRoutes 註冊帳戶相關路由
+func (h *AccountHandler) RegisterRoutes(r gin.IRouter) {
+	r.POST("/accounts", h.CreateAccount) // 新增帳戶
+	r.GET("/accounts", h.GetAccounts)    // 查詢帳戶及餘額
+
+	r.POST("/accounts/:id/installments", h.CreateInstallmentPlan) // 新增信用卡分期付款
+	r.GET("/accounts/:id/installments", h.GetInstallmentPlans)    // 查詢信用卡分期付款
+	r.GET("/accounts/:id/statements/:cycle", h.GetStatement)      // 查詢信用卡某期帳單 (cycle: YYYY-MM)
+}
+
+// 新增銀行、信用卡或現金帳戶
+func (h *AccountHandler) CreateAccount(c *gin.Context) {
+	var account entity.Account
+	if !bindJSON(c, &account) {
+		return
+	}
+
+	created, err := h.Service.CreateAccount(account)
+	if err != nil {
+		respondAccountError(c, err, "Failed to create account")
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// 查詢用戶的帳戶及目前餘額
+func (h *AccountHandler) GetAccounts(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		respondFieldError(c, "user_id", validation.CodeRequired, nil, "user_id is required")
+		return
+	}
+
+	accounts, err := h.Service.GetAccounts(userID)
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to fetch accounts")
+		return
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// 新增信用卡分期付款，並產生每期的子交易
+func (h *AccountHandler) CreateInstallmentPlan(c *gin.Context) {
+	var plan entity.InstallmentPlan
+	if !bindJSON(c, &plan) {
+		return
+	}
+
+	created, err := h.Service.CreateInstallmentPlan(c.Param("id"), plan)
+	if err != nil {
+		respondAccountError(c, err, "Failed to create installment plan")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, created)
+}
+
+// 查詢信用卡的分期付款計畫
+func (h *AccountHandler) GetInstallmentPlans(c *gin.Context) {
+	plans, err := h.Service.GetInstallmentPlans(c.Query("user_id"), c.Param("id"))
+	if err != nil {
+		respondAccountError(c, err, "Failed to fetch installment plans")
+		return
+	}
+
+	c.JSON(http.StatusOK, plans)
+}
+
+// 查詢信用卡某期帳單，用於與銀行帳單對帳
+func (h *AccountHandler) GetStatement(c *gin.Context) {
+	cycle, err := time.Parse("2006-01", c.Param("cycle"))
+	if err != nil {
+		respondFieldError(c, "cycle", validation.CodeInvalidFormat, c.Param("cycle"), "cycle must be in YYYY-MM format")
+		return
+	}
+
+	statement, err := h.Service.GetStatement(c.Query("user_id"), c.Param("id"), cycle.Year(), cycle.Month())
+	if err != nil {
+		respondAccountError(c, err, "Failed to fetch statement")
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// respondAccountError 將帳戶相關錯誤轉換為對應的 HTTP 狀態碼
+func respondAccountError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, db.ErrAccountNotFound):
+		respondError(c, http.StatusNotFound, err)
+	case errors.Is(err, service.ErrInvalidAccount), errors.Is(err, service.ErrInvalidInstallment):
+		respondError(c, http.StatusBadRequest, err)
+	default:
+		respondMessage(c, http.StatusInternalServerError, message)
+	}
+}
@@ -1,10 +1,19 @@
 package handler
 
 import (
+	"bytes"
+	"errors"
+	"fintrack/internal/db"
 	"fintrack/internal/entity"
+	"fintrack/internal/export"
 	"fintrack/internal/service"
+	"fintrack/internal/validation"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,65 +26,338 @@ func NewTransactionHandler(s service.TransactionService) *TransactionHandler {
 	return &TransactionHandler{Service: s}
 }
 
+// RouteRegistrar 由其他模組的 handler 實現，用於掛載各自的路由
+type RouteRegistrar interface {
+	RegisterRoutes(r gin.IRouter)
+}
+
+// dateLayout 為查詢參數使用的日期格式
+const dateLayout = "2006-01-02"
+
+// NewRouter 組合所有 handler 的路由
+func NewRouter(th *TransactionHandler, rh *RecurringHandler, ah *AccountHandler, lh *LoanHandler, ih *InvestmentHandler, ph *PortabilityHandler, sh *SubscriptionHandler, xh *TaxHandler, uh *SettingsHandler) *gin.Engine {
+	return th.SetupRouter(rh, ah, lh, ih, ph, sh, xh, uh)
+}
+
+// parseDateQuery 解析 YYYY-MM-DD 格式的查詢參數，格式錯誤時直接回應 400
+func parseDateQuery(c *gin.Context, key string, def time.Time) (time.Time, bool) {
+	v := c.Query(key)
+	if v == "" {
+		return def, true
+	}
+	t, err := time.Parse(dateLayout, v)
+	if err != nil {
+		respondFieldError(c, key, validation.CodeInvalidFormat, v, key+" must be in YYYY-MM-DD format")
+		return time.Time{}, false
+	}
+	return t, true
+}
+
 // SetupRouter 設置 Gin 路由
-func (h *TransactionHandler) SetupRouter() *gin.Engine {
+func (h *TransactionHandler) SetupRouter(registrars ...RouteRegistrar) *gin.Engine {
 	r := gin.Default()
 
-	r.POST("/transactions", h.AddTransaction)      // 新增交易紀錄
-	r.GET("/transactions", h.GetTransactions)      // 查詢交易紀錄
-	r.POST("/reconcile/import", h.ImportReconcile) // 匯入銀行或信用卡帳單
-	r.GET("/reports", h.GetReports)                // 生成並查詢財務報表
+	r.POST("/transactions", h.AddTransaction)               // 新增交易紀錄
+	r.POST("/transactions/batch", h.AddTransactionBatch)    // 批次新增交易紀錄
+	r.GET("/transactions", h.GetTransactions)               // 查詢交易紀錄
+	r.GET("/transactions/search", h.SearchTransactions)     // 全文檢索交易
+	r.GET("/transactions/submissions/:id", h.GetSubmission) // 查詢非同步異動的處理狀態
+	r.GET("/transactions/:id", h.GetTransaction)            // 查詢單筆交易
+	r.PUT("/transactions/:id", h.UpdateTransaction)         // 取代交易內容
+	r.PATCH("/transactions/:id", h.PatchTransaction)        // 修改部分欄位
+	r.DELETE("/transactions/:id", h.DeleteTransaction)      // 刪除交易
+	r.POST("/reconcile/import", h.ImportReconcile)          // 匯入銀行或信用卡帳單
+	r.GET("/reports", h.GetReports)                         // 生成並查詢財務報表
+	r.GET("/reports/compare", h.CompareReports)             // 比較本期與基準期間的收支
+	r.GET("/openapi.json", GetOpenAPI)                      // API 文件
+	r.GET("/docs/*filepath", GetSwaggerUI)                  // Swagger UI
+
+	for _, registrar := range registrars {
+		registrar.RegisterRoutes(r)
+	}
 
 	return r
 }
 
-// 接收用戶的交易記錄並將其發送至 RabbitMQ
+// 接收用戶的交易記錄並將其發送至 RabbitMQ，回應中的提交包含伺服器產生的交易 ID
 func (h *TransactionHandler) AddTransaction(c *gin.Context) {
 	var tx entity.Transaction
-	if err := c.ShouldBindJSON(&tx); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &tx) {
 		return
 	}
 
-	// 數據驗證
-	if tx.Amount <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Amount must be greater than zero"})
+	// 欄位由 service 以與消費者相同的規則驗證，非同步處理寫入資料庫，帶有 Idempotency-Key 的重送返回第一次的回應
+	var submission *service.Submission
+	var replayed bool
+	var err error
+	if key := c.GetHeader("Idempotency-Key"); key != "" {
+		submission, replayed, err = h.Service.AddTransactionOnce(c.Request.Context(), key, tx)
+	} else {
+		submission, err = h.Service.AddTransaction(tx)
+	}
+	switch {
+	case errors.Is(err, service.ErrInvalidIdempotencyKey), errors.Is(err, service.ErrInvalidTransaction):
+		respondError(c, http.StatusBadRequest, err)
 		return
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		respondError(c, http.StatusUnprocessableEntity, err)
+		return
+	case errors.Is(err, service.ErrIdempotencyInProgress):
+		respondError(c, http.StatusConflict, err)
+		return
+	case err != nil:
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
 	}
-	if tx.Date.IsZero() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+	acceptSubmission(c, submission, "Transaction received and will be processed")
+}
+
+// maxBatchBytes 為批次新增請求內容的上限
+const maxBatchBytes = 32 << 20
+
+// 批次新增交易，請求為 JSON 陣列，Content-Type 為 application/x-ndjson 時為每行一筆。
+// 返回每筆的處理結果；至少一筆送出時為 202，全部內容不正確時為 422，否則為 503
+func (h *TransactionHandler) AddTransactionBatch(c *gin.Context) {
+	ndjson := c.ContentType() == "application/x-ndjson" || c.ContentType() == "application/ndjson"
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxBatchBytes)
+
+	result, err := h.Service.AddTransactionBatch(c.Request.Context(), body, ndjson)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		respondMessage(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must be at most %d bytes", maxBatchBytes))
+		return
+	case errors.Is(err, service.ErrInvalidBatch):
+		respondError(c, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		respondMessage(c, http.StatusInternalServerError, "Failed to add transactions")
 		return
 	}
 
-	// 非同步處理寫入資料庫
-	err := h.Service.AddTransaction(tx)
+	status := http.StatusAccepted
+	if result.Accepted == 0 {
+		status = http.StatusServiceUnavailable
+		if result.Failed == 0 {
+			status = http.StatusUnprocessableEntity
+		}
+	}
+	c.JSON(status, result)
+}
+
+// acceptSubmission 回應 202 與提交紀錄，Location 指向可查詢處理結果的網址
+func acceptSubmission(c *gin.Context, submission *service.Submission, message string) {
+	c.Header("Location", "/transactions/submissions/"+submission.ID)
+	c.JSON(http.StatusAccepted, gin.H{"message": message, "submission": submission})
+}
+
+// 查詢非同步交易異動的處理狀態，wait=true 時最多等待數秒至處理完成
+func (h *TransactionHandler) GetSubmission(c *gin.Context) {
+	wait, _ := strconv.ParseBool(c.Query("wait"))
+	submission, err := h.Service.GetSubmission(c.Request.Context(), c.Query("user_id"), c.Param("id"), wait)
+	if errors.Is(err, service.ErrSubmissionNotFound) {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMessage(c, http.StatusInternalServerError, "Failed to fetch submission")
 		return
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{"message": "Transaction received and will be processed"})
+	c.JSON(http.StatusOK, submission)
 }
 
-// 查詢交易紀錄，支持分頁和篩選
+// 查詢交易紀錄，支持篩選、排序與游標分頁；格式錯誤的參數回應 400
 func (h *TransactionHandler) GetTransactions(c *gin.Context) {
-	userID := c.Query("user_id")
-	category := c.Query("category")
-	startDate := c.Query("start_date")
-	endDate := c.Query("end_date")
-	pageStr := c.DefaultQuery("page", "1")
-	pageSizeStr := c.DefaultQuery("page_size", "10")
+	req, ok := parseListRequest(c)
+	if !ok {
+		return
+	}
 
-	page, _ := strconv.Atoi(pageStr)
-	pageSize, _ := strconv.Atoi(pageSizeStr)
+	page, err := h.Service.GetTransactions(req)
+	if errors.Is(err, service.ErrInvalidReportRequest) || errors.Is(err, service.ErrInvalidTransactionQuery) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to fetch transactions")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
 
-	transactions, err := h.Service.GetTransactions(userID, category, startDate, endDate, page, pageSize)
+// 以全文檢索搜尋交易的說明、收款方與備註，可搭配交易列表的篩選參數
+func (h *TransactionHandler) SearchTransactions(c *gin.Context) {
+	req, ok := parseListRequest(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.Service.SearchTransactions(req, c.Query("q"))
+	if errors.Is(err, service.ErrInvalidReportRequest) || errors.Is(err, service.ErrInvalidTransactionQuery) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
+		respondMessage(c, http.StatusInternalServerError, "Failed to search transactions")
 		return
 	}
 
-	c.JSON(http.StatusOK, transactions)
+	c.JSON(http.StatusOK, result)
+}
+
+// parseListRequest 解析交易列表的篩選、排序與分頁參數，格式錯誤時直接回應 400
+func parseListRequest(c *gin.Context) (service.TransactionListRequest, bool) {
+	req := service.TransactionListRequest{
+		UserID:      c.Query("user_id"),
+		Categories:  queryList(c, "category"),
+		StartDate:   c.Query("start_date"),
+		EndDate:     c.Query("end_date"),
+		Sources:     queryList(c, "source"),
+		AccountID:   c.Query("account_id"),
+		Payee:       c.Query("payee"),
+		Tags:        queryList(c, "tag"),
+		Description: c.Query("description"),
+		Sort:        c.Query("sort"),
+		Order:       c.Query("order"),
+		Cursor:      c.Query("cursor"),
+	}
+	var ok bool
+	if req.MinAmount, ok = parseFloatQuery(c, "min_amount"); !ok {
+		return req, false
+	}
+	if req.MaxAmount, ok = parseFloatQuery(c, "max_amount"); !ok {
+		return req, false
+	}
+	if req.Reconciled, ok = parseBoolQuery(c, "reconciled"); !ok {
+		return req, false
+	}
+	includeTotal, ok := parseBoolQuery(c, "include_total")
+	if !ok {
+		return req, false
+	}
+	req.IncludeTotal = includeTotal != nil && *includeTotal
+	if v := c.Query("page_size"); v != "" {
+		pageSize, err := strconv.Atoi(v)
+		if err != nil {
+			respondFieldError(c, "page_size", validation.CodeInvalidFormat, v, "page_size must be an integer")
+			return req, false
+		}
+		req.PageSize = pageSize
+	}
+	return req, true
+}
+
+// queryList 返回可重複或以逗號分隔的查詢參數，去除空白與空值
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, v := range c.QueryArray(key) {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
+}
+
+// parseFloatQuery 解析數字查詢參數，未指定時返回 nil，格式錯誤時直接回應 400
+func parseFloatQuery(c *gin.Context, key string) (*float64, bool) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, true
+	}
+	value, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		respondFieldError(c, key, validation.CodeInvalidFormat, v, key+" must be a number")
+		return nil, false
+	}
+	return &value, true
+}
+
+// parseBoolQuery 解析 true/false 查詢參數，未指定時返回 nil，格式錯誤時直接回應 400
+func parseBoolQuery(c *gin.Context, key string) (*bool, bool) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, true
+	}
+	value, err := strconv.ParseBool(v)
+	if err != nil {
+		respondFieldError(c, key, validation.CodeInvalidFormat, v, key+" must be true or false")
+		return nil, false
+	}
+	return &value, true
+}
+
+// 查詢用戶的單筆交易
+func (h *TransactionHandler) GetTransaction(c *gin.Context) {
+	tx, err := h.Service.GetTransaction(c.Query("user_id"), c.Param("id"))
+	if err != nil {
+		respondTransactionError(c, err, "Failed to fetch transaction")
+		return
+	}
+
+	c.JSON(http.StatusOK, tx)
+}
+
+// 以請求內容取代交易，修改經由 RabbitMQ 非同步寫入
+func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
+	var tx entity.Transaction
+	if !bindJSON(c, &tx) {
+		return
+	}
+
+	submission, err := h.Service.UpdateTransaction(c.Query("user_id"), c.Param("id"), tx)
+	if err != nil {
+		respondTransactionError(c, err, "Failed to update transaction")
+		return
+	}
+
+	acceptSubmission(c, submission, "Transaction will be updated")
+}
+
+// 只修改請求中提供的欄位
+func (h *TransactionHandler) PatchTransaction(c *gin.Context) {
+	var patch service.TransactionPatch
+	if !bindJSON(c, &patch) {
+		return
+	}
+
+	submission, err := h.Service.PatchTransaction(c.Query("user_id"), c.Param("id"), patch)
+	if err != nil {
+		respondTransactionError(c, err, "Failed to update transaction")
+		return
+	}
+
+	acceptSubmission(c, submission, "Transaction will be updated")
+}
+
+// 刪除用戶的交易，刪除經由 RabbitMQ 非同步執行
+func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
+	submission, err := h.Service.DeleteTransaction(c.Query("user_id"), c.Param("id"))
+	if err != nil {
+		respondTransactionError(c, err, "Failed to delete transaction")
+		return
+	}
+
+	acceptSubmission(c, submission, "Transaction will be deleted")
+}
+
+// respondTransactionError 將交易相關錯誤轉換為對應的 HTTP 狀態碼
+func respondTransactionError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, db.ErrTransactionNotFound):
+		respondError(c, http.StatusNotFound, err)
+	case errors.Is(err, service.ErrInvalidTransaction):
+		respondError(c, http.StatusBadRequest, err)
+	default:
+		respondMessage(c, http.StatusInternalServerError, message)
+	}
 }
 
 // 匯入銀行或信用卡帳單
@@ -83,7 +365,7 @@ func (h *TransactionHandler) ImportReconcile(c *gin.Context) {
 	// 此處處理文件上傳和格式驗證，省略具體實現
 	err := h.Service.ImportTransactions(c.Request.Body)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to import transactions"})
+		respondMessage(c, http.StatusBadRequest, "Failed to import transactions")
 		return
 	}
 	c.JSON(http.StatusAccepted, gin.H{"message": "Transactions imported successfully"})
@@ -96,9 +378,84 @@ func (h *TransactionHandler) GetReports(c *gin.Context) {
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
 
+	if format := strings.ToLower(c.DefaultQuery("format", "json")); format != "json" {
+		h.exportReport(c, format, userID, reportType, startDate, endDate)
+		return
+	}
+
 	report, err := h.Service.GenerateReport(c.Request.Context(), userID, reportType, startDate, endDate)
+	if errors.Is(err, service.ErrInvalidReportRequest) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to generate report")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// exportReport 以 CSV、XLSX 或 PDF 下載報表
+func (h *TransactionHandler) exportReport(c *gin.Context, format, userID, reportType, startDate, endDate string) {
+	if !export.Supported(format) {
+		respondFieldError(c, "format", validation.CodeInvalidValue, format, "format must be json, csv, xlsx or pdf")
+		return
+	}
+
+	doc, err := h.Service.ExportReport(c.Request.Context(), userID, reportType, startDate, endDate)
+	if errors.Is(err, service.ErrInvalidReportRequest) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to generate report")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, doc, format); err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to export report")
+		return
+	}
+
+	name := "report"
+	for _, part := range []string{reportType, startDate, endDate} {
+		if part != "" {
+			name += "-" + part
+		}
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+format))
+	c.Data(http.StatusOK, export.ContentType(format), buf.Bytes())
+}
+
+// 比較本期與去年同期、過去 12 個月平均等基準期間的分類收支
+func (h *TransactionHandler) CompareReports(c *gin.Context) {
+	req := service.ComparisonRequest{
+		StartDate:    c.Query("start_date"),
+		EndDate:      c.Query("end_date"),
+		CompareStart: c.Query("compare_start"),
+		CompareEnd:   c.Query("compare_end"),
+	}
+	if baseline := c.Query("baseline"); baseline != "" {
+		req.Baselines = strings.Split(baseline, ",")
+	}
+	if threshold := c.Query("threshold"); threshold != "" {
+		value, err := strconv.ParseFloat(threshold, 64)
+		if err != nil || value <= 0 {
+			respondFieldError(c, "threshold", validation.CodeInvalidValue, threshold, "threshold must be a positive number")
+			return
+		}
+		req.Threshold = value
+	}
+
+	report, err := h.Service.CompareReports(c.Request.Context(), c.Query("user_id"), req)
+	if errors.Is(err, service.ErrInvalidReportRequest) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate report"})
+		respondMessage(c, http.StatusInternalServerError, "Failed to compare reports")
 		return
 	}
 
@@ -5,10 +5,15 @@ import (
 	"context"
 	"encoding/json"
 	"fintrack/internal/entity"
+	"fintrack/internal/export"
 	"fintrack/internal/handler"
+	"fintrack/internal/service"
+	"fintrack/internal/validation"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,14 +27,65 @@ type MockTransactionService struct {
 	mock.Mock
 }
 
-func (m *MockTransactionService) AddTransaction(tx entity.Transaction) error {
+func (m *MockTransactionService) AddTransaction(tx entity.Transaction) (*service.Submission, error) {
 	args := m.Called(tx)
-	return args.Error(0)
+	submission, _ := args.Get(0).(*service.Submission)
+	return submission, args.Error(1)
+}
+
+func (m *MockTransactionService) GetTransactions(req service.TransactionListRequest) (*service.TransactionPage, error) {
+	args := m.Called(req)
+	page, _ := args.Get(0).(*service.TransactionPage)
+	return page, args.Error(1)
+}
+
+func (m *MockTransactionService) SearchTransactions(req service.TransactionListRequest, text string) (*service.SearchResult, error) {
+	args := m.Called(req, text)
+	result, _ := args.Get(0).(*service.SearchResult)
+	return result, args.Error(1)
+}
+
+func (m *MockTransactionService) GetTransaction(userID, txID string) (*entity.Transaction, error) {
+	args := m.Called(userID, txID)
+	tx, _ := args.Get(0).(*entity.Transaction)
+	return tx, args.Error(1)
+}
+
+func (m *MockTransactionService) AddTransactionOnce(ctx context.Context, idempotencyKey string, tx entity.Transaction) (*service.Submission, bool, error) {
+	args := m.Called(ctx, idempotencyKey, tx)
+	submission, _ := args.Get(0).(*service.Submission)
+	return submission, args.Bool(1), args.Error(2)
+}
+
+func (m *MockTransactionService) AddTransactionBatch(ctx context.Context, r io.Reader, ndjson bool) (*service.BatchResult, error) {
+	data, _ := io.ReadAll(r)
+	args := m.Called(ctx, string(data), ndjson)
+	result, _ := args.Get(0).(*service.BatchResult)
+	return result, args.Error(1)
+}
+
+func (m *MockTransactionService) UpdateTransaction(userID, txID string, tx entity.Transaction) (*service.Submission, error) {
+	args := m.Called(userID, txID, tx)
+	submission, _ := args.Get(0).(*service.Submission)
+	return submission, args.Error(1)
+}
+
+func (m *MockTransactionService) PatchTransaction(userID, txID string, patch service.TransactionPatch) (*service.Submission, error) {
+	args := m.Called(userID, txID, patch)
+	submission, _ := args.Get(0).(*service.Submission)
+	return submission, args.Error(1)
+}
+
+func (m *MockTransactionService) DeleteTransaction(userID, txID string) (*service.Submission, error) {
+	args := m.Called(userID, txID)
+	submission, _ := args.Get(0).(*service.Submission)
+	return submission, args.Error(1)
 }
 
-func (m *MockTransactionService) GetTransactions(userID, category, startDate, endDate string, page, pageSize int) ([]entity.Transaction, error) {
-	args := m.Called(userID, category, startDate, endDate, page, pageSize)
-	return args.Get(0).([]entity.Transaction), args.Error(1)
+func (m *MockTransactionService) GetSubmission(ctx context.Context, userID, submissionID string, wait bool) (*service.Submission, error) {
+	args := m.Called(ctx, userID, submissionID, wait)
+	submission, _ := args.Get(0).(*service.Submission)
+	return submission, args.Error(1)
 }
 
 func (m *MockTransactionService) ImportTransactions(data io.Reader) error {
@@ -42,6 +98,18 @@ func (m *MockTransactionService) GenerateReport(ctx context.Context, userID, rep
 	return args.Get(0), args.Error(1)
 }
 
+func (m *MockTransactionService) CompareReports(ctx context.Context, userID string, req service.ComparisonRequest) (*service.ComparisonReport, error) {
+	args := m.Called(ctx, userID, req)
+	report, _ := args.Get(0).(*service.ComparisonReport)
+	return report, args.Error(1)
+}
+
+func (m *MockTransactionService) ExportReport(ctx context.Context, userID, reportType, startDate, endDate string) (*export.Document, error) {
+	args := m.Called(ctx, userID, reportType, startDate, endDate)
+	doc, _ := args.Get(0).(*export.Document)
+	return doc, args.Error(1)
+}
+
 func TestAddTransaction(t *testing.T) {
 	// 設置 Gin 模式為測試模式
 	gin.SetMode(gin.TestMode)
@@ -68,7 +136,7 @@ func TestAddTransaction(t *testing.T) {
 			transaction.Category == tx.Category &&
 			transaction.Description == tx.Description &&
 			transaction.Source == tx.Source
-	})).Return(nil)
+	})).Return(&service.Submission{ID: "sub1", Status: service.SubmissionPending}, nil)
 
 	// 構建 HTTP 請求
 	reqBody, _ := json.Marshal(tx)
@@ -80,8 +148,121 @@ func TestAddTransaction(t *testing.T) {
 	router.POST("/transactions", handler.AddTransaction)
 	router.ServeHTTP(w, req)
 
-	// 檢查狀態碼
+	// 檢查狀態碼與查詢處理結果的網址
 	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "/transactions/submissions/sub1", w.Header().Get("Location"))
+	mockService.AssertExpectations(t)
+}
+
+func TestAddTransactionValidationError(t *testing.T) {
+	mockService := new(MockTransactionService)
+	handler := handler.NewTransactionHandler(mockService)
+	invalid := validation.Errors{
+		{Code: validation.CodeMustBePositive, Message: "amount must be greater than zero", Field: "Amount", Value: -5.0},
+		{Code: validation.CodeRequired, Message: "date is required", Field: "Date"},
+	}
+	mockService.On("AddTransaction", mock.Anything).Return(nil, fmt.Errorf("%w: %w", service.ErrInvalidTransaction, invalid))
+	router := handler.SetupRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(`{"UserID":"user123","Amount":-5}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp validation.Response
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "invalid transaction: amount must be greater than zero; date is required", resp.Error)
+	assert.Equal(t, validation.CodeValidationFailed, resp.Code)
+	assert.Len(t, resp.Details, 2)
+	assert.Equal(t, "Amount", resp.Details[0].Field)
+	assert.Equal(t, -5.0, resp.Details[0].Value)
+
+	// 型別不符的欄位在解析時就指出
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(`{"UserID":"user123","Amount":"ten"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, validation.CodeValidationFailed, resp.Code)
+	assert.Equal(t, validation.CodeInvalidType, resp.Details[0].Code)
+	assert.Equal(t, "Amount", resp.Details[0].Field)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(`{`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	resp = validation.Response{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, validation.CodeInvalidRequest, resp.Code)
+	assert.Empty(t, resp.Details)
+}
+
+func TestAddTransactionIdempotent(t *testing.T) {
+	mockService := new(MockTransactionService)
+	handler := handler.NewTransactionHandler(mockService)
+
+	body := `{"UserID":"user123","Date":"2024-03-10T12:00:00Z","Amount":100}`
+	mockService.On("AddTransactionOnce", mock.Anything, "key1", mock.Anything).
+		Return(&service.Submission{ID: "sub1", Status: service.SubmissionPending}, true, nil)
+	mockService.On("AddTransactionOnce", mock.Anything, "key2", mock.Anything).
+		Return(nil, false, service.ErrIdempotencyKeyReused)
+
+	router := handler.SetupRouter()
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader([]byte(body)))
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 重送返回第一次的提交
+	w := send("key1")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+	assert.Contains(t, w.Body.String(), `"id":"sub1"`)
+
+	// 冪等鍵用於不同內容
+	w = send("key2")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	mockService.AssertNotCalled(t, "AddTransaction", mock.Anything)
+	mockService.AssertExpectations(t)
+}
+
+func TestAddTransactionBatch(t *testing.T) {
+	mockService := new(MockTransactionService)
+	handler := handler.NewTransactionHandler(mockService)
+
+	ndjson := "{\"UserID\":\"user123\",\"Amount\":100}\n{\"UserID\":\"user123\",\"Amount\":-1}\n"
+	mockService.On("AddTransactionBatch", mock.Anything, ndjson, true).Return(&service.BatchResult{
+		Accepted: 1,
+		Rejected: 1,
+		Items: []service.BatchItemResult{
+			{Index: 0, Status: service.BatchItemAccepted, TransactionID: "01HB8X3J6Q2V7ZK4M9T1R5N0CD"},
+			{Index: 1, Status: service.BatchItemRejected, Error: "invalid transaction: amount must be greater than zero"},
+		},
+	}, nil)
+	mockService.On("AddTransactionBatch", mock.Anything, "{}", false).Return(nil, service.ErrInvalidBatch)
+	mockService.On("AddTransactionBatch", mock.Anything, "[{}]", false).Return(&service.BatchResult{
+		Rejected: 1,
+		Items:    []service.BatchItemResult{{Index: 0, Status: service.BatchItemRejected}},
+	}, nil)
+
+	router := handler.SetupRouter()
+	send := func(body, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/transactions/batch", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(ndjson, "application/x-ndjson")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), `"accepted":1`)
+
+	w = send("{}", "application/json")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 全部內容不正確
+	w = send("[{}]", "application/json")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	mockService.AssertExpectations(t)
 }
 
@@ -93,7 +274,10 @@ func TestGetTransactions(t *testing.T) {
 		{ID: "1", UserID: "user123", Date: time.Now(), Amount: 100.0, Category: "INCOME", Description: "Salary"},
 	}
 
-	mockService.On("GetTransactions", "user123", "", "", "", 1, 10).Return(transactions, nil)
+	mockService.On("GetTransactions", service.TransactionListRequest{UserID: "user123"}).
+		Return(&service.TransactionPage{Transactions: transactions, NextCursor: "abc"}, nil)
+	mockService.On("GetTransactions", service.TransactionListRequest{UserID: "user123", Sort: "amount", Order: "asc", Cursor: "abc", PageSize: 20, IncludeTotal: true}).
+		Return(nil, service.ErrInvalidTransactionQuery)
 
 	req := httptest.NewRequest(http.MethodGet, "/transactions?user_id=user123", nil)
 	w := httptest.NewRecorder()
@@ -102,5 +286,158 @@ func TestGetTransactions(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"next_cursor":"abc"`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/transactions?user_id=user123&sort=amount&order=asc&cursor=abc&page_size=20&include_total=true", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetTransactionsFilters(t *testing.T) {
+	mockService := new(MockTransactionService)
+	handler := handler.NewTransactionHandler(mockService)
+
+	minAmount, reconciled := 50.0, true
+	mockService.On("GetTransactions", service.TransactionListRequest{
+		UserID:      "user123",
+		Categories:  []string{"FOOD", "HOME", "TRAVEL"},
+		StartDate:   "2024-03-01",
+		MinAmount:   &minAmount,
+		Sources:     []string{"BANK"},
+		Reconciled:  &reconciled,
+		AccountID:   "acc1",
+		Payee:       "IKEA",
+		Tags:        []string{"home"},
+		Description: "sofa",
+	}).Return(&service.TransactionPage{Transactions: []entity.Transaction{}}, nil)
+
+	router := handler.SetupRouter()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/transactions?user_id=user123&category=FOOD,HOME&category=TRAVEL&start_date=2024-03-01&min_amount=50&source=BANK&reconciled=true&account_id=acc1&payee=IKEA&tag=home&description=sofa", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 格式錯誤的參數不再被忽略
+	for _, query := range []string{"min_amount=abc", "max_amount=NaN", "reconciled=maybe", "page_size=ten", "include_total=yes"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/transactions?user_id=user123&"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Contains(t, w.Body.String(), `"code":"INVALID_FORMAT","message":`, query)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestSearchTransactions(t *testing.T) {
+	mockService := new(MockTransactionService)
+	handler := handler.NewTransactionHandler(mockService)
+
+	mockService.On("SearchTransactions", service.TransactionListRequest{UserID: "user123", StartDate: "last_year"}, "IKEA 沙發").
+		Return(&service.SearchResult{Query: "IKEA 沙發", Transactions: []service.TransactionHit{{Transaction: entity.Transaction{ID: "tx1", Payee: "IKEA"}, Score: 1.5}}}, nil)
+	mockService.On("SearchTransactions", service.TransactionListRequest{UserID: "user123"}, "").Return(nil, service.ErrInvalidTransactionQuery)
+
+	router := handler.SetupRouter()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/transactions/search?user_id=user123&start_date=last_year&q=IKEA+%E6%B2%99%E7%99%BC", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"score":1.5`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/transactions/search?user_id=user123", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateAndDeleteTransaction(t *testing.T) {
+	mockService := new(MockTransactionService)
+	handler := handler.NewTransactionHandler(mockService)
+
+	amount := 250.0
+	mockService.On("PatchTransaction", "user123", "tx1", service.TransactionPatch{Amount: &amount}).
+		Return(&service.Submission{ID: "sub1", TransactionID: "tx1", Patch: &service.TransactionPatch{Amount: &amount}}, nil)
+	mockService.On("UpdateTransaction", "user123", "tx1", mock.Anything).
+		Return(nil, fmt.Errorf("%w: amount must be greater than zero", service.ErrInvalidTransaction))
+	mockService.On("DeleteTransaction", "", "tx1").Return(nil, fmt.Errorf("%w: user ID is required", service.ErrInvalidTransaction))
+	mockService.On("DeleteTransaction", "user123", "tx1").Return(&service.Submission{ID: "sub2"}, nil)
+
+	router := handler.SetupRouter()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/transactions/tx1?user_id=user123", strings.NewReader(`{"Amount": 250}`)))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), `"Amount":250`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/transactions/tx1?user_id=user123", strings.NewReader(`{"Amount": 0}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/transactions/tx1", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/transactions/tx1?user_id=user123", nil))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetSubmission(t *testing.T) {
+	mockService := new(MockTransactionService)
+	handler := handler.NewTransactionHandler(mockService)
+
+	mockService.On("GetSubmission", mock.Anything, "user123", "sub1", true).
+		Return(&service.Submission{ID: "sub1", Status: service.SubmissionRejected, Reason: "invalid transaction: amount must be greater than zero"}, nil)
+	mockService.On("GetSubmission", mock.Anything, "user456", "sub1", false).Return(nil, service.ErrSubmissionNotFound)
+
+	router := handler.SetupRouter()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/transactions/submissions/sub1?user_id=user123&wait=true", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"REJECTED"`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/transactions/submissions/sub1?user_id=user456", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCompareReports(t *testing.T) {
+	mockService := new(MockTransactionService)
+	handler := handler.NewTransactionHandler(mockService)
+
+	req := service.ComparisonRequest{
+		StartDate: "2024-03-01",
+		EndDate:   "2024-03-31",
+		Baselines: []string{"year_ago", "trailing_average"},
+		Threshold: 30,
+	}
+	mockService.On("CompareReports", mock.Anything, "user123", req).Return(&service.ComparisonReport{User: "user123"}, nil)
+
+	router := handler.SetupRouter()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports/compare?user_id=user123&start_date=2024-03-01&end_date=2024-03-31&baseline=year_ago,trailing_average&threshold=30", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports/compare?user_id=user123&start_date=2024-03-01&end_date=2024-03-31&threshold=abc", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetReportsExport(t *testing.T) {
+	mockService := new(MockTransactionService)
+	handler := handler.NewTransactionHandler(mockService)
+
+	doc := &export.Document{Tables: []export.Table{{Name: "Summary", Header: []string{"Period"}, Rows: [][]interface{}{{"2024-03"}}}}}
+	mockService.On("ExportReport", mock.Anything, "user123", "monthly", "2024-03-01", "2024-03-31").Return(doc, nil)
+
+	router := handler.SetupRouter()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports?user_id=user123&report_type=monthly&start_date=2024-03-01&end_date=2024-03-31&format=csv", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="report-monthly-2024-03-01-2024-03-31.csv"`, w.Header().Get("Content-Disposition"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports?user_id=user123&format=docx", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 	mockService.AssertExpectations(t)
 }
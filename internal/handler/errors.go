@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"errors"
+	"fintrack/internal/service"
+	"fintrack/internal/validation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError 以統一的錯誤格式回應。錯誤碼依錯誤與狀態碼決定，錯誤鏈中有欄位錯誤時為 VALIDATION_FAILED 並列出每個欄位
+func respondError(c *gin.Context, status int, err error) {
+	c.JSON(status, validation.NewResponse(errorCode(status, err), err))
+}
+
+// respondMessage 以固定訊息回應錯誤，用於不對外顯示內部錯誤的情況
+func respondMessage(c *gin.Context, status int, message string) {
+	respondError(c, status, errors.New(message))
+}
+
+// respondFieldError 回應單一查詢參數或欄位的錯誤
+func respondFieldError(c *gin.Context, field, code string, value interface{}, message string) {
+	respondError(c, http.StatusBadRequest, validation.FieldError{Code: code, Message: message, Field: field, Value: value})
+}
+
+// bindJSON 解析 JSON 請求內容，失敗時回應 400 並返回 false；型別不符時指出欄位
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		respondError(c, http.StatusBadRequest, validation.DecodeError(err))
+		return false
+	}
+	return true
+}
+
+// errorCode 返回錯誤對應的錯誤碼，沒有專屬錯誤碼的錯誤依狀態碼決定
+func errorCode(status int, err error) string {
+	switch {
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		return validation.CodeIdempotencyKeyReused
+	case errors.Is(err, service.ErrIdempotencyInProgress):
+		return validation.CodeIdempotencyInProgress
+	}
+	switch {
+	case status == http.StatusNotFound:
+		return validation.CodeNotFound
+	case status == http.StatusConflict:
+		return validation.CodeConflict
+	case status == http.StatusRequestEntityTooLarge:
+		return validation.CodePayloadTooLarge
+	case status >= http.StatusInternalServerError:
+		return validation.CodeInternal
+	default:
+		return validation.CodeInvalidRequest
+	}
+}
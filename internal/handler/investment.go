@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"errors"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fintrack/internal/service"
+	"fintrack/internal/validation"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InvestmentHandler struct {
+	Service service.InvestmentService
+}
+
+func NewInvestmentHandler(s service.InvestmentService) *InvestmentHandler {
+	return &InvestmentHandler{Service: s}
+}
+
+// RegisterRoutes 註冊投資相關路由
+func (h *InvestmentHandler) RegisterRoutes(r gin.IRouter) {
+	r.POST("/investments/securities", h.SaveSecurity)    // 新增或更新證券
+	r.GET("/investments/securities", h.GetSecurities)    // 查詢證券
+	r.POST("/investments/trades", h.RecordTrade)         // 記錄買賣、股利與分割
+	r.GET("/investments/trades", h.GetTrades)            // 查詢投資交易
+	r.POST("/investments/prices/import", h.ImportPrices) // 從 CSV 匯入收盤價
+	r.GET("/investments/portfolio", h.GetPortfolio)      // 查詢持倉市值與未實現損益
+	r.GET("/investments/gains", h.GetGains)              // 查詢已實現損益與股利收入
+}
+
+// 新增或更新證券
+func (h *InvestmentHandler) SaveSecurity(c *gin.Context) {
+	var security entity.Security
+	if !bindJSON(c, &security) {
+		return
+	}
+
+	saved, err := h.Service.SaveSecurity(security)
+	if err != nil {
+		respondInvestmentError(c, err, "Failed to save security")
+		return
+	}
+
+	c.JSON(http.StatusCreated, saved)
+}
+
+// 查詢所有證券
+func (h *InvestmentHandler) GetSecurities(c *gin.Context) {
+	securities, err := h.Service.GetSecurities()
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to fetch securities")
+		return
+	}
+
+	c.JSON(http.StatusOK, securities)
+}
+
+// 記錄一筆投資交易
+func (h *InvestmentHandler) RecordTrade(c *gin.Context) {
+	var trade entity.Trade
+	if !bindJSON(c, &trade) {
+		return
+	}
+
+	recorded, err := h.Service.RecordTrade(trade)
+	if err != nil {
+		respondInvestmentError(c, err, "Failed to record trade")
+		return
+	}
+
+	c.JSON(http.StatusCreated, recorded)
+}
+
+// 查詢用戶的投資交易
+func (h *InvestmentHandler) GetTrades(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		respondFieldError(c, "user_id", validation.CodeRequired, nil, "user_id is required")
+		return
+	}
+
+	trades, err := h.Service.GetTrades(userID)
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to fetch trades")
+		return
+	}
+
+	c.JSON(http.StatusOK, trades)
+}
+
+// 從 CSV 匯入收盤價，請求內容為 symbol,date,close
+func (h *InvestmentHandler) ImportPrices(c *gin.Context) {
+	count, err := h.Service.ImportPrices(c.Request.Body)
+	if err != nil {
+		respondInvestmentError(c, err, "Failed to import prices")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imported": count})
+}
+
+// 查詢指定日期的持倉市值，預設為今天
+func (h *InvestmentHandler) GetPortfolio(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		respondFieldError(c, "user_id", validation.CodeRequired, nil, "user_id is required")
+		return
+	}
+	asOf, ok := parseDateQuery(c, "as_of", time.Now().UTC().Truncate(24*time.Hour))
+	if !ok {
+		return
+	}
+	method, err := service.ParseCostMethod(c.Query("method"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	report, err := h.Service.GetPortfolio(userID, asOf, method)
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to value portfolio")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// 查詢期間內的已實現損益與股利收入，預設為今年初至今天
+func (h *InvestmentHandler) GetGains(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		respondFieldError(c, "user_id", validation.CodeRequired, nil, "user_id is required")
+		return
+	}
+	now := time.Now().UTC()
+	from, ok := parseDateQuery(c, "start_date", time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC))
+	if !ok {
+		return
+	}
+	to, ok := parseDateQuery(c, "end_date", now.Truncate(24*time.Hour))
+	if !ok {
+		return
+	}
+	method, err := service.ParseCostMethod(c.Query("method"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	report, err := h.Service.GetGains(userID, from, to, method)
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to calculate gains")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// respondInvestmentError 將投資相關錯誤轉換為對應的 HTTP 狀態碼
+func respondInvestmentError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, db.ErrAccountNotFound), errors.Is(err, db.ErrSecurityNotFound):
+		respondError(c, http.StatusNotFound, err)
+	case errors.Is(err, service.ErrInvalidTrade), errors.Is(err, service.ErrInvalidPriceFile):
+		respondError(c, http.StatusBadRequest, err)
+	default:
+		respondMessage(c, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fintrack/internal/service"
+	"fintrack/internal/validation"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoanHandler struct {
+	Service service.LoanService
+}
+
+func NewLoanHandler(s service.LoanService) *LoanHandler {
+	return &LoanHandler{Service: s}
+}
+
+// RegisterRoutes 註冊貸款相關路由
+func (h *LoanHandler) RegisterRoutes(r gin.IRouter) {
+	r.POST("/loans", h.CreateLoan)                 // 新增貸款
+	r.GET("/loans", h.GetLoans)                    // 查詢貸款
+	r.GET("/loans/:id/schedule", h.GetSchedule)    // 查詢攤還表
+	r.POST("/loans/:id/payments", h.RecordPayment) // 記錄還款並拆分本金與利息
+	r.GET("/loans/:id/report", h.GetLoanReport)    // 查詢期間內的還款摘要
+}
+
+// 新增貸款
+func (h *LoanHandler) CreateLoan(c *gin.Context) {
+	var loan entity.Loan
+	if !bindJSON(c, &loan) {
+		return
+	}
+
+	created, err := h.Service.CreateLoan(loan)
+	if err != nil {
+		respondLoanError(c, err, "Failed to create loan")
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// 查詢用戶的貸款
+func (h *LoanHandler) GetLoans(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		respondFieldError(c, "user_id", validation.CodeRequired, nil, "user_id is required")
+		return
+	}
+
+	loans, err := h.Service.GetLoans(userID)
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to fetch loans")
+		return
+	}
+
+	c.JSON(http.StatusOK, loans)
+}
+
+// 查詢貸款的攤還表
+func (h *LoanHandler) GetSchedule(c *gin.Context) {
+	schedule, err := h.Service.GetSchedule(c.Query("user_id"), c.Param("id"))
+	if err != nil {
+		respondLoanError(c, err, "Failed to build amortization schedule")
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// paymentRequest 表示一筆貸款還款
+type paymentRequest struct {
+	UserID string    `json:"user_id" binding:"required"`
+	Date   time.Time `json:"date" binding:"required"`
+	Amount float64   `json:"amount" binding:"required"`
+}
+
+// 記錄貸款還款
+func (h *LoanHandler) RecordPayment(c *gin.Context) {
+	var req paymentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	payment, err := h.Service.RecordPayment(req.UserID, c.Param("id"), req.Date, req.Amount)
+	if err != nil {
+		respondLoanError(c, err, "Failed to record loan payment")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, payment)
+}
+
+// 查詢期間內的還款摘要，預設為今年初至今天
+func (h *LoanHandler) GetLoanReport(c *gin.Context) {
+	now := time.Now().UTC()
+	from, ok := parseDateQuery(c, "start_date", time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC))
+	if !ok {
+		return
+	}
+	to, ok := parseDateQuery(c, "end_date", now.Truncate(24*time.Hour))
+	if !ok {
+		return
+	}
+
+	report, err := h.Service.GetLoanReport(c.Query("user_id"), c.Param("id"), from, to)
+	if err != nil {
+		respondLoanError(c, err, "Failed to generate loan report")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// respondLoanError 將貸款相關錯誤轉換為對應的 HTTP 狀態碼
+func respondLoanError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, db.ErrLoanNotFound):
+		respondError(c, http.StatusNotFound, err)
+	case errors.Is(err, service.ErrInvalidLoan):
+		respondError(c, http.StatusBadRequest, err)
+	default:
+		respondMessage(c, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// openAPISpec 為描述所有路由的 OpenAPI 文件，路由或回應結構變更時須同步修改，openapi_test.go 會檢查兩者一致
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// swaggerInitializer 取代 Swagger UI 預設的初始化腳本，改為載入本服務的文件
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "/openapi.json",
+    dom_id: '#swagger-ui',
+    deepLinking: true,
+    presets: [
+      SwaggerUIBundle.presets.apis,
+      SwaggerUIStandalonePreset
+    ],
+    plugins: [
+      SwaggerUIBundle.plugins.DownloadUrl
+    ],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// 返回 OpenAPI 文件
+func GetOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPISpec)
+}
+
+// 提供內嵌的 Swagger UI
+func GetSwaggerUI(c *gin.Context) {
+	path := c.Param("filepath")
+	if path == "/swagger-initializer.js" {
+		c.Data(http.StatusOK, "application/javascript; charset=utf-8", []byte(swaggerInitializer))
+		return
+	}
+	c.FileFromFS(path, http.FS(swaggerFiles.FS))
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fintrack/internal/service"
+	"fintrack/internal/validation"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxArchiveUploadSize 為匯入封存檔的大小上限
+const maxArchiveUploadSize = 100 << 20
+
+type PortabilityHandler struct {
+	Service service.PortabilityService
+}
+
+func NewPortabilityHandler(s service.PortabilityService) *PortabilityHandler {
+	return &PortabilityHandler{Service: s}
+}
+
+// RegisterRoutes 註冊用戶資料匯出與匯入路由
+func (h *PortabilityHandler) RegisterRoutes(r gin.IRouter) {
+	r.GET("/archive", h.ExportUser)  // 下載用戶的完整資料封存檔
+	r.POST("/archive", h.ImportUser) // 匯入封存檔至指定用戶
+}
+
+// 下載用戶的完整資料封存檔 (zip)
+func (h *PortabilityHandler) ExportUser(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		respondFieldError(c, "user_id", validation.CodeRequired, nil, "user_id is required")
+		return
+	}
+
+	var buf bytes.Buffer
+	manifest, err := h.Service.ExportUser(userID, &buf)
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to export user data")
+		return
+	}
+
+	filename := "fintrack-" + userID + "-" + manifest.ExportedAt.Format("20060102") + ".zip"
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// 匯入封存檔至指定用戶，請求內容為匯出的 zip 檔
+func (h *PortabilityHandler) ImportUser(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		respondFieldError(c, "user_id", validation.CodeRequired, nil, "user_id is required")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxArchiveUploadSize))
+	if err != nil {
+		respondMessage(c, http.StatusRequestEntityTooLarge, "archive exceeds the upload size limit")
+		return
+	}
+
+	summary, err := h.Service.ImportUser(bytes.NewReader(body), int64(len(body)), userID)
+	if errors.Is(err, service.ErrInvalidArchive) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, service.ErrArchiveConflict) {
+		respondError(c, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to import user data")
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
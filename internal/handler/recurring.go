@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"errors"
+	"fintrack/internal/service"
+	"fintrack/internal/validation"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RecurringHandler struct {
+	Service service.RecurringService
+}
+
+func NewRecurringHandler(s service.RecurringService) *RecurringHandler {
+	return &RecurringHandler{Service: s}
+}
+
+// RegisterRoutes 註冊週期性收支相關路由
+func (h *RecurringHandler) RegisterRoutes(r gin.IRouter) {
+	r.GET("/recurring/detected", h.GetDetectedSeries)       // 偵測週期性收支與訂閱
+	r.GET("/recurring/schedules", h.GetSchedules)           // 查詢受管理的排程
+	r.POST("/recurring/schedules/promote", h.PromoteSeries) // 將偵測結果轉為受管理排程
+	r.GET("/calendar", h.GetCalendar)                       // 查詢帳單行事曆與預估餘額
+}
+
+// 偵測用戶的週期性收支與訂閱
+func (h *RecurringHandler) GetDetectedSeries(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		respondFieldError(c, "user_id", validation.CodeRequired, nil, "user_id is required")
+		return
+	}
+
+	series, err := h.Service.DetectRecurring(userID)
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to detect recurring transactions")
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// 查詢受管理的週期性排程
+func (h *RecurringHandler) GetSchedules(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		respondFieldError(c, "user_id", validation.CodeRequired, nil, "user_id is required")
+		return
+	}
+
+	schedules, err := h.Service.GetSchedules(userID)
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to fetch recurring schedules")
+		return
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+// promoteRequest 指定要轉為排程的偵測結果
+type promoteRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Payee  string `json:"payee" binding:"required"`
+	Type   string `json:"type"` // INCOME 或 EXPENSE，收款方同時有收入與支出序列時必填
+}
+
+// 將偵測到的週期性收支轉為受管理排程
+func (h *RecurringHandler) PromoteSeries(c *gin.Context) {
+	var req promoteRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	schedule, err := h.Service.PromoteSeries(req.UserID, req.Payee, req.Type)
+	switch {
+	case errors.Is(err, service.ErrAmbiguousSeries):
+		respondError(c, http.StatusBadRequest, err)
+		return
+	case errors.Is(err, service.ErrSeriesNotFound):
+		respondError(c, http.StatusNotFound, err)
+		return
+	case errors.Is(err, service.ErrScheduleExists):
+		respondError(c, http.StatusConflict, err)
+		return
+	case err != nil:
+		respondMessage(c, http.StatusInternalServerError, "Failed to create recurring schedule")
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// maxCalendarDays 限制行事曆單次查詢的天數
+const maxCalendarDays = 366
+
+// 查詢指定期間的帳單行事曆，預設為今天起 30 天
+func (h *RecurringHandler) GetCalendar(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		respondFieldError(c, "user_id", validation.CodeRequired, nil, "user_id is required")
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, to := today, today.AddDate(0, 0, 30)
+	var err error
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(dateLayout, v); err != nil {
+			respondFieldError(c, "from", validation.CodeInvalidFormat, v, "from must be in YYYY-MM-DD format")
+			return
+		}
+		to = from.AddDate(0, 0, 30)
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(dateLayout, v); err != nil {
+			respondFieldError(c, "to", validation.CodeInvalidFormat, v, "to must be in YYYY-MM-DD format")
+			return
+		}
+	}
+	if to.Before(from) || to.Sub(from) > maxCalendarDays*24*time.Hour {
+		respondFieldError(c, "to", validation.CodeInvalidValue, c.Query("to"), "to must be after from and within 366 days")
+		return
+	}
+
+	calendar, err := h.Service.GetCalendar(userID, from, to)
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to build calendar")
+		return
+	}
+
+	c.JSON(http.StatusOK, calendar)
+}
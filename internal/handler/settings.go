@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"fintrack/internal/entity"
+	"fintrack/internal/service"
+	"fintrack/internal/validation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SettingsHandler struct {
+	Service service.SettingsService
+}
+
+func NewSettingsHandler(s service.SettingsService) *SettingsHandler {
+	return &SettingsHandler{Service: s}
+}
+
+// RegisterRoutes 註冊用戶偏好設定路由
+func (h *SettingsHandler) RegisterRoutes(r gin.IRouter) {
+	r.GET("/settings", h.GetSettings)  // 查詢時區與會計年度設定
+	r.PUT("/settings", h.SaveSettings) // 更新時區與會計年度設定
+}
+
+// 查詢用戶的時區與會計年度設定
+func (h *SettingsHandler) GetSettings(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		respondFieldError(c, "user_id", validation.CodeRequired, nil, "user_id is required")
+		return
+	}
+
+	settings, err := h.Service.GetSettings(userID)
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to fetch settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// 更新用戶的時區與會計年度設定
+func (h *SettingsHandler) SaveSettings(c *gin.Context) {
+	var settings entity.UserSettings
+	if !bindJSON(c, &settings) {
+		return
+	}
+	settings.UserID = c.Query("user_id")
+
+	saved, err := h.Service.SaveSettings(settings)
+	if errors.Is(err, service.ErrInvalidSettings) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to save settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
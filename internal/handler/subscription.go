@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fintrack/internal/service"
+	"fintrack/internal/validation"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reportSchedulerInterval 為檢查到期訂閱與重試寄送的間隔
+const reportSchedulerInterval = time.Minute
+
+type SubscriptionHandler struct {
+	Service service.SubscriptionService
+}
+
+func NewSubscriptionHandler(s service.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{Service: s}
+}
+
+// RegisterRoutes 註冊報表訂閱相關路由
+func (h *SubscriptionHandler) RegisterRoutes(r gin.IRouter) {
+	r.POST("/subscriptions", h.CreateSubscription)          // 新增報表訂閱
+	r.GET("/subscriptions", h.GetSubscriptions)             // 查詢報表訂閱
+	r.DELETE("/subscriptions/:id", h.DeleteSubscription)    // 取消報表訂閱
+	r.GET("/subscriptions/:id/deliveries", h.GetDeliveries) // 查詢寄送紀錄
+}
+
+// 新增報表訂閱
+func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
+	var subscription entity.ReportSubscription
+	if !bindJSON(c, &subscription) {
+		return
+	}
+
+	created, err := h.Service.CreateSubscription(subscription)
+	if err != nil {
+		respondSubscriptionError(c, err, "Failed to create subscription")
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// 查詢用戶的報表訂閱
+func (h *SubscriptionHandler) GetSubscriptions(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		respondFieldError(c, "user_id", validation.CodeRequired, nil, "user_id is required")
+		return
+	}
+
+	subscriptions, err := h.Service.GetSubscriptions(userID)
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to fetch subscriptions")
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// 取消報表訂閱
+func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
+	if err := h.Service.DeleteSubscription(c.Query("user_id"), c.Param("id")); err != nil {
+		respondSubscriptionError(c, err, "Failed to delete subscription")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// 查詢訂閱的寄送紀錄
+func (h *SubscriptionHandler) GetDeliveries(c *gin.Context) {
+	deliveries, err := h.Service.GetDeliveries(c.Query("user_id"), c.Param("id"))
+	if err != nil {
+		respondSubscriptionError(c, err, "Failed to fetch deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func respondSubscriptionError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, db.ErrSubscriptionNotFound):
+		respondError(c, http.StatusNotFound, err)
+	case errors.Is(err, service.ErrInvalidSubscription):
+		respondError(c, http.StatusBadRequest, err)
+	default:
+		respondMessage(c, http.StatusInternalServerError, message)
+	}
+}
+
+// ReportScheduler 定期寄送到期的訂閱報表
+type ReportScheduler struct {
+	Service service.SubscriptionService
+}
+
+func NewReportScheduler(s service.SubscriptionService) *ReportScheduler {
+	return &ReportScheduler{Service: s}
+}
+
+// Start 在背景每分鐘檢查一次到期的訂閱與待重試的寄送
+func (s *ReportScheduler) Start() {
+	go func() {
+		ticker := time.NewTicker(reportSchedulerInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if err := s.Service.RunDue(context.Background(), now.UTC()); err != nil {
+				log.Printf("Failed to run report deliveries: %v", err)
+			}
+		}
+	}()
+}
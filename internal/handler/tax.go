@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"errors"
+	"fintrack/internal/entity"
+	"fintrack/internal/service"
+	"fintrack/internal/validation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaxHandler struct {
+	Service service.TaxService
+}
+
+func NewTaxHandler(s service.TaxService) *TaxHandler {
+	return &TaxHandler{Service: s}
+}
+
+// RegisterRoutes 註冊稅務類別對應路由
+func (h *TaxHandler) RegisterRoutes(r gin.IRouter) {
+	r.GET("/tax/mappings", h.GetTaxMappings)  // 查詢分類與標籤的稅務類別對應
+	r.PUT("/tax/mappings", h.SaveTaxMappings) // 取代稅務類別對應
+}
+
+// 查詢用戶生效中的稅務類別對應
+func (h *TaxHandler) GetTaxMappings(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		respondFieldError(c, "user_id", validation.CodeRequired, nil, "user_id is required")
+		return
+	}
+
+	mappings, err := h.Service.GetTaxMappings(userID)
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to fetch tax mappings")
+		return
+	}
+
+	c.JSON(http.StatusOK, mappings)
+}
+
+// 以請求內容取代用戶的稅務類別對應，空陣列恢復預設對應
+func (h *TaxHandler) SaveTaxMappings(c *gin.Context) {
+	var mappings []entity.TaxMapping
+	if !bindJSON(c, &mappings) {
+		return
+	}
+
+	saved, err := h.Service.SaveTaxMappings(c.Query("user_id"), mappings)
+	if errors.Is(err, service.ErrInvalidTaxMapping) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to save tax mappings")
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
@@ -0,0 +1,181 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fintrack/config"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 定義郵件寄送接口
+type Sender interface {
+	Send(msg Message) error
+}
+
+// Message 表示一封郵件，附件以 base64 編碼
+type Message struct {
+	From        string // 空字串時使用設定檔的寄件者
+	To          []string
+	Subject     string
+	Body        string // 純文字內容
+	Attachments []Attachment
+}
+
+// Attachment 表示郵件附件
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// sendTimeout 為寄送單封郵件（連線、認證到寄出）的時間上限，避免無回應的伺服器阻塞排程
+const sendTimeout = time.Minute
+
+// SMTP 實現 Sender 接口
+type SMTP struct {
+	config  config.SMTPConfig
+	timeout time.Duration
+}
+
+// NewSender 創建並返回一個 Sender 實例，並使用 SMTP 寄送
+func NewSender(config config.SMTPConfig) Sender {
+	return NewSMTP(config)
+}
+
+// NewSMTP 使用 SMTPConfig 來初始化 SMTP
+func NewSMTP(config config.SMTPConfig) *SMTP {
+	return &SMTP{config: config, timeout: sendTimeout}
+}
+
+// Send 透過 SMTP 寄送郵件，伺服器支援 STARTTLS 時會自動加密連線
+func (s *SMTP) Send(msg Message) error {
+	if msg.From == "" {
+		msg.From = s.config.From
+	}
+	if msg.From == "" || len(msg.To) == 0 {
+		return errors.New("mail: sender and recipients are required")
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	// smtp.SendMail 沒有逾時，自行連線並為整個對話設定期限
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	conn, err := net.DialTimeout("tcp", addr, s.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.config.Host}); err != nil {
+			return err
+		}
+	}
+	if s.config.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("mail: server doesn't support AUTH")
+		}
+		if err := c.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(msg.From); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Bytes 將郵件組成 MIME 格式，有附件時使用 multipart/mixed
+func (m Message) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	headers := []string{
+		"From: " + m.From,
+		"To: " + strings.Join(m.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+	}
+	for _, h := range headers {
+		buf.WriteString(h + "\r\n")
+	}
+
+	if len(m.Attachments) == 0 {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: base64\r\n\r\n")
+		writeBase64(&buf, []byte(m.Body))
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", mw.Boundary())
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeBase64(part, []byte(m.Body))
+
+	for _, a := range m.Attachments {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64(part, a.Data)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 以每行 76 個字元輸出 base64 內容
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		_, _ = w.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	_, _ = w.Write([]byte(encoded + "\r\n"))
+}
@@ -0,0 +1,139 @@
+package mail
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fintrack/config"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTPServer 啟動只支援基本指令的本機 SMTP 伺服器，收到的郵件內容會送到 received；
+// reject 為 true 時拒絕收件者
+func fakeSMTPServer(t *testing.T, reject bool) (int, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "RCPT") && reject:
+				reply("550 mailbox unavailable")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"), strings.HasPrefix(cmd, "RSET"), strings.HasPrefix(cmd, "NOOP"):
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(strings.TrimPrefix(l, "."))
+				}
+				received <- data.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestSMTPSend(t *testing.T) {
+	port, received := fakeSMTPServer(t, false)
+	sender := NewSMTP(config.SMTPConfig{Host: "127.0.0.1", Port: port, From: "reports@fintrack.local"})
+
+	err := sender.Send(Message{
+		To:          []string{"user@example.com"},
+		Subject:     "2024-03 月報",
+		Body:        "本月報表如附件",
+		Attachments: []Attachment{{Filename: "report.csv", ContentType: "text/csv", Data: []byte("Period,Income\n2024-03,1000\n")}},
+	})
+	assert.NoError(t, err)
+
+	msg, err := mail.ReadMessage(strings.NewReader(<-received))
+	assert.NoError(t, err)
+	assert.Equal(t, "reports@fintrack.local", msg.Header.Get("From"))
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.Equal(t, "2024-03 月報", subject)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	_, err = mr.NextPart()
+	assert.NoError(t, err)
+	part, err := mr.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "report.csv", part.FileName())
+	assert.Equal(t, "base64", part.Header.Get("Content-Transfer-Encoding"))
+	data, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+	assert.NoError(t, err)
+	assert.Equal(t, "Period,Income\n2024-03,1000\n", string(data))
+}
+
+func TestSMTPSendRejected(t *testing.T) {
+	port, _ := fakeSMTPServer(t, true)
+	sender := NewSMTP(config.SMTPConfig{Host: "127.0.0.1", Port: port, From: "reports@fintrack.local"})
+
+	err := sender.Send(Message{To: []string{"nobody@example.com"}, Subject: "test", Body: "test"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "550")
+}
+
+func TestSMTPSendTimeout(t *testing.T) {
+	// 接受連線但從不回應的伺服器
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { conn.Close() })
+	}()
+
+	sender := NewSMTP(config.SMTPConfig{Host: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port, From: "reports@fintrack.local"})
+	sender.timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	err = sender.Send(Message{To: []string{"user@example.com"}, Subject: "test", Body: "test"})
+	var netErr net.Error
+	if assert.ErrorAs(t, err, &netErr) {
+		assert.True(t, netErr.Timeout())
+	}
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -11,6 +11,7 @@ import (
 // MQProducer 定義生產者接口
 type MQProducer interface {
 	SendMessage(body []byte) error
+	SendMessages(bodies [][]byte) error
 	Close() error
 }
 
@@ -31,7 +32,7 @@ func NewMQProducer(config config.RabbitMQConfig) (MQProducer, error) {
 	return NewRabbitMQClient(config)
 }
 
-func NewMQConsumer(config config.RabbitMQConfig) (MQProducer, error)  {
+func NewMQConsumer(config config.RabbitMQConfig) (MQProducer, error) {
 	return NewRabbitMQClient(config)
 }
 
@@ -83,16 +84,45 @@ func (c *RabbitMQClient) SendMessage(body []byte) error {
 	return err
 }
 
+// SendMessages 在獨立的 AMQP 事務通道中發送一批消息，全部送出或全部不送出
+func (c *RabbitMQClient) SendMessages(bodies [][]byte) error {
+	ch, err := c.connection.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer ch.Close()
+
+	if err := ch.Tx(); err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	for _, body := range bodies {
+		err := ch.Publish("", c.queue.Name, false, false, amqp.Publishing{
+			ContentType: "application/json",
+			Body:        body,
+		})
+		if err != nil {
+			log.Printf("Failed to send message to RabbitMQ: %v", err)
+			_ = ch.TxRollback()
+			return err
+		}
+	}
+	if err := ch.TxCommit(); err != nil {
+		log.Printf("Failed to commit messages to RabbitMQ: %v", err)
+		return err
+	}
+	return nil
+}
+
 // ConsumeMessages 消費 RabbitMQ 隊列中的消息
 func (c *RabbitMQClient) ConsumeMessages() (<-chan amqp.Delivery, error) {
 	msgs, err := c.channel.Consume(
 		c.queue.Name, // queue
-		"",                  // consumer
-		true,                // auto-ack
-		false,               // exclusive
-		false,               // no-local
-		false,               // no-wait
-		nil,                 // args
+		"",           // consumer
+		true,         // auto-ack
+		false,        // exclusive
+		false,        // no-local
+		false,        // no-wait
+		nil,          // args
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to consume messages: %w", err)
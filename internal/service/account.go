@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fintrack/internal/cache"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fintrack/internal/mq"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidAccount 表示帳戶資料不正確
+var ErrInvalidAccount = errors.New("invalid account")
+
+// AccountSummary 表示帳戶及其目前餘額
+type AccountSummary struct {
+	entity.Account
+	Balance float64 `json:"balance"`
+}
+
+// maxInstallments 分期付款的最大期數
+const maxInstallments = 60
+
+// ErrInvalidInstallment 表示分期付款資料不正確
+var ErrInvalidInstallment = errors.New("invalid installment plan")
+
+// Statement 表示信用卡某期帳單，用於與銀行帳單對帳
+type Statement struct {
+	AccountID         string               `json:"account_id"`
+	Cycle             string               `json:"cycle"` // 結帳日
+	PeriodStart       string               `json:"period_start"`
+	PeriodEnd         string               `json:"period_end"`
+	DueDate           string               `json:"due_date"`
+	Charges           float64              `json:"charges"`
+	Credits           float64              `json:"credits"`
+	BalanceDue        float64              `json:"balance_due"`
+	ReconciledCount   int                  `json:"reconciled_count"`
+	UnreconciledCount int                  `json:"unreconciled_count"`
+	Transactions      []entity.Transaction `json:"transactions"`
+}
+
+type AccountService interface {
+	CreateAccount(account entity.Account) (*entity.Account, error)
+	GetAccounts(userID string) ([]AccountSummary, error)
+	CreateInstallmentPlan(accountID string, plan entity.InstallmentPlan) (*entity.InstallmentPlan, error)
+	GetInstallmentPlans(userID, accountID string) ([]entity.InstallmentPlan, error)
+	GetStatement(userID, accountID string, year int, month time.Month) (*Statement, error)
+}
+
+type accountService struct {
+	repo     db.DBClient
+	cache    cache.Cache
+	producer mq.MQProducer
+}
+
+func NewAccountService(repo db.DBClient, cache cache.Cache, producer mq.MQProducer) AccountService {
+	return &accountService{repo: repo, cache: cache, producer: producer}
+}
+
+// CreateAccount 驗證並建立帳戶，帳戶 ID 由伺服器產生
+func (s *accountService) CreateAccount(account entity.Account) (*entity.Account, error) {
+	if err := validateAccount(account); err != nil {
+		return nil, err
+	}
+
+	account.ID = uuid.NewString()
+	account.CreatedAt = time.Now()
+	if err := s.repo.SaveAccount(account); err != nil {
+		return nil, err
+	}
+	// 淨資產報表包含帳戶的期初餘額，新帳戶須使快取的報表失效
+	if err := bumpReportVersion(context.Background(), s.cache, account.UserID); err != nil {
+		log.Printf("Failed to invalidate cached reports: %v", err)
+	}
+	return &account, nil
+}
+
+// GetAccounts 查詢用戶的帳戶及目前餘額（期初餘額加上所有已記錄的交易）
+func (s *accountService) GetAccounts(userID string) ([]AccountSummary, error) {
+	accounts, err := s.repo.GetAccounts(userID)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := s.repo.GetAccountBalances(userID, time.Now().AddDate(0, 0, 1).Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]AccountSummary, 0, len(accounts))
+	for _, account := range accounts {
+		summaries = append(summaries, AccountSummary{
+			Account: account,
+			Balance: roundAmount(account.OpeningBalance + balances[account.ID]),
+		})
+	}
+	return summaries, nil
+}
+
+// validateAccount 驗證帳戶欄位，信用卡需設定結帳日與繳款日
+func validateAccount(account entity.Account) error {
+	if account.UserID == "" || account.Name == "" {
+		return fmt.Errorf("%w: user_id and account_name are required", ErrInvalidAccount)
+	}
+	switch account.Type {
+	case entity.AccountTypeBank, entity.AccountTypeCash, entity.AccountTypeInvestment:
+	case entity.AccountTypeCreditCard:
+		if account.ClosingDay < 1 || account.ClosingDay > 31 || account.DueDay < 1 || account.DueDay > 31 {
+			return fmt.Errorf("%w: credit cards require closing_day and due_day between 1 and 31", ErrInvalidAccount)
+		}
+	default:
+		return fmt.Errorf("%w: account_type must be BANK, CREDIT_CARD, CASH or INVESTMENT", ErrInvalidAccount)
+	}
+	return nil
+}
+
+// CreateInstallmentPlan 建立信用卡分期付款計畫，並將每期扣款以子交易送至 RabbitMQ 寫入。
+// 子交易以一次事務送出，送出失敗時刪除計畫，避免留下只有部分扣款的計畫
+func (s *accountService) CreateInstallmentPlan(accountID string, plan entity.InstallmentPlan) (*entity.InstallmentPlan, error) {
+	if _, err := s.creditCard(plan.UserID, accountID); err != nil {
+		return nil, err
+	}
+	if plan.TotalAmount <= 0 || plan.Installments < 2 || plan.Installments > maxInstallments || plan.PurchaseDate.IsZero() {
+		return nil, fmt.Errorf("%w: total_amount must be positive, purchase_date is required and installments must be between 2 and %d", ErrInvalidInstallment, maxInstallments)
+	}
+
+	plan.ID = uuid.NewString()
+	plan.AccountID = accountID
+	plan.CreatedAt = time.Now()
+
+	// 子交易以 InstallmentPlanID 與 InstallmentNo 對應計畫
+	amounts := plan.Amounts()
+	events := make([]TransactionEvent, 0, len(amounts))
+	for i, amount := range amounts {
+		n := i + 1
+		child := entity.Transaction{
+			ID:                newTransactionID(),
+			UserID:            plan.UserID,
+			Date:              plan.ChargeDate(n),
+			Amount:            amount,
+			Category:          plan.Category,
+			Description:       fmt.Sprintf("%s (%d/%d)", plan.Description, n, plan.Installments),
+			Source:            "CREDIT_CARD",
+			Type:              entity.TypeExpense,
+			Payee:             plan.Payee,
+			AccountID:         accountID,
+			InstallmentPlanID: plan.ID,
+			InstallmentNo:     n,
+		}
+		events = append(events, TransactionEvent{Event: EventTransactionCreated, UserID: plan.UserID, Transaction: child})
+	}
+
+	if err := s.repo.SaveInstallmentPlan(plan); err != nil {
+		return nil, err
+	}
+	if _, err := sendEvents(context.Background(), s.cache, s.producer, events); err != nil {
+		if delErr := s.repo.DeleteInstallmentPlan(plan.ID); delErr != nil {
+			log.Printf("Failed to delete installment plan %s after publish failure: %v", plan.ID, delErr)
+		}
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// GetInstallmentPlans 查詢信用卡的分期付款計畫
+func (s *accountService) GetInstallmentPlans(userID, accountID string) ([]entity.InstallmentPlan, error) {
+	if _, err := s.creditCard(userID, accountID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetInstallmentPlans(accountID)
+}
+
+// GetStatement 返回信用卡在指定月份結帳的帳單
+func (s *accountService) GetStatement(userID, accountID string, year int, month time.Month) (*Statement, error) {
+	account, err := s.creditCard(userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	closing := account.ClosingDate(year, month, time.UTC)
+	prevClosing, _ := account.StatementPeriod(closing)
+	periodStart := prevClosing.AddDate(0, 0, 1)
+	transactions, err := s.repo.GetStatementTransactions(accountID, closing.Format(dateLayout), periodStart.Format(dateLayout), closing.AddDate(0, 0, 1).Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &Statement{
+		AccountID:    accountID,
+		Cycle:        closing.Format(dateLayout),
+		PeriodStart:  periodStart.Format(dateLayout),
+		PeriodEnd:    closing.Format(dateLayout),
+		DueDate:      account.DueDate(closing).Format(dateLayout),
+		Transactions: transactions,
+	}
+	for _, tx := range transactions {
+		if tx.IsIncome() {
+			statement.Credits += tx.Amount
+		} else {
+			statement.Charges += tx.Amount
+		}
+		if tx.Reconciled {
+			statement.ReconciledCount++
+		} else {
+			statement.UnreconciledCount++
+		}
+	}
+	statement.Charges = roundAmount(statement.Charges)
+	statement.Credits = roundAmount(statement.Credits)
+	statement.BalanceDue = roundAmount(statement.Charges - statement.Credits)
+	return statement, nil
+}
+
+// creditCard 查詢屬於該用戶且已設定結帳日的信用卡帳戶
+func (s *accountService) creditCard(userID, accountID string) (*entity.Account, error) {
+	account, err := s.repo.GetAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.UserID != userID {
+		return nil, db.ErrAccountNotFound
+	}
+	if !account.IsLiability() || account.ClosingDay == 0 {
+		return nil, fmt.Errorf("%w: account is not a credit card with a closing day", ErrInvalidAccount)
+	}
+	return account, nil
+}
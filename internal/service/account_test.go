@@ -0,0 +1,169 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// accountRepo 以記憶體保存帳戶、分期計畫與帳單交易
+type accountRepo struct {
+	db.DBClient
+	accounts      map[string]entity.Account
+	plans         map[string]entity.InstallmentPlan
+	transactions  []entity.Transaction
+	statementArgs []string
+}
+
+func (r *accountRepo) GetAccountByID(accountID string) (*entity.Account, error) {
+	account, ok := r.accounts[accountID]
+	if !ok {
+		return nil, db.ErrAccountNotFound
+	}
+	return &account, nil
+}
+
+func (r *accountRepo) SaveInstallmentPlan(plan entity.InstallmentPlan) error {
+	r.plans[plan.ID] = plan
+	return nil
+}
+
+func (r *accountRepo) DeleteInstallmentPlan(planID string) error {
+	delete(r.plans, planID)
+	return nil
+}
+
+func (r *accountRepo) GetStatementTransactions(accountID, cycle, periodStart, periodEnd string) ([]entity.Transaction, error) {
+	r.statementArgs = []string{accountID, cycle, periodStart, periodEnd}
+	return r.transactions, nil
+}
+
+func newAccountRepo() *accountRepo {
+	return &accountRepo{
+		accounts: map[string]entity.Account{
+			"card1": {ID: "card1", UserID: "user123", Type: entity.AccountTypeCreditCard, ClosingDay: 5, DueDay: 20},
+			"bank1": {ID: "bank1", UserID: "user123", Type: entity.AccountTypeBank},
+		},
+		plans: make(map[string]entity.InstallmentPlan),
+	}
+}
+
+func TestCreateInstallmentPlan(t *testing.T) {
+	repo := newAccountRepo()
+	queue := &queueStub{}
+	c := &cacheStub{values: map[string]string{}}
+	s := &accountService{repo: repo, cache: c, producer: queue}
+
+	plan, err := s.CreateInstallmentPlan("card1", entity.InstallmentPlan{
+		UserID:       "user123",
+		Payee:        "Apple",
+		Description:  "iPhone",
+		PurchaseDate: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		TotalAmount:  1000,
+		Installments: 3,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Contains(t, repo.plans, plan.ID)
+	assert.Equal(t, "card1", plan.AccountID)
+
+	// 所有子交易以一次事務送出，每筆都是帶有提交紀錄的新增事件
+	assert.Equal(t, 1, queue.batches)
+	if !assert.Len(t, queue.messages, 3) {
+		return
+	}
+	var total float64
+	var last entity.Transaction
+	for i, message := range queue.messages {
+		var event TransactionEvent
+		assert.NoError(t, json.Unmarshal(message, &event))
+		assert.Equal(t, EventTransactionCreated, event.Event)
+		assert.Contains(t, c.values, submissionKey(event.SubmissionID))
+		child := event.Transaction
+		_, err := ulid.Parse(child.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, plan.ID, child.InstallmentPlanID)
+		assert.Equal(t, i+1, child.InstallmentNo)
+		assert.Equal(t, "card1", child.AccountID)
+		assert.Equal(t, entity.TypeExpense, child.Type)
+		total += child.Amount
+		last = child
+	}
+	assert.Equal(t, 1000.0, roundAmount(total))
+	assert.Equal(t, "iPhone (3/3)", last.Description)
+	assert.Equal(t, time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), last.Date)
+}
+
+func TestCreateInstallmentPlanDeletesPlanWhenPublishFails(t *testing.T) {
+	repo := newAccountRepo()
+	c := &cacheStub{values: map[string]string{}}
+	s := &accountService{repo: repo, cache: c, producer: &queueStub{err: errors.New("connection closed")}}
+
+	_, err := s.CreateInstallmentPlan("card1", entity.InstallmentPlan{
+		UserID:       "user123",
+		PurchaseDate: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC),
+		TotalAmount:  600,
+		Installments: 6,
+	})
+	assert.Error(t, err)
+	assert.Empty(t, repo.plans)
+	assert.Empty(t, c.values)
+}
+
+func TestCreateInstallmentPlanValidation(t *testing.T) {
+	repo := newAccountRepo()
+	queue := &queueStub{}
+	s := &accountService{repo: repo, producer: queue}
+	valid := entity.InstallmentPlan{UserID: "user123", PurchaseDate: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), TotalAmount: 600, Installments: 6}
+
+	_, err := s.CreateInstallmentPlan("bank1", valid)
+	assert.ErrorIs(t, err, ErrInvalidAccount)
+
+	other := valid
+	other.UserID = "user456"
+	_, err = s.CreateInstallmentPlan("card1", other)
+	assert.ErrorIs(t, err, db.ErrAccountNotFound)
+
+	single := valid
+	single.Installments = 1
+	_, err = s.CreateInstallmentPlan("card1", single)
+	assert.ErrorIs(t, err, ErrInvalidInstallment)
+
+	assert.Empty(t, repo.plans)
+	assert.Empty(t, queue.messages)
+}
+
+func TestGetStatement(t *testing.T) {
+	repo := newAccountRepo()
+	repo.transactions = []entity.Transaction{
+		{ID: "1", AccountID: "card1", Date: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), Amount: 1500, Reconciled: true},
+		{ID: "2", AccountID: "card1", Date: time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC), Amount: 333.33},
+		{ID: "3", AccountID: "card1", Date: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Amount: 200, Type: entity.TypeIncome, Reconciled: true},
+	}
+	s := &accountService{repo: repo}
+
+	statement, err := s.GetStatement("user123", "card1", 2024, time.June)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// 消費期間為上期結帳日的次日至本期結帳日，查詢的結束日不包含在內
+	assert.Equal(t, []string{"card1", "2024-06-05", "2024-05-06", "2024-06-06"}, repo.statementArgs)
+	assert.Equal(t, "2024-06-05", statement.Cycle)
+	assert.Equal(t, "2024-05-06", statement.PeriodStart)
+	assert.Equal(t, "2024-06-20", statement.DueDate)
+	assert.Equal(t, 1833.33, statement.Charges)
+	assert.Equal(t, 200.0, statement.Credits)
+	assert.Equal(t, 1633.33, statement.BalanceDue)
+	assert.Equal(t, 2, statement.ReconciledCount)
+	assert.Equal(t, 1, statement.UnreconciledCount)
+
+	_, err = s.GetStatement("user456", "card1", 2024, time.June)
+	assert.ErrorIs(t, err, db.ErrAccountNotFound)
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"fintrack/internal/entity"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// 異常交易類型
+const (
+	AnomalyPayeeOutlier    = "PAYEE_OUTLIER"    // 金額遠高於此收款方的歷史分布
+	AnomalyCategoryOutlier = "CATEGORY_OUTLIER" // 金額遠高於此分類的歷史分布
+	AnomalyNewMerchant     = "NEW_MERCHANT"     // 首次出現的收款方且金額偏高
+	AnomalyDuplicate       = "DUPLICATE"        // 短時間內相同收款方與金額的重複扣款
+)
+
+const (
+	anomalyHistoryMonths   = 12             // 建立歷史分布時回溯的月數
+	defaultAnomalyDays     = 30             // 未指定範圍時檢查最近的天數
+	anomalyMinHistory      = 5              // 計算分布所需的最少歷史筆數
+	outlierMADs            = 3.5            // 超過中位數幾個 MAD（換算為標準差）視為異常
+	outlierMinRatio        = 2.0            // 金額至少為中位數的倍數，避免固定金額的訂閱小幅調漲也被標記
+	newMerchantPercentile  = 0.9            // 新收款方的金額超過歷史支出此百分位時標記
+	newMerchantMinAmount   = 1000.0         // 新收款方標記的最低金額
+	duplicateWindow        = 48 * time.Hour // 重複扣款的時間窗
+	spikeMinRatio          = 1.5            // 分類支出超過預期的倍數
+	spikeMinIncrease       = 1000.0         // 分類支出超過預期的最低金額
+	madToStandardDeviation = 1.4826         // 常態分布下 MAD 換算為標準差的係數
+)
+
+// AnomalyReport 表示檢查期間內的異常交易與分類支出暴增
+type AnomalyReport struct {
+	User         string               `json:"user"`
+	ReportType   string               `json:"report_type"`
+	From         string               `json:"from"`
+	To           string               `json:"to"`
+	HistoryFrom  string               `json:"history_from"` // 歷史分布的起始日，至檢查期間開始前一日
+	Transactions []TransactionAnomaly `json:"transactions"`
+	Categories   []CategorySpike      `json:"categories"`
+}
+
+// TransactionAnomaly 表示被標記的交易及所有符合的原因
+type TransactionAnomaly struct {
+	Transaction entity.Transaction `json:"transaction"`
+	Reasons     []AnomalyReason    `json:"reasons"`
+}
+
+// AnomalyReason 表示標記的原因與說明
+type AnomalyReason struct {
+	Kind        string `json:"kind"`
+	Explanation string `json:"explanation"`
+	RelatedID   string `json:"related_id,omitempty"` // 重複扣款時為先前的交易
+}
+
+// CategorySpike 表示分類支出相較歷史平均暴增
+type CategorySpike struct {
+	Category    string  `json:"category"`
+	Amount      float64 `json:"amount"`
+	Expected    float64 `json:"expected"` // 依歷史每日平均換算至檢查期間的金額
+	Ratio       float64 `json:"ratio"`
+	Explanation string  `json:"explanation"`
+}
+
+// generateAnomalies 以檢查期間之前的支出建立收款方與分類的歷史分布，標記期間內的異常支出。
+// 分布以中位數與 MAD 計算，避免少數大額交易拉高門檻
+func (s *transactionService) generateAnomalies(cal userCalendar, userID, startDate, endDate string) (*AnomalyReport, error) {
+	from, to, err := anomalyRange(cal, startDate, endDate, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	historyFrom := from.AddDate(0, -anomalyHistoryMonths, 0)
+
+	transactions, err := s.repo.GetTransactions(userID, cal.dbTime(historyFrom), cal.dbTime(to.Add(-time.Second)))
+	if err != nil {
+		return nil, err
+	}
+	transactions = cal.localize(transactions)
+	sort.SliceStable(transactions, func(i, j int) bool { return transactions[i].Date.Before(transactions[j].Date) })
+
+	var history, current []entity.Transaction
+	for _, tx := range transactions {
+		if tx.IsIncome() {
+			continue
+		}
+		if tx.Date.Before(from) {
+			history = append(history, tx)
+		} else {
+			current = append(current, tx)
+		}
+	}
+
+	// 分類的每日平均只以實際有紀錄的期間計算，歷史不滿 12 個月的新用戶不會因分母過大而被標記暴增
+	historyStart := historyFrom
+	if len(history) > 0 {
+		d := history[0].Date
+		if first := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, cal.loc); first.After(historyStart) {
+			historyStart = first
+		}
+	}
+
+	report := &AnomalyReport{
+		User:         userID,
+		ReportType:   ReportTypeAnomalies,
+		From:         from.Format(dateLayout),
+		To:           to.AddDate(0, 0, -1).Format(dateLayout),
+		HistoryFrom:  historyFrom.Format(dateLayout),
+		Transactions: detectTransactionAnomalies(history, current),
+		Categories:   detectCategorySpikes(history, current, from.Sub(historyStart), to.Sub(from)),
+	}
+	return report, nil
+}
+
+// anomalyRange 返回檢查期間 [from, to)，未指定時為包含今天的最近 30 天
+func anomalyRange(cal userCalendar, startDate, endDate string, now time.Time) (time.Time, time.Time, error) {
+	today := now.In(cal.loc)
+	to := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, cal.loc).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -defaultAnomalyDays)
+	if startDate != "" {
+		start, err := cal.parseDate(startDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: start_date must be in YYYY-MM-DD format", ErrInvalidReportRequest)
+		}
+		from = start
+	}
+	if endDate != "" {
+		end, err := cal.parseDate(endDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: end_date must be in YYYY-MM-DD format", ErrInvalidReportRequest)
+		}
+		to = end.AddDate(0, 0, 1)
+	}
+	if !from.Before(to) || from.AddDate(1, 0, 0).Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: anomaly range must end after it starts and span at most one year", ErrInvalidReportRequest)
+	}
+	return from, to, nil
+}
+
+// detectTransactionAnomalies 依時間順序檢查每筆支出，檢查過的交易也會納入後續交易的分布
+func detectTransactionAnomalies(history, current []entity.Transaction) []TransactionAnomaly {
+	byPayee := make(map[string][]float64)
+	byCategory := make(map[string][]float64)
+	var all []float64
+	add := func(tx entity.Transaction) {
+		payee := normalizePayee(payeeOf(tx))
+		byPayee[payee] = append(byPayee[payee], tx.Amount)
+		byCategory[tx.Category] = append(byCategory[tx.Category], tx.Amount)
+		all = append(all, tx.Amount)
+	}
+	for _, tx := range history {
+		add(tx)
+	}
+
+	anomalies := []TransactionAnomaly{}
+	recent := append([]entity.Transaction(nil), history...)
+	for _, tx := range current {
+		var reasons []AnomalyReason
+		payee := normalizePayee(payeeOf(tx))
+
+		if amounts := byPayee[payee]; len(amounts) >= anomalyMinHistory {
+			if m, limit, ok := outlier(tx.Amount, amounts); ok {
+				reasons = append(reasons, AnomalyReason{
+					Kind:        AnomalyPayeeOutlier,
+					Explanation: fmt.Sprintf("%.2f is %.1fx the median of %.2f over %d earlier payments to %s (threshold %.2f)", tx.Amount, tx.Amount/m, m, len(amounts), payeeOf(tx), limit),
+				})
+			}
+		} else if amounts := byCategory[tx.Category]; len(amounts) >= anomalyMinHistory {
+			// 收款方歷史不足時以分類的分布判斷
+			if m, limit, ok := outlier(tx.Amount, amounts); ok {
+				reasons = append(reasons, AnomalyReason{
+					Kind:        AnomalyCategoryOutlier,
+					Explanation: fmt.Sprintf("%.2f is %.1fx the median of %.2f over %d earlier %s expenses (threshold %.2f)", tx.Amount, tx.Amount/m, m, len(amounts), tx.Category, limit),
+				})
+			}
+		}
+
+		if len(byPayee[payee]) == 0 && len(all) >= anomalyMinHistory {
+			limit := math.Max(percentile(all, newMerchantPercentile), newMerchantMinAmount)
+			if tx.Amount >= limit {
+				reasons = append(reasons, AnomalyReason{
+					Kind:        AnomalyNewMerchant,
+					Explanation: fmt.Sprintf("first payment to %s is %.2f, above %.0f%% of earlier expenses (%.2f)", payeeOf(tx), tx.Amount, newMerchantPercentile*100, limit),
+				})
+			}
+		}
+
+		if tx.InstallmentPlanID == "" {
+			for i := len(recent) - 1; i >= 0 && tx.Date.Sub(recent[i].Date) <= duplicateWindow; i-- {
+				prev := recent[i]
+				if prev.InstallmentPlanID == "" && prev.Amount == tx.Amount && normalizePayee(payeeOf(prev)) == payee {
+					reasons = append(reasons, AnomalyReason{
+						Kind:        AnomalyDuplicate,
+						Explanation: fmt.Sprintf("same payee and amount as %s on %s, %s earlier", prev.ID, prev.Date.Format(dateLayout), tx.Date.Sub(prev.Date).Round(time.Minute)),
+						RelatedID:   prev.ID,
+					})
+					break
+				}
+			}
+		}
+
+		if len(reasons) > 0 {
+			anomalies = append(anomalies, TransactionAnomaly{Transaction: tx, Reasons: reasons})
+		}
+		add(tx)
+		recent = append(recent, tx)
+	}
+	return anomalies
+}
+
+// outlier 判斷金額是否超過分布的門檻，返回中位數與門檻
+func outlier(amount float64, amounts []float64) (float64, float64, bool) {
+	m := median(amounts)
+	if m <= 0 {
+		return m, 0, false
+	}
+	deviations := make([]float64, len(amounts))
+	for i, a := range amounts {
+		deviations[i] = math.Abs(a - m)
+	}
+	limit := math.Max(m+outlierMADs*madToStandardDeviation*median(deviations), m*outlierMinRatio)
+	return m, roundAmount(limit), amount > limit
+}
+
+// percentile 返回數列的 p 百分位（最近排名法）
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// detectCategorySpikes 將各分類的歷史每日平均換算至檢查期間，標記明顯超出的分類；沒有歷史的分類不比較。
+// historySpan 為第一筆歷史交易至檢查期間開始的長度，短於檢查期間時歷史不足以估計，不標記任何分類
+func detectCategorySpikes(history, current []entity.Transaction, historySpan, span time.Duration) []CategorySpike {
+	spikes := []CategorySpike{}
+	if historySpan < span {
+		return spikes
+	}
+	past := make(map[string]float64)
+	for _, tx := range history {
+		past[tx.Category] += tx.Amount
+	}
+	totals := make(map[string]float64)
+	for _, tx := range current {
+		totals[tx.Category] += tx.Amount
+	}
+
+	scale := span.Hours() / historySpan.Hours()
+	historyDays := int(math.Round(historySpan.Hours() / 24))
+	for category, amount := range totals {
+		expected := past[category] * scale
+		if expected <= 0 || amount < expected*spikeMinRatio || amount-expected < spikeMinIncrease {
+			continue
+		}
+		ratio := amount / expected
+		spikes = append(spikes, CategorySpike{
+			Category:    category,
+			Amount:      roundAmount(amount),
+			Expected:    roundAmount(expected),
+			Ratio:       roundAmount(ratio),
+			Explanation: fmt.Sprintf("%s spending of %.2f is %.1fx the %.2f expected from the previous %d days", category, amount, ratio, expected, historyDays),
+		})
+	}
+	sort.Slice(spikes, func(i, j int) bool { return spikes[i].Amount-spikes[i].Expected > spikes[j].Amount-spikes[j].Expected })
+	return spikes
+}
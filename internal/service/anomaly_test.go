@@ -0,0 +1,97 @@
+package service
+
+import (
+	"bytes"
+	"fintrack/internal/entity"
+	"fintrack/internal/export"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateAnomalies(t *testing.T) {
+	at := func(month, day, hour int) time.Time {
+		return time.Date(2024, time.Month(month), day, hour, 0, 0, 0, time.UTC)
+	}
+	expense := func(id string, date time.Time, payee, category string, amount float64) entity.Transaction {
+		return entity.Transaction{ID: id, UserID: "user123", Date: date, Payee: payee, Category: category, Amount: amount, Type: entity.TypeExpense}
+	}
+
+	var transactions []entity.Transaction
+	for i := 0; i < 10; i++ {
+		transactions = append(transactions, expense(fmt.Sprintf("coffee%d", i), at(1+i/2, 3+i*2, 8), "Starbucks", "FOOD", 140+float64(i%3)*10))
+	}
+	for m := 1; m <= 12; m++ {
+		transactions = append(transactions, expense(fmt.Sprintf("px%d", m), time.Date(2023, time.Month(m), 20, 9, 0, 0, 0, time.UTC).AddDate(0, 5, 0), "PX Mart", "GROCERY", 1900+float64(m%3)*100))
+	}
+	for m := 1; m <= 5; m++ {
+		transactions = append(transactions, expense(fmt.Sprintf("nf%d", m), at(m, 15, 10), "Netflix", "ENTERTAINMENT", 390))
+	}
+	transactions = append(transactions,
+		expense("c1", at(6, 5, 9), "Starbucks", "FOOD", 900),
+		expense("c2", at(6, 10, 12), "Unknown Shop", "SHOPPING", 8000),
+		expense("c3", at(6, 15, 10), "Netflix", "ENTERTAINMENT", 390),
+		expense("c4", at(6, 16, 9), "netflix ", "ENTERTAINMENT", 390),
+		expense("c5", at(6, 2, 9), "PX Mart", "GROCERY", 2100),
+		expense("c6", at(6, 20, 9), "PX Mart", "GROCERY", 2100),
+		entity.Transaction{ID: "salary", UserID: "user123", Date: at(6, 5, 0), Category: "SALARY", Amount: 90000, Type: entity.TypeIncome},
+	)
+
+	s := &transactionService{repo: &stubRepo{transactions: transactions}}
+	report, err := s.generateAnomalies(utcCalendar, "user123", "2024-06-01", "2024-06-30")
+	assert.NoError(t, err)
+	assert.Equal(t, "2023-06-01", report.HistoryFrom)
+
+	kinds := make(map[string][]string)
+	for _, a := range report.Transactions {
+		for _, reason := range a.Reasons {
+			kinds[a.Transaction.ID] = append(kinds[a.Transaction.ID], reason.Kind)
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"c1": {AnomalyPayeeOutlier},
+		"c2": {AnomalyNewMerchant},
+		"c4": {AnomalyDuplicate},
+	}, kinds)
+	assert.Equal(t, "c3", report.Transactions[2].Reasons[0].RelatedID)
+	assert.Contains(t, report.Transactions[0].Reasons[0].Explanation, "6.0x the median of 150.00 over 10 earlier payments to Starbucks")
+
+	assert.Len(t, report.Categories, 1)
+	assert.Equal(t, "GROCERY", report.Categories[0].Category)
+	assert.Equal(t, 4200.0, report.Categories[0].Amount)
+
+	var buf bytes.Buffer
+	assert.NoError(t, export.WriteCSV(&buf, anomaliesDocument(report)))
+	assert.Contains(t, buf.String(), "DUPLICATE,")
+
+	_, err = s.generateAnomalies(utcCalendar, "user123", "2024-06-01", "2025-07-01")
+	assert.ErrorIs(t, err, ErrInvalidReportRequest)
+}
+
+func TestDetectCategorySpikesUsesRecordedHistory(t *testing.T) {
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 30)
+	expense := func(date time.Time, amount float64) entity.Transaction {
+		return entity.Transaction{UserID: "user123", Date: date, Category: "GROCERY", Amount: amount, Type: entity.TypeExpense}
+	}
+
+	// 新用戶只有 60 天的紀錄，每月支出穩定，不應被標記暴增
+	var transactions []entity.Transaction
+	for day := 60; day > 0; day -= 3 {
+		transactions = append(transactions, expense(from.AddDate(0, 0, -day), 300))
+	}
+	for day := 0; day < 30; day += 3 {
+		transactions = append(transactions, expense(from.AddDate(0, 0, day), 300))
+	}
+	s := &transactionService{repo: &stubRepo{transactions: transactions}}
+	report, err := s.generateAnomalies(utcCalendar, "user123", from.Format(dateLayout), to.AddDate(0, 0, -1).Format(dateLayout))
+	assert.NoError(t, err)
+	assert.Empty(t, report.Categories)
+
+	// 歷史短於檢查期間時無法估計，不標記
+	history := []entity.Transaction{expense(from.AddDate(0, 0, -5), 100)}
+	current := []entity.Transaction{expense(from, 5000)}
+	assert.Empty(t, detectCategorySpikes(history, current, 5*24*time.Hour, 30*24*time.Hour))
+}
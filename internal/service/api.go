@@ -7,17 +7,66 @@ import (
 	"fintrack/internal/cache"
 	"fintrack/internal/db"
 	"fintrack/internal/entity"
+	"fintrack/internal/export"
 	"fintrack/internal/mq"
+	"fintrack/internal/validation"
+	"fmt"
 	"io"
 	"log"
+	"strings"
 	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// dateLayout 為 API 與資料庫查詢共用的日期格式
+const dateLayout = "2006-01-02"
+
+// 報表類型
+const (
+	ReportTypeMonthly   = "monthly"
+	ReportTypeAnnual    = "annual"
+	ReportTypeForecast  = "forecast"
+	ReportTypeNetWorth  = "net_worth"
+	ReportTypeTax       = "tax_summary"
+	ReportTypeAnomalies = "anomalies"
 )
 
+// ErrInvalidReportRequest 表示報表參數不正確
+var ErrInvalidReportRequest = errors.New("invalid report request")
+
+// ErrInvalidTransaction 表示交易內容不正確
+var ErrInvalidTransaction = errors.New("invalid transaction")
+
+// TransactionPatch 表示部分修改交易的欄位，未提供的欄位維持原值；欄位名稱與 entity.Transaction 相同
+type TransactionPatch struct {
+	Date        *time.Time
+	Amount      *float64
+	Category    *string
+	Description *string
+	Notes       *string
+	Source      *string
+	Type        *string
+	Payee       *string
+	AccountID   *string
+	Tags        *string
+}
+
 type TransactionService interface {
-	AddTransaction(tx entity.Transaction) error
-	GetTransactions(userID, category, startDate, endDate string, page, pageSize int) ([]entity.Transaction, error)
+	AddTransaction(tx entity.Transaction) (*Submission, error)
+	AddTransactionOnce(ctx context.Context, idempotencyKey string, tx entity.Transaction) (*Submission, bool, error)
+	AddTransactionBatch(ctx context.Context, r io.Reader, ndjson bool) (*BatchResult, error)
+	GetTransactions(req TransactionListRequest) (*TransactionPage, error)
+	SearchTransactions(req TransactionListRequest, text string) (*SearchResult, error)
+	GetTransaction(userID, txID string) (*entity.Transaction, error)
+	UpdateTransaction(userID, txID string, tx entity.Transaction) (*Submission, error)
+	PatchTransaction(userID, txID string, patch TransactionPatch) (*Submission, error)
+	DeleteTransaction(userID, txID string) (*Submission, error)
+	GetSubmission(ctx context.Context, userID, submissionID string, wait bool) (*Submission, error)
 	ImportTransactions(data io.Reader) error
 	GenerateReport(ctx context.Context, userID, reportType, startDate, endDate string) (interface{}, error)
+	CompareReports(ctx context.Context, userID string, req ComparisonRequest) (*ComparisonReport, error)
+	ExportReport(ctx context.Context, userID, reportType, startDate, endDate string) (*export.Document, error)
 }
 
 type transactionService struct {
@@ -30,30 +79,270 @@ func NewTransactionService(repo db.DBClient, cache cache.Cache, producer mq.MQPr
 	return &transactionService{repo: repo, cache: cache, producer: producer}
 }
 
-// 新增交易紀錄，將寫入操作委派給 RabbitMQ 進行異步處理
-func (s *transactionService) AddTransaction(tx entity.Transaction) error {
-	if tx.Amount == 0 {
-		return errors.New("transaction amount cannot be zero")
+// 新增交易紀錄，將寫入操作委派給 RabbitMQ 進行異步處理。
+// 交易 ID 由伺服器產生可依時間排序的 ULID，客戶端提供的 ID 保留為外部參照
+func (s *transactionService) AddTransaction(tx entity.Transaction) (*Submission, error) {
+	if tx.ExternalID == "" {
+		tx.ExternalID = tx.ID
 	}
+	if err := validateTransaction(tx); err != nil {
+		return nil, err
+	}
+	tx.ID = newTransactionID()
 
 	// 將交易數據轉換為 JSON 並推送到 RabbitMQ
-	message, err := json.Marshal(tx)
+	return s.sendEvent(TransactionEvent{Event: EventTransactionCreated, UserID: tx.UserID, Transaction: tx})
+}
+
+// newTransactionID 產生新的交易 ID，ULID 的字典順序即建立時間順序
+func newTransactionID() string {
+	return ulid.Make().String()
+}
+
+// sendEvent 將交易事件推送到 RabbitMQ，與新增交易使用同一個隊列以保持處理順序。
+// 送出前先記錄待處理的提交，消費者處理後更新狀態，客戶端可憑提交 ID 查詢結果
+func (s *transactionService) sendEvent(event TransactionEvent) (*Submission, error) {
+	ctx := context.Background()
+	submission, message, err := prepareEvent(ctx, s.cache, event)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := s.producer.SendMessage(message); err != nil {
 		log.Printf("Failed to send transaction message to RabbitMQ: %v", err)
-		return err
+		_ = s.cache.Delete(ctx, submissionKey(submission.ID))
+		return nil, err
+	}
+
+	return submission, nil
+}
+
+// sendEvents 在同一個 RabbitMQ 事務中推送多筆交易事件，任一筆失敗時全部不送出並刪除已記錄的提交
+func sendEvents(ctx context.Context, c cache.Cache, producer mq.MQProducer, events []TransactionEvent) ([]*Submission, error) {
+	submissions := make([]*Submission, 0, len(events))
+	messages := make([][]byte, 0, len(events))
+	discard := func() {
+		for _, submission := range submissions {
+			_ = c.Delete(ctx, submissionKey(submission.ID))
+		}
+	}
+	for _, event := range events {
+		submission, message, err := prepareEvent(ctx, c, event)
+		if err != nil {
+			discard()
+			return nil, err
+		}
+		submissions = append(submissions, submission)
+		messages = append(messages, message)
 	}
 
+	if err := producer.SendMessages(messages); err != nil {
+		log.Printf("Failed to send %d transaction messages to RabbitMQ: %v", len(messages), err)
+		discard()
+		return nil, err
+	}
+	return submissions, nil
+}
+
+// prepareEvent 記錄待處理的提交並返回帶有提交 ID 的事件消息
+func prepareEvent(ctx context.Context, c cache.Cache, event TransactionEvent) (*Submission, []byte, error) {
+	submission := newSubmission(event)
+	if err := saveSubmission(ctx, c, submission); err != nil {
+		return nil, nil, err
+	}
+	event.SubmissionID = submission.ID
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		_ = c.Delete(ctx, submissionKey(submission.ID))
+		return nil, nil, err
+	}
+	return submission, message, nil
+}
+
+// GetTransaction 查詢屬於該用戶的單筆交易，時間以用戶時區表示
+func (s *transactionService) GetTransaction(userID, txID string) (*entity.Transaction, error) {
+	tx, err := s.ownedTransaction(userID, txID)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := s.calendar(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &cal.localize([]entity.Transaction{*tx})[0], nil
+}
+
+// UpdateTransaction 以請求內容取代交易的可編輯欄位，ID、擁有者、對帳狀態與分期資訊維持原值。
+// 修改經由 RabbitMQ 非同步寫入，消費者處理時才套用到當時的交易
+func (s *transactionService) UpdateTransaction(userID, txID string, tx entity.Transaction) (*Submission, error) {
+	return s.sendPatch(userID, txID, replacement(tx))
+}
+
+// PatchTransaction 只修改請求中提供的欄位
+func (s *transactionService) PatchTransaction(userID, txID string, patch TransactionPatch) (*Submission, error) {
+	return s.sendPatch(userID, txID, patch)
+}
+
+// sendPatch 檢查修改的欄位並送出只包含修改內容的事件。擁有者與修改後的交易由消費者檢查，
+// 因此尚在隊列中的新增交易也可以修改，並依送出順序套用
+func (s *transactionService) sendPatch(userID, txID string, patch TransactionPatch) (*Submission, error) {
+	if err := patch.validate(userID); err != nil {
+		return nil, err
+	}
+	return s.sendEvent(TransactionEvent{Event: EventTransactionUpdated, UserID: userID, Transaction: entity.Transaction{ID: txID}, Patch: &patch})
+}
+
+// replacement 返回以交易內容取代所有可編輯欄位的修改
+func replacement(tx entity.Transaction) TransactionPatch {
+	return TransactionPatch{
+		Date:        &tx.Date,
+		Amount:      &tx.Amount,
+		Category:    &tx.Category,
+		Description: &tx.Description,
+		Notes:       &tx.Notes,
+		Source:      &tx.Source,
+		Type:        &tx.Type,
+		Payee:       &tx.Payee,
+		AccountID:   &tx.AccountID,
+		Tags:        &tx.Tags,
+	}
+}
+
+// validate 以共用規則檢查修改中提供的欄位，不需查詢資料庫
+func (p TransactionPatch) validate(userID string) error {
+	var v validation.Validator
+	v.Required("UserID", "user ID", userID)
+	if p.Amount != nil {
+		v.Positive("Amount", "amount", *p.Amount)
+	}
+	if p.Date != nil {
+		v.RequiredTime("Date", "date", *p.Date)
+	}
+	if p.Type != nil {
+		v.OneOf("Type", "type", *p.Type, entity.TypeIncome, entity.TypeExpense)
+	}
+	return invalidTransaction(&v)
+}
+
+// apply 返回套用修改後的交易，未提供的欄位維持原值
+func (p TransactionPatch) apply(tx entity.Transaction) entity.Transaction {
+	if p.Date != nil {
+		tx.Date = *p.Date
+	}
+	if p.Amount != nil {
+		tx.Amount = *p.Amount
+	}
+	if p.Category != nil {
+		tx.Category = *p.Category
+	}
+	if p.Description != nil {
+		tx.Description = *p.Description
+	}
+	if p.Notes != nil {
+		tx.Notes = *p.Notes
+	}
+	if p.Source != nil {
+		tx.Source = *p.Source
+	}
+	if p.Type != nil {
+		tx.Type = *p.Type
+	}
+	if p.Payee != nil {
+		tx.Payee = *p.Payee
+	}
+	if p.AccountID != nil {
+		tx.AccountID = *p.AccountID
+	}
+	if p.Tags != nil {
+		tx.Tags = *p.Tags
+	}
+	return tx
+}
+
+// validateTransaction 以共用規則檢查交易
+func validateTransaction(tx entity.Transaction) error {
+	var v validation.Validator
+	validation.Transaction(&v, tx)
+	return invalidTransaction(&v)
+}
+
+// invalidTransaction 將欄位錯誤包裝為 ErrInvalidTransaction，沒有錯誤時返回 nil
+func invalidTransaction(v *validation.Validator) error {
+	if err := v.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTransaction, err)
+	}
 	return nil
 }
 
-// 查詢交易紀錄
-func (s *transactionService) GetTransactions(userID, category, startDate, endDate string, page, pageSize int) ([]entity.Transaction, error) {
-	// 分頁查詢交易記錄，支持篩選條件
-	return s.repo.GetFilteredTransactions(userID, category, startDate, endDate, page, pageSize)
+// DeleteTransaction 送出刪除事件，交易是否屬於該用戶由消費者檢查
+func (s *transactionService) DeleteTransaction(userID, txID string) (*Submission, error) {
+	var v validation.Validator
+	v.Required("UserID", "user ID", userID)
+	if err := invalidTransaction(&v); err != nil {
+		return nil, err
+	}
+	return s.sendEvent(TransactionEvent{Event: EventTransactionDeleted, UserID: userID, Transaction: entity.Transaction{ID: txID}})
+}
+
+// ownedTransaction 查詢屬於該用戶的交易，其他用戶的交易視為不存在
+func (s *transactionService) ownedTransaction(userID, txID string) (*entity.Transaction, error) {
+	tx, err := s.repo.GetTransactionByID(txID)
+	if err != nil {
+		return nil, err
+	}
+	if tx.UserID != userID {
+		return nil, db.ErrTransactionNotFound
+	}
+	return tx, nil
+}
+
+// 查詢交易紀錄，依排序欄位與 ID 以游標分頁
+func (s *transactionService) GetTransactions(req TransactionListRequest) (*TransactionPage, error) {
+	// 日期範圍依用戶時區解析，支援 this_month 等快捷範圍
+	cal, err := s.calendar(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := cal.dayRange(cal.resolveRange(req.StartDate, req.EndDate, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	query, err := req.listQuery(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// 多取一筆判斷是否還有下一頁
+	pageSize := query.Limit
+	query.Limit++
+	transactions, err := s.repo.GetFilteredTransactions(query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TransactionPage{Transactions: transactions}
+	if len(transactions) > pageSize {
+		page.Transactions = transactions[:pageSize]
+		order := OrderAsc
+		if query.Descending {
+			order = OrderDesc
+		}
+		page.NextCursor = encodeCursor(page.Transactions[pageSize-1], query.SortField, order)
+	}
+	if page.Transactions == nil {
+		page.Transactions = []entity.Transaction{}
+	}
+	cal.localize(page.Transactions)
+
+	if req.IncludeTotal {
+		total, err := s.repo.CountFilteredTransactions(query)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+	return page, nil
 }
 
 // 匯入帳單交易
@@ -65,29 +354,76 @@ func (s *transactionService) ImportTransactions(data io.Reader) error {
 
 // 生成財務報表
 func (s *transactionService) GenerateReport(ctx context.Context, userID, reportType, startDate, endDate string) (interface{}, error) {
-	cacheKey := "report:" + userID + ":" + reportType + ":" + startDate + ":" + endDate
+	// 快捷範圍先依用戶時區解析為日期，避免跨月後仍命中舊的快取
+	cal, err := s.calendar(userID)
+	if err != nil {
+		return nil, err
+	}
+	startDate, endDate = cal.resolveRange(startDate, endDate, time.Now())
+	// 快取鍵包含用戶的報表版本，交易異動後版本遞增，舊的快取不再命中
+	cacheKey := "report:" + userID + ":" + reportVersion(ctx, s.cache, userID) + ":" + reportType + ":" + startDate + ":" + endDate + ":" + cal.cacheKey()
 
-	// 從緩存中獲取報表
+	// 從緩存中獲取報表，緩存內容為序列化後的 JSON
 	report, err := s.cache.Get(ctx, cacheKey)
 	if err == nil && report != "" {
 		log.Println("Cache hit: returning cached report")
-		return report, nil
+		return json.RawMessage(report), nil
 	}
 
-	// 根據日期範圍查詢並生成報表
-	transactions, err := s.repo.GetTransactions(userID, startDate, endDate)
+	var generatedReport interface{}
+	switch strings.ToLower(reportType) {
+	case ReportTypeMonthly:
+		generatedReport, err = s.generatePeriodReport(cal, userID, ReportTypeMonthly, startDate, endDate, db.PeriodMonth)
+	case ReportTypeAnnual, "yearly":
+		generatedReport, err = s.generatePeriodReport(cal, userID, ReportTypeAnnual, startDate, endDate, db.PeriodYear)
+	case ReportTypeForecast:
+		generatedReport, err = s.generateForecast(cal, userID, startDate, endDate)
+	case ReportTypeNetWorth:
+		generatedReport, err = s.generateNetWorth(userID, startDate, endDate)
+	case ReportTypeTax:
+		generatedReport, err = s.generateTaxSummary(cal, userID, startDate)
+	case ReportTypeAnomalies:
+		generatedReport, err = s.generateAnomalies(cal, userID, startDate, endDate)
+	default:
+		generatedReport, err = s.generateEntriesReport(cal, userID, startDate, endDate)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	generatedReport := map[string]interface{}{
-		"user":    userID,
-		"period":  startDate + " - " + endDate,
-		"entries": transactions,
-	}
-
 	// 將生成的報表存入緩存
-	_ = s.cache.Set(ctx, cacheKey, generatedReport, 24*time.Hour)
+	if data, err := json.Marshal(generatedReport); err == nil {
+		_ = s.cache.Set(ctx, cacheKey, data, 24*time.Hour)
+	}
 
 	return generatedReport, nil
 }
+
+// EntriesReport 表示未指定報表類型時返回的期間交易明細
+type EntriesReport struct {
+	User    string               `json:"user"`
+	Period  string               `json:"period"` // 查詢的起訖日期，格式為 "YYYY-MM-DD - YYYY-MM-DD"
+	Entries []entity.Transaction `json:"entries"`
+}
+
+// generateEntriesReport 返回指定期間內的所有交易明細
+func (s *transactionService) generateEntriesReport(cal userCalendar, userID, startDate, endDate string) (*EntriesReport, error) {
+	// 根據用戶時區的日期範圍查詢並生成報表
+	from, to, err := cal.dayRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.repo.GetTransactions(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if transactions == nil {
+		transactions = []entity.Transaction{}
+	}
+
+	return &EntriesReport{
+		User:    userID,
+		Period:  startDate + " - " + endDate,
+		Entries: cal.localize(transactions),
+	}, nil
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fintrack/internal/entity"
+	"fintrack/internal/validation"
+	"fmt"
+	"io"
+	"log"
+)
+
+// ErrInvalidBatch 表示批次請求的格式不正確或筆數超過上限
+var ErrInvalidBatch = errors.New("invalid batch")
+
+// 批次中每筆交易的處理結果
+const (
+	BatchItemAccepted = "ACCEPTED" // 已送入隊列
+	BatchItemRejected = "REJECTED" // 內容不正確，未送出
+	BatchItemFailed   = "FAILED"   // 送出失敗，可重試
+)
+
+const (
+	maxBatchSize      = 5000    // 單次請求的最多筆數
+	batchPublishSize  = 500     // 每次送往 RabbitMQ 的筆數
+	maxBatchLineBytes = 1 << 20 // NDJSON 單行的最大長度
+)
+
+// BatchResult 表示批次新增的彙總與每筆結果，結果順序與請求相同
+type BatchResult struct {
+	Accepted int               `json:"accepted"`
+	Rejected int               `json:"rejected"`
+	Failed   int               `json:"failed"`
+	Items    []BatchItemResult `json:"items"`
+}
+
+// BatchItemResult 表示批次中一筆交易的結果
+type BatchItemResult struct {
+	Index         int                     `json:"index"` // 在請求中的位置，從 0 開始
+	Status        string                  `json:"status"`
+	ExternalID    string                  `json:"external_id,omitempty"` // 客戶端提供的 ID，方便對應請求
+	TransactionID string                  `json:"transaction_id,omitempty"`
+	SubmissionID  string                  `json:"submission_id,omitempty"`
+	Error         string                  `json:"error,omitempty"`
+	Code          string                  `json:"code,omitempty"` // 與 HTTP API 相同的錯誤碼
+	Details       []validation.FieldError `json:"details,omitempty"`
+}
+
+// fail 記錄該筆的錯誤，錯誤碼與欄位錯誤的格式與 HTTP API 的錯誤回應相同
+func (item *BatchItemResult) fail(status, code string, err error) {
+	resp := validation.NewResponse(code, err)
+	item.Status, item.Error, item.Code, item.Details = status, resp.Error, resp.Code, resp.Details
+}
+
+// batchEntry 表示通過驗證、等待送出的交易
+type batchEntry struct {
+	index int
+	tx    entity.Transaction
+}
+
+// AddTransactionBatch 批次新增交易。請求為 JSON 陣列，ndjson 為 true 時為每行一筆的 NDJSON。
+// 每筆分別驗證，通過的交易每 batchPublishSize 筆以一次 RabbitMQ 事務送出，單筆錯誤不影響其他交易
+func (s *transactionService) AddTransactionBatch(ctx context.Context, r io.Reader, ndjson bool) (*BatchResult, error) {
+	items, err := readBatch(r, ndjson)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BatchResult{Items: make([]BatchItemResult, len(items))}
+	var ready []batchEntry
+	for i, raw := range items {
+		item := &result.Items[i]
+		item.Index = i
+
+		var tx entity.Transaction
+		if err := json.Unmarshal(raw, &tx); err != nil {
+			item.fail(BatchItemRejected, validation.CodeInvalidRequest, fmt.Errorf("%w: %w", ErrInvalidTransaction, validation.DecodeError(err)))
+			continue
+		}
+		if tx.ExternalID == "" {
+			tx.ExternalID = tx.ID
+		}
+		item.ExternalID = tx.ExternalID
+		if err := validateTransaction(tx); err != nil {
+			item.fail(BatchItemRejected, validation.CodeValidationFailed, err)
+			continue
+		}
+		tx.ID = newTransactionID()
+		ready = append(ready, batchEntry{index: i, tx: tx})
+	}
+
+	for start := 0; start < len(ready); start += batchPublishSize {
+		s.publishBatch(ctx, ready[start:min(start+batchPublishSize, len(ready))], result.Items)
+	}
+
+	for _, item := range result.Items {
+		switch item.Status {
+		case BatchItemAccepted:
+			result.Accepted++
+		case BatchItemRejected:
+			result.Rejected++
+		default:
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+// publishBatch 記錄每筆交易的提交並以一次事務送出，送出失敗時整批標記為 FAILED 並移除提交紀錄
+func (s *transactionService) publishBatch(ctx context.Context, entries []batchEntry, items []BatchItemResult) {
+	var sent []batchEntry
+	var submissions []*Submission
+	messages := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		submission, message, err := prepareEvent(ctx, s.cache, TransactionEvent{Event: EventTransactionCreated, UserID: entry.tx.UserID, Transaction: entry.tx})
+		if err != nil {
+			log.Printf("Failed to prepare transaction %s: %v", entry.tx.ID, err)
+			items[entry.index].fail(BatchItemFailed, validation.CodeInternal, errors.New("failed to save submission"))
+			continue
+		}
+		sent = append(sent, entry)
+		submissions = append(submissions, submission)
+		messages = append(messages, message)
+	}
+	if len(messages) == 0 {
+		return
+	}
+
+	if err := s.producer.SendMessages(messages); err != nil {
+		log.Printf("Failed to send %d transaction messages to RabbitMQ: %v", len(messages), err)
+		for i, entry := range sent {
+			_ = s.cache.Delete(ctx, submissionKey(submissions[i].ID))
+			items[entry.index].fail(BatchItemFailed, validation.CodeInternal, errors.New("failed to publish transaction"))
+		}
+		return
+	}
+	for i, entry := range sent {
+		item := &items[entry.index]
+		item.Status = BatchItemAccepted
+		item.TransactionID = entry.tx.ID
+		item.SubmissionID = submissions[i].ID
+	}
+}
+
+// readBatch 讀取 JSON 陣列或 NDJSON 中每筆交易的原始內容。
+// JSON 陣列的語法錯誤使整個請求無效；NDJSON 以行為單位，無法解析的行在驗證時個別拒絕
+func readBatch(r io.Reader, ndjson bool) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	add := func(raw json.RawMessage) error {
+		if len(items) == maxBatchSize {
+			return fmt.Errorf("%w: a batch can contain at most %d transactions", ErrInvalidBatch, maxBatchSize)
+		}
+		items = append(items, raw)
+		return nil
+	}
+
+	if ndjson {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxBatchLineBytes)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			if err := add(append(json.RawMessage(nil), line...)); err != nil {
+				return nil, err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBatch, err)
+		}
+	} else {
+		dec := json.NewDecoder(r)
+		token, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBatch, err)
+		}
+		if token != json.Delim('[') {
+			return nil, fmt.Errorf("%w: body must be a JSON array of transactions", ErrInvalidBatch)
+		}
+		for dec.More() {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidBatch, err)
+			}
+			if err := add(raw); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBatch, err)
+		}
+	}
+
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: batch is empty", ErrInvalidBatch)
+	}
+	return items, nil
+}
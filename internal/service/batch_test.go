@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fintrack/internal/entity"
+	"fintrack/internal/validation"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddTransactionBatch(t *testing.T) {
+	cache := &cacheStub{values: make(map[string]string)}
+	queue := &queueStub{}
+	s := &transactionService{cache: cache, producer: queue}
+
+	body := `[
+		{"ID": "local-1", "UserID": "user123", "Date": "2024-03-10T12:00:00Z", "Amount": 100},
+		{"ID": "local-2", "UserID": "user123", "Date": "2024-03-10T12:00:00Z", "Amount": 0},
+		{"ID": "local-3", "Date": "2024-03-10T12:00:00Z", "Amount": 50},
+		{"ID": "local-4", "UserID": "user123", "Date": "2024-03-10T12:00:00Z", "Amount": "ten"}
+	]`
+	result, err := s.AddTransactionBatch(context.Background(), strings.NewReader(body), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Accepted)
+	assert.Equal(t, 3, result.Rejected)
+	assert.Len(t, queue.messages, 1)
+
+	first := result.Items[0]
+	assert.Equal(t, BatchItemAccepted, first.Status)
+	assert.Equal(t, "local-1", first.ExternalID)
+	assert.Len(t, first.TransactionID, 26)
+	submission, err := s.GetSubmission(context.Background(), "user123", first.SubmissionID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, first.TransactionID, submission.TransactionID)
+
+	assert.Equal(t, "invalid transaction: amount must be greater than zero", result.Items[1].Error)
+	assert.Equal(t, "invalid transaction: user ID is required", result.Items[2].Error)
+	assert.Equal(t, validation.CodeValidationFailed, result.Items[2].Code)
+	assert.Equal(t, "UserID", result.Items[2].Details[0].Field)
+	assert.Equal(t, 3, result.Items[3].Index)
+	assert.Equal(t, BatchItemRejected, result.Items[3].Status)
+	assert.Equal(t, validation.CodeInvalidType, result.Items[3].Details[0].Code)
+	assert.Equal(t, "Amount", result.Items[3].Details[0].Field)
+}
+
+func TestAddTransactionRequiresUser(t *testing.T) {
+	queue := &queueStub{}
+	s := &transactionService{cache: &cacheStub{values: make(map[string]string)}, producer: queue}
+
+	// 單筆新增與批次新增使用相同的規則
+	_, err := s.AddTransaction(entity.Transaction{Date: time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC), Amount: 100, Type: entity.TypeExpense})
+	assert.ErrorIs(t, err, ErrInvalidTransaction)
+	assert.Equal(t, "UserID", validation.Details(err)[0].Field)
+	assert.Empty(t, queue.messages)
+}
+
+func TestAddTransactionBatchNDJSON(t *testing.T) {
+	cache := &cacheStub{values: make(map[string]string)}
+	queue := &queueStub{}
+	s := &transactionService{cache: cache, producer: queue}
+
+	// 1201 筆有效交易分三次送出，無法解析的行個別拒絕
+	var body strings.Builder
+	for i := 0; i < 1201; i++ {
+		fmt.Fprintf(&body, `{"ID":"local-%d","UserID":"user123","Date":"2024-03-10T12:00:00Z","Amount":10}`+"\n", i)
+	}
+	body.WriteString("\n{not json\n")
+
+	result, err := s.AddTransactionBatch(context.Background(), strings.NewReader(body.String()), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1201, result.Accepted)
+	assert.Equal(t, 1, result.Rejected)
+	assert.Equal(t, 3, queue.batches)
+	assert.Equal(t, 1201, result.Items[1201].Index)
+	assert.Equal(t, BatchItemRejected, result.Items[1201].Status)
+}
+
+func TestAddTransactionBatchPublishFailure(t *testing.T) {
+	cache := &cacheStub{values: make(map[string]string)}
+	queue := &queueStub{err: errors.New("connection closed")}
+	s := &transactionService{cache: cache, producer: queue}
+
+	body := `[{"UserID":"user123","Date":"2024-03-10T12:00:00Z","Amount":10}]`
+	result, err := s.AddTransactionBatch(context.Background(), strings.NewReader(body), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, BatchItemFailed, result.Items[0].Status)
+	assert.Empty(t, result.Items[0].SubmissionID)
+	assert.Empty(t, cache.values)
+}
+
+func TestReadBatch(t *testing.T) {
+	_, err := readBatch(strings.NewReader(`{"UserID":"user123"}`), false)
+	assert.ErrorIs(t, err, ErrInvalidBatch)
+	_, err = readBatch(strings.NewReader(`[{"UserID":"user123"}`), false)
+	assert.ErrorIs(t, err, ErrInvalidBatch)
+	_, err = readBatch(strings.NewReader(`[]`), false)
+	assert.ErrorIs(t, err, ErrInvalidBatch)
+
+	tooMany := "[" + strings.TrimSuffix(strings.Repeat("{},", maxBatchSize+1), ",") + "]"
+	_, err = readBatch(strings.NewReader(tooMany), false)
+	assert.ErrorIs(t, err, ErrInvalidBatch)
+
+	items, err := readBatch(strings.NewReader("[{}, {}]"), false)
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+}
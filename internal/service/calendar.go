@@ -0,0 +1,258 @@
+package service
+
+import (
+	"fintrack/internal/entity"
+	"sort"
+	"time"
+)
+
+// 行事曆事件來源
+const (
+	EventSourceSchedule     = "SCHEDULE"     // 受管理的週期性排程
+	EventSourceSubscription = "SUBSCRIPTION" // 偵測到但尚未受管理的訂閱
+	EventSourceCardDue      = "CARD_DUE"     // 信用卡繳款截止日
+	EventSourceRecorded     = "RECORDED"     // 今天以前已記錄的交易
+)
+
+// UnassignedAccount 用於彙總未指定帳戶的交易與事件
+const UnassignedAccount = "UNASSIGNED"
+
+// CalendarEvent 表示某日預期的收入或支出
+type CalendarEvent struct {
+	Source     string  `json:"source"`
+	Payee      string  `json:"payee"`
+	Category   string  `json:"category,omitempty"`
+	Type       string  `json:"type"`
+	Amount     float64 `json:"amount"`
+	AccountID  string  `json:"account_id"`
+	TransferTo string  `json:"transfer_to,omitempty"` // 信用卡繳款時被清償的卡片帳戶
+}
+
+// CalendarDay 表示某日的預期收支與預估帳戶餘額
+type CalendarDay struct {
+	Date             string             `json:"date"`
+	Inflow           float64            `json:"inflow"`
+	Outflow          float64            `json:"outflow"`
+	Events           []CalendarEvent    `json:"events"`
+	Balances         map[string]float64 `json:"balances"`
+	TotalBalance     float64            `json:"total_balance"`
+	NegativeAccounts []string           `json:"negative_accounts,omitempty"`
+}
+
+// Calendar 表示一段期間內的帳單行事曆
+type Calendar struct {
+	UserID          string             `json:"user_id"`
+	From            string             `json:"from"`
+	To              string             `json:"to"`
+	OpeningBalances map[string]float64 `json:"opening_balances"`
+	Days            []CalendarDay      `json:"days"`
+}
+
+// GetCalendar 結合週期性排程、信用卡繳款日與偵測到的訂閱，預估每日收支與帳戶餘額。
+// 今天以前的日期使用已記錄的交易，從今天開始才使用預估
+func (s *recurringService) GetCalendar(userID string, from, to time.Time) (*Calendar, error) {
+	now := time.Now()
+	series, history, schedules, err := s.detect(userID, now)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.repo.GetAccounts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 查詢期間若在未來，從今天開始推算，讓期初餘額包含期間之前的預期收支
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, from.Location())
+	start := from
+	if start.After(today) {
+		start = today
+	}
+
+	recorded, err := s.repo.GetAccountBalances(userID, start.Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]float64)
+	liabilities := make(map[string]bool)
+	for _, account := range accounts {
+		balances[account.ID] = account.OpeningBalance + recorded[account.ID]
+		liabilities[account.ID] = account.IsLiability()
+	}
+	if unassigned := recorded[""]; unassigned != 0 {
+		balances[UnassignedAccount] = unassigned
+	}
+
+	// 預估從今天開始，今天以前的收支已有實際紀錄
+	projectFrom := start
+	if projectFrom.Before(today) {
+		projectFrom = today
+	}
+
+	events := make(map[string][]CalendarEvent)
+	addEvent := func(date time.Time, event CalendarEvent) {
+		if event.AccountID == "" {
+			event.AccountID = UnassignedAccount
+		}
+		key := date.Format(dateLayout)
+		events[key] = append(events[key], event)
+	}
+
+	if start.Before(today) {
+		past, err := s.repo.GetTransactions(userID, start.Format(dateLayout), endOfDay(today.AddDate(0, 0, -1)).UTC().Format(dbTimeLayout))
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range past {
+			event := CalendarEvent{
+				Source:    EventSourceRecorded,
+				Payee:     payeeOf(tx),
+				Category:  tx.Category,
+				Type:      entity.TypeExpense,
+				Amount:    tx.Amount,
+				AccountID: tx.AccountID,
+			}
+			if tx.IsIncome() {
+				event.Type = entity.TypeIncome
+			}
+			addEvent(tx.Date.In(from.Location()), event)
+		}
+	}
+
+	for _, schedule := range schedules {
+		for _, date := range schedule.Occurrences(projectFrom, to) {
+			addEvent(date, CalendarEvent{
+				Source:    EventSourceSchedule,
+				Payee:     schedule.Payee,
+				Category:  schedule.Category,
+				Type:      schedule.Type,
+				Amount:    schedule.Amount,
+				AccountID: schedule.AccountID,
+			})
+		}
+	}
+
+	for _, ser := range series {
+		if ser.Managed {
+			continue
+		}
+		expected := entity.RecurringSchedule{Cadence: ser.Cadence, NextDate: ser.NextExpectedDate, Active: true}
+		for _, date := range expected.Occurrences(projectFrom, to) {
+			addEvent(date, CalendarEvent{
+				Source:    EventSourceSubscription,
+				Payee:     ser.Payee,
+				Category:  ser.Category,
+				Type:      ser.Type,
+				Amount:    ser.LastAmount,
+				AccountID: ser.AccountID,
+			})
+		}
+	}
+
+	for _, account := range accounts {
+		if !account.IsLiability() || account.ClosingDay == 0 || account.DueDay == 0 {
+			continue
+		}
+		for _, due := range cardDues(account, history, projectFrom, to) {
+			addEvent(due.date, due.event)
+		}
+	}
+
+	calendar := &Calendar{
+		UserID:          userID,
+		From:            from.Format(dateLayout),
+		To:              to.Format(dateLayout),
+		OpeningBalances: make(map[string]float64),
+	}
+	for day := start; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if day.Equal(from) {
+			calendar.OpeningBalances = copyBalances(balances)
+		}
+
+		key := day.Format(dateLayout)
+		entry := CalendarDay{Date: key, Events: events[key]}
+		for _, event := range entry.Events {
+			if event.Type == entity.TypeIncome {
+				entry.Inflow += event.Amount
+				balances[event.AccountID] += event.Amount
+			} else {
+				entry.Outflow += event.Amount
+				balances[event.AccountID] -= event.Amount
+			}
+			if event.TransferTo != "" {
+				balances[event.TransferTo] += event.Amount
+			}
+		}
+		if day.Before(from) {
+			continue
+		}
+
+		entry.Inflow = roundAmount(entry.Inflow)
+		entry.Outflow = roundAmount(entry.Outflow)
+		entry.Balances = copyBalances(balances)
+		for accountID, balance := range entry.Balances {
+			entry.TotalBalance += balance
+			// 信用卡本身即為負債，只檢查資產帳戶是否透支
+			if balance < 0 && !liabilities[accountID] && accountID != UnassignedAccount {
+				entry.NegativeAccounts = append(entry.NegativeAccounts, accountID)
+			}
+		}
+		entry.TotalBalance = roundAmount(entry.TotalBalance)
+		sort.Strings(entry.NegativeAccounts)
+		calendar.Days = append(calendar.Days, entry)
+	}
+	return calendar, nil
+}
+
+type cardDue struct {
+	date  time.Time
+	event CalendarEvent
+}
+
+// cardDues 計算信用卡在期間內的繳款日與應繳金額（該期帳單的消費淨額）
+func cardDues(account entity.Account, history []entity.Transaction, from, to time.Time) []cardDue {
+	var dues []cardDue
+	// 繳款日可能落在結帳日的次月，因此從前一個月的結帳日開始計算
+	month := time.Date(from.Year(), from.Month()-1, 1, 0, 0, 0, 0, from.Location())
+	for ; !month.After(to); month = month.AddDate(0, 1, 0) {
+		closing := account.ClosingDate(month.Year(), month.Month(), month.Location())
+		due := account.DueDate(closing)
+		if due.Before(from) || due.After(to) {
+			continue
+		}
+
+		periodStart, periodEnd := account.StatementPeriod(closing)
+		var charges float64
+		for _, tx := range history {
+			if tx.AccountID == account.ID && tx.Date.After(endOfDay(periodStart)) && !tx.Date.After(endOfDay(periodEnd)) {
+				charges -= tx.SignedAmount()
+			}
+		}
+		if charges <= 0 {
+			continue
+		}
+
+		dues = append(dues, cardDue{date: due, event: CalendarEvent{
+			Source:     EventSourceCardDue,
+			Payee:      account.Name,
+			Type:       entity.TypeExpense,
+			Amount:     roundAmount(charges),
+			AccountID:  account.PaymentAccountID,
+			TransferTo: account.ID,
+		}})
+	}
+	return dues
+}
+
+// endOfDay 返回當日最後一刻，用於包含結帳日當天的交易
+func endOfDay(t time.Time) time.Time {
+	return t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+}
+
+func copyBalances(balances map[string]float64) map[string]float64 {
+	copied := make(map[string]float64, len(balances))
+	for accountID, balance := range balances {
+		copied[accountID] = roundAmount(balance)
+	}
+	return copied
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"fintrack/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCardDues(t *testing.T) {
+	card := entity.Account{ID: "card1", Name: "Visa", Type: entity.AccountTypeCreditCard, ClosingDay: 5, DueDay: 20, PaymentAccountID: "bank1"}
+	history := []entity.Transaction{
+		// 上期結帳日當天的消費屬於上一期
+		{ID: "1", AccountID: "card1", Date: time.Date(2024, 5, 5, 12, 0, 0, 0, time.UTC), Amount: 999},
+		{ID: "2", AccountID: "card1", Date: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), Amount: 1500},
+		{ID: "3", AccountID: "card1", Date: time.Date(2024, 6, 5, 23, 0, 0, 0, time.UTC), Amount: 500},
+		{ID: "4", AccountID: "card1", Date: time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC), Amount: 200, Type: entity.TypeIncome},
+		{ID: "5", AccountID: "other", Date: time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC), Amount: 80},
+	}
+
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	dues := cardDues(card, history, from, to)
+
+	if assert.Len(t, dues, 1) {
+		assert.Equal(t, time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC), dues[0].date)
+		assert.Equal(t, 1800.0, dues[0].event.Amount)
+		assert.Equal(t, "bank1", dues[0].event.AccountID)
+		assert.Equal(t, "card1", dues[0].event.TransferTo)
+	}
+}
+
+func TestCardDuesNextMonth(t *testing.T) {
+	// 繳款日不大於結帳日時，繳款日落在次月
+	card := entity.Account{ID: "card1", Type: entity.AccountTypeCreditCard, ClosingDay: 25, DueDay: 10}
+	history := []entity.Transaction{
+		{ID: "1", AccountID: "card1", Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Amount: 300},
+	}
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	dues := cardDues(card, history, from, to)
+
+	if assert.Len(t, dues, 1) {
+		assert.Equal(t, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), dues[0].date)
+		assert.Equal(t, 300.0, dues[0].event.Amount)
+	}
+}
+
+func TestGetCalendarProjectsBalances(t *testing.T) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	repo := &stubRepo{
+		accounts: []entity.Account{
+			{ID: "bank1", UserID: "user123", Type: entity.AccountTypeBank, OpeningBalance: 200},
+			{ID: "card1", UserID: "user123", Type: entity.AccountTypeCreditCard},
+		},
+		balances: map[string]float64{"bank1": 800, "card1": -300},
+		schedules: []entity.RecurringSchedule{
+			{ID: "s1", Payee: "Landlord", Type: entity.TypeExpense, Cadence: entity.CadenceMonthly, Amount: 1500, NextDate: today.AddDate(0, 0, 2), Active: true, AccountID: "bank1"},
+			{ID: "s2", Payee: "ACME Corp", Type: entity.TypeIncome, Cadence: entity.CadenceMonthly, Amount: 2000, NextDate: today.AddDate(0, 0, 4), Active: true, AccountID: "bank1"},
+		},
+	}
+	s := &recurringService{repo: repo}
+
+	calendar, err := s.GetCalendar("user123", today, today.AddDate(0, 0, 5))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"bank1": 1000, "card1": -300}, calendar.OpeningBalances)
+	if !assert.Len(t, calendar.Days, 6) {
+		return
+	}
+
+	// 信用卡為負債，餘額為負不列入透支
+	assert.Empty(t, calendar.Days[0].NegativeAccounts)
+	assert.Equal(t, 700.0, calendar.Days[0].TotalBalance)
+
+	rent := calendar.Days[2]
+	assert.Equal(t, 1500.0, rent.Outflow)
+	assert.Equal(t, map[string]float64{"bank1": -500, "card1": -300}, rent.Balances)
+	assert.Equal(t, []string{"bank1"}, rent.NegativeAccounts)
+	assert.Equal(t, []string{"bank1"}, calendar.Days[3].NegativeAccounts)
+
+	payday := calendar.Days[4]
+	assert.Equal(t, 2000.0, payday.Inflow)
+	assert.Equal(t, 1500.0, payday.Balances["bank1"])
+	assert.Empty(t, payday.NegativeAccounts)
+
+	// 查詢期間在未來時，期初餘額包含今天至期間前的預估收支
+	calendar, err = s.GetCalendar("user123", today.AddDate(0, 0, 3), today.AddDate(0, 0, 5))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"bank1": -500, "card1": -300}, calendar.OpeningBalances)
+	assert.Len(t, calendar.Days, 3)
+}
+
+func TestGetCalendarUsesRecordedTransactionsForPastDays(t *testing.T) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	repo := &stubRepo{
+		accounts: []entity.Account{{ID: "bank1", UserID: "user123", Type: entity.AccountTypeBank}},
+		balances: map[string]float64{"bank1": 1000},
+		transactions: []entity.Transaction{
+			{ID: "1", UserID: "user123", Date: today.AddDate(0, 0, -2).Add(12 * time.Hour), Amount: 1500, Payee: "Landlord", AccountID: "bank1"},
+			{ID: "2", UserID: "user123", Date: today.AddDate(0, 0, -1).Add(9 * time.Hour), Amount: 100, Type: entity.TypeIncome, Payee: "Refund", AccountID: "bank1"},
+			// 期間之前的交易已包含在期初餘額中
+			{ID: "3", UserID: "user123", Date: today.AddDate(0, 0, -4), Amount: 50, AccountID: "bank1"},
+		},
+		// 已過去的排程日不應再預估，以免與實際交易重複計算
+		schedules: []entity.RecurringSchedule{
+			{ID: "s1", Payee: "Landlord", Type: entity.TypeExpense, Cadence: entity.CadenceWeekly, Amount: 1500, NextDate: today.AddDate(0, 0, -2), Active: true, AccountID: "bank1"},
+		},
+	}
+	s := &recurringService{repo: repo}
+
+	calendar, err := s.GetCalendar("user123", today.AddDate(0, 0, -3), today.AddDate(0, 0, 5))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"bank1": 1000}, calendar.OpeningBalances)
+	if !assert.Len(t, calendar.Days, 9) {
+		return
+	}
+
+	assert.Empty(t, calendar.Days[0].Events)
+	rent := calendar.Days[1]
+	if assert.Len(t, rent.Events, 1) {
+		assert.Equal(t, EventSourceRecorded, rent.Events[0].Source)
+		assert.Equal(t, "Landlord", rent.Events[0].Payee)
+	}
+	assert.Equal(t, -500.0, rent.Balances["bank1"])
+	assert.Equal(t, []string{"bank1"}, rent.NegativeAccounts)
+
+	refund := calendar.Days[2]
+	assert.Equal(t, 100.0, refund.Inflow)
+	assert.Equal(t, -400.0, refund.Balances["bank1"])
+
+	// 今天起依排程預估，下一次在 5 天後
+	assert.Empty(t, calendar.Days[3].Events)
+	next := calendar.Days[8]
+	if assert.Len(t, next.Events, 1) {
+		assert.Equal(t, EventSourceSchedule, next.Events[0].Source)
+	}
+	assert.Equal(t, -1900.0, next.Balances["bank1"])
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 比較報表的基準期間
+const (
+	BaselinePrevious        = "previous"         // 緊接在本期之前、長度相同的期間
+	BaselineYearAgo         = "year_ago"         // 去年同期
+	BaselineTrailingAverage = "trailing_average" // 本期之前 12 個月的月平均，換算為本期的月數
+	BaselineCustom          = "custom"           // 自訂期間
+)
+
+const (
+	trailingAverageMonths         = 12
+	defaultSignificantIncreasePct = 20 // 支出分類增加超過此百分比即標記為顯著
+)
+
+// ComparisonRequest 表示比較報表的參數，期間皆以整月計算
+type ComparisonRequest struct {
+	StartDate    string
+	EndDate      string
+	Baselines    []string // 未指定時比較去年同期與過去 12 個月平均
+	CompareStart string   // 自訂基準期間
+	CompareEnd   string
+	Threshold    float64 // 顯著增加的百分比門檻，0 時使用預設值
+}
+
+// ComparisonReport 表示本期與一或多個基準期間的比較
+type ComparisonReport struct {
+	User        string        `json:"user"`
+	Threshold   float64       `json:"threshold"`
+	Current     PeriodSummary `json:"current"`
+	Comparisons []Comparison  `json:"comparisons"`
+}
+
+// Comparison 表示本期與單一基準期間的差異
+type Comparison struct {
+	Baseline             string           `json:"baseline"`
+	From                 string           `json:"from"`
+	To                   string           `json:"to"`
+	Summary              PeriodSummary    `json:"summary"`
+	Delta                PeriodDelta      `json:"delta"`
+	SignificantIncreases []CategoryChange `json:"significant_increases"`
+}
+
+// CompareReports 以月報相同的資料庫彙總，比較本期與各基準期間的分類收支
+func (s *transactionService) CompareReports(ctx context.Context, userID string, req ComparisonRequest) (*ComparisonReport, error) {
+	cal, err := s.calendar(userID)
+	if err != nil {
+		return nil, err
+	}
+	req.StartDate, req.EndDate = cal.resolveRange(req.StartDate, req.EndDate, time.Now())
+	if req.StartDate == "" || req.EndDate == "" {
+		return nil, fmt.Errorf("%w: start_date and end_date are required", ErrInvalidReportRequest)
+	}
+	from, to, err := cal.periodRange(req.StartDate, req.EndDate, db.PeriodMonth, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	threshold := req.Threshold
+	if threshold <= 0 {
+		threshold = defaultSignificantIncreasePct
+	}
+	baselines := req.Baselines
+	if len(baselines) == 0 {
+		baselines = []string{BaselineYearAgo, BaselineTrailingAverage}
+	}
+
+	months := monthsBetween(from, to)
+	current, err := s.summarizeMonths(cal, userID, from, to, 1)
+	if err != nil {
+		return nil, err
+	}
+	report := &ComparisonReport{User: userID, Threshold: threshold, Current: *current}
+
+	for _, baseline := range baselines {
+		baseline = strings.ToLower(strings.TrimSpace(baseline))
+		var baseFrom, baseTo time.Time
+		scale := 1.0
+		switch baseline {
+		case BaselinePrevious:
+			baseFrom, baseTo = from.AddDate(0, -months, 0), from
+		case BaselineYearAgo:
+			baseFrom, baseTo = from.AddDate(-1, 0, 0), to.AddDate(-1, 0, 0)
+		case BaselineTrailingAverage:
+			baseFrom, baseTo = from.AddDate(0, -trailingAverageMonths, 0), from
+			scale = float64(months) / trailingAverageMonths
+		case BaselineCustom:
+			if req.CompareStart == "" || req.CompareEnd == "" {
+				return nil, fmt.Errorf("%w: compare_start and compare_end are required for a custom baseline", ErrInvalidReportRequest)
+			}
+			if baseFrom, baseTo, err = cal.periodRange(req.CompareStart, req.CompareEnd, db.PeriodMonth, time.Now()); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%w: baseline must be previous, year_ago, trailing_average or custom", ErrInvalidReportRequest)
+		}
+
+		summary, err := s.summarizeMonths(cal, userID, baseFrom, baseTo, scale)
+		if err != nil {
+			return nil, err
+		}
+		delta := comparePeriods(summary, current)
+		comparison := Comparison{
+			Baseline:             baseline,
+			From:                 baseFrom.Format(dateLayout),
+			To:                   baseTo.AddDate(0, 0, -1).Format(dateLayout),
+			Summary:              *summary,
+			SignificantIncreases: []CategoryChange{},
+		}
+		for i, change := range delta.Categories {
+			if isSignificantIncrease(change, threshold) {
+				delta.Categories[i].Significant = true
+				comparison.SignificantIncreases = append(comparison.SignificantIncreases, delta.Categories[i])
+			}
+		}
+		sort.SliceStable(comparison.SignificantIncreases, func(i, j int) bool {
+			return comparison.SignificantIncreases[i].Change > comparison.SignificantIncreases[j].Change
+		})
+		comparison.Delta = *delta
+		report.Comparisons = append(report.Comparisons, comparison)
+	}
+	return report, nil
+}
+
+// summarizeMonths 合併 [from, to) 內各月的彙總，scale 用於將多月合計換算為平均
+func (s *transactionService) summarizeMonths(cal userCalendar, userID string, from, to time.Time, scale float64) (*PeriodSummary, error) {
+	summaries, err := aggregatePeriods(s.repo, userID, cal, from, to, cal.bucket(db.PeriodMonth))
+	if err != nil {
+		return nil, err
+	}
+
+	label := cal.periodKey(from, db.PeriodMonth)
+	if last := to.AddDate(0, -1, 0); last.After(from) {
+		label += "/" + cal.periodKey(last, db.PeriodMonth)
+	}
+	combined := newPeriodSummary(label)
+
+	type key struct{ category, txType string }
+	categories := make(map[key]*CategorySummary)
+	var count int
+	for _, summary := range summaries {
+		combined.Income += summary.Income
+		combined.Expense += summary.Expense
+		count += summary.TransactionCount
+		for _, c := range summary.Categories {
+			k := key{c.Category, c.Type}
+			if categories[k] == nil {
+				categories[k] = &CategorySummary{Category: c.Category, Type: c.Type}
+			}
+			categories[k].Amount += c.Amount
+			categories[k].Count += c.Count
+		}
+	}
+
+	for _, c := range categories {
+		c.Amount = roundAmount(c.Amount * scale)
+		c.Count = int(math.Round(float64(c.Count) * scale))
+		combined.Categories = append(combined.Categories, *c)
+	}
+	sort.Slice(combined.Categories, func(i, j int) bool {
+		if combined.Categories[i].Amount != combined.Categories[j].Amount {
+			return combined.Categories[i].Amount > combined.Categories[j].Amount
+		}
+		return combined.Categories[i].Category < combined.Categories[j].Category
+	})
+
+	combined.Income = roundAmount(combined.Income * scale)
+	combined.Expense = roundAmount(combined.Expense * scale)
+	combined.NetSavings = roundAmount(combined.Income - combined.Expense)
+	combined.TransactionCount = int(math.Round(float64(count) * scale))
+	if combined.Income > 0 {
+		combined.SavingsRate = roundAmount(combined.NetSavings / combined.Income * 100)
+	}
+	return combined, nil
+}
+
+// isSignificantIncrease 判斷支出分類的增加是否超過門檻，基準期間沒有支出的新分類也視為顯著
+func isSignificantIncrease(change CategoryChange, threshold float64) bool {
+	if change.Type != entity.TypeExpense || change.Change <= 0 {
+		return false
+	}
+	return change.Percent == nil || *change.Percent >= threshold
+}
+
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()-from.Month())
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareReports(t *testing.T) {
+	repo := &reportRepo{totals: []db.CategoryTotal{
+		{Period: "2023-03", Category: "FOOD", Direction: entity.TypeExpense, Total: 8000, Count: 20},
+		{Period: "2024-03", Category: "FOOD", Direction: entity.TypeExpense, Total: 10000, Count: 25},
+		{Period: "2024-03", Category: "TRAVEL", Direction: entity.TypeExpense, Total: 15000, Count: 2},
+	}}
+	for month := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC); month.Before(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)); month = month.AddDate(0, 1, 0) {
+		repo.totals = append(repo.totals, db.CategoryTotal{Period: month.Format("2006-01"), Category: "FOOD", Direction: entity.TypeExpense, Total: 9000, Count: 22})
+	}
+	s := &transactionService{repo: repo}
+
+	report, err := s.CompareReports(context.Background(), "user123", ComparisonRequest{StartDate: "2024-03-01", EndDate: "2024-03-31"})
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-03", report.Current.Period)
+	assert.Equal(t, 25000.0, report.Current.Expense)
+	assert.Len(t, report.Comparisons, 2)
+
+	yearAgo := report.Comparisons[0]
+	assert.Equal(t, BaselineYearAgo, yearAgo.Baseline)
+	assert.Equal(t, "2023-03-01", yearAgo.From)
+	assert.Equal(t, "2023-03-31", yearAgo.To)
+	assert.Equal(t, []string{"TRAVEL", "FOOD"}, categoryNames(yearAgo.SignificantIncreases))
+	assert.Equal(t, 25.0, *yearAgo.SignificantIncreases[1].Percent)
+
+	// 過去 12 個月平均為 (8000 + 9000 * 11) / 12，增加幅度未達門檻
+	trailing := report.Comparisons[1]
+	assert.Equal(t, 8916.67, trailing.Summary.Expense)
+	assert.Equal(t, []string{"TRAVEL"}, categoryNames(trailing.SignificantIncreases))
+	assert.False(t, trailing.Delta.Categories[0].Significant)
+
+	_, err = s.CompareReports(context.Background(), "user123", ComparisonRequest{StartDate: "2024-03-01", EndDate: "2024-03-31", Baselines: []string{BaselineCustom}})
+	assert.ErrorIs(t, err, ErrInvalidReportRequest)
+}
+
+func categoryNames(changes []CategoryChange) []string {
+	names := make([]string, len(changes))
+	for i, change := range changes {
+		names[i] = change.Category
+	}
+	return names
+}
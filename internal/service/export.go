@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fintrack/internal/export"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var transactionHeader = []string{"Date", "Category", "Type", "Payee", "Description", "Account", "Amount"}
+
+// ExportReport 產生報表並轉為與格式無關的文件，供 CSV、XLSX 與 PDF 輸出
+func (s *transactionService) ExportReport(ctx context.Context, userID, reportType, startDate, endDate string) (*export.Document, error) {
+	cal, err := s.calendar(userID)
+	if err != nil {
+		return nil, err
+	}
+	startDate, endDate = cal.resolveRange(startDate, endDate, time.Now())
+
+	switch reportType = strings.ToLower(reportType); reportType {
+	case ReportTypeMonthly:
+		return s.exportPeriodReport(cal, userID, ReportTypeMonthly, startDate, endDate, db.PeriodMonth)
+	case ReportTypeAnnual, "yearly":
+		return s.exportPeriodReport(cal, userID, ReportTypeAnnual, startDate, endDate, db.PeriodYear)
+	case ReportTypeForecast:
+		report, err := s.generateForecast(cal, userID, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		return forecastDocument(report), nil
+	case ReportTypeNetWorth:
+		report, err := s.generateNetWorth(userID, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		return netWorthDocument(report), nil
+	case ReportTypeTax:
+		report, err := s.generateTaxSummary(cal, userID, startDate)
+		if err != nil {
+			return nil, err
+		}
+		return taxSummaryDocument(report), nil
+	case ReportTypeAnomalies:
+		report, err := s.generateAnomalies(cal, userID, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		return anomaliesDocument(report), nil
+	}
+
+	from, to, err := cal.dayRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.repo.GetTransactions(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	transactions = cal.localize(transactions)
+	return &export.Document{
+		Title:  "Transactions " + startDate + " - " + endDate,
+		Tables: []export.Table{transactionTable(transactions)},
+	}, nil
+}
+
+// exportPeriodReport 將月報或年報輸出為摘要、分類與交易明細三張表，並附上分類支出與淨儲蓄的長條圖
+func (s *transactionService) exportPeriodReport(cal userCalendar, userID, reportType, startDate, endDate string, period db.Period) (*export.Document, error) {
+	report, err := s.generatePeriodReport(cal, userID, reportType, startDate, endDate, period)
+	if err != nil {
+		return nil, err
+	}
+	// 交易日期含時間，結束日需包含當天全日
+	from, to, err := cal.dayRange(report.From, report.To)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.repo.GetTransactions(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	transactions = cal.localize(transactions)
+
+	summary := export.Table{Name: "Summary", Header: []string{"Period", "Income", "Expense", "Net Savings", "Savings Rate (%)", "Transactions"}}
+	categories := export.Table{Name: "By Category", Header: []string{"Period", "Category", "Type", "Amount", "Count"}}
+	savings := export.Chart{Title: "Net savings by period"}
+	expenses := make(map[string]float64)
+	for _, p := range report.Periods {
+		summary.Rows = append(summary.Rows, []interface{}{p.Period, p.Income, p.Expense, p.NetSavings, p.SavingsRate, p.TransactionCount})
+		savings.Bars = append(savings.Bars, export.Bar{Label: p.Period, Value: p.NetSavings})
+		for _, c := range p.Categories {
+			categories.Rows = append(categories.Rows, []interface{}{p.Period, c.Category, c.Type, c.Amount, c.Count})
+			if c.Type == entity.TypeExpense {
+				expenses[c.Category] += c.Amount
+			}
+		}
+	}
+
+	byCategory := export.Chart{Title: "Expense by category"}
+	for category, amount := range expenses {
+		byCategory.Bars = append(byCategory.Bars, export.Bar{Label: category, Value: roundAmount(amount)})
+	}
+	sort.Slice(byCategory.Bars, func(i, j int) bool { return byCategory.Bars[i].Value > byCategory.Bars[j].Value })
+
+	title := "Monthly report "
+	if period == db.PeriodYear {
+		title = "Annual report "
+	}
+	return &export.Document{
+		Title:  title + report.From + " - " + report.To,
+		Tables: []export.Table{summary, categories, transactionTable(transactions)},
+		Charts: []export.Chart{byCategory, savings},
+	}, nil
+}
+
+func forecastDocument(report *ForecastReport) *export.Document {
+	table := export.Table{Name: "Forecast", Header: []string{"Month", "Income", "Expense", "Net"}}
+	chart := export.Chart{Title: "Projected net by month"}
+	for _, m := range report.Months {
+		table.Rows = append(table.Rows, []interface{}{m.Month, m.Income, m.Expense, m.Net})
+		chart.Bars = append(chart.Bars, export.Bar{Label: m.Month, Value: m.Net})
+	}
+	return &export.Document{Title: "Cash-flow forecast", Tables: []export.Table{table}, Charts: []export.Chart{chart}}
+}
+
+func netWorthDocument(report *NetWorthReport) *export.Document {
+	table := export.Table{Name: "Net Worth", Header: []string{"Month", "As Of", "Assets", "Liabilities", "Net Worth"}}
+	items := export.Table{Name: "Items", Header: []string{"Month", "Name", "Type", "Liability", "Balance"}}
+	chart := export.Chart{Title: "Net worth by month"}
+	for _, m := range report.Months {
+		table.Rows = append(table.Rows, []interface{}{m.Month, m.AsOf, m.Assets, m.Liabilities, m.NetWorth})
+		chart.Bars = append(chart.Bars, export.Bar{Label: m.Month, Value: m.NetWorth})
+		for _, item := range m.Items {
+			liability := "N"
+			if item.Liability {
+				liability = "Y"
+			}
+			items.Rows = append(items.Rows, []interface{}{m.Month, item.Name, item.Type, liability, item.Balance})
+		}
+	}
+	return &export.Document{Title: "Net worth", Tables: []export.Table{table, items}, Charts: []export.Chart{chart}}
+}
+
+// taxSummaryDocument 輸出各類別合計與每一類別的佐證交易，股利所得另列投資帳戶的現金股利
+func taxSummaryDocument(report *TaxSummaryReport) *export.Document {
+	summary := export.Table{Name: "Tax Summary", Header: []string{"Kind", "Bucket", "Name", "Total", "Count"}}
+	supporting := export.Table{Name: "Supporting Transactions", Header: append([]string{"Bucket"}, transactionHeader...)}
+	for _, group := range []struct {
+		kind    string
+		buckets []TaxBucketSummary
+	}{{"INCOME", report.Income}, {"DEDUCTION", report.Deductions}} {
+		for _, b := range group.buckets {
+			summary.Rows = append(summary.Rows, []interface{}{group.kind, string(b.Bucket), b.Name, b.Total, b.Count})
+			for _, row := range transactionTable(b.Transactions).Rows {
+				supporting.Rows = append(supporting.Rows, append([]interface{}{string(b.Bucket)}, row...))
+			}
+			for _, trade := range b.Dividends {
+				supporting.Rows = append(supporting.Rows, []interface{}{string(b.Bucket), trade.Date.Format(dateLayout), "DIVIDEND", entity.TypeIncome, trade.Symbol, "", trade.AccountID, trade.Amount})
+			}
+		}
+	}
+	summary.Rows = append(summary.Rows,
+		[]interface{}{"INCOME", "", "Total", report.TotalIncome, ""},
+		[]interface{}{"DEDUCTION", "", "Total", report.TotalDeductions, ""},
+	)
+	return &export.Document{
+		Title:  fmt.Sprintf("Tax summary %d", report.TaxYear),
+		Tables: []export.Table{summary, supporting},
+	}
+}
+
+// anomaliesDocument 將異常報表輸出為異常交易與分類暴增兩張表，同一筆交易的每個原因各佔一列
+func anomaliesDocument(report *AnomalyReport) *export.Document {
+	transactions := export.Table{Name: "Unusual Transactions", Header: append([]string{"Kind", "Explanation", "ID"}, transactionHeader...)}
+	for _, a := range report.Transactions {
+		row := transactionTable([]entity.Transaction{a.Transaction}).Rows[0]
+		for _, reason := range a.Reasons {
+			transactions.Rows = append(transactions.Rows, append([]interface{}{reason.Kind, reason.Explanation, a.Transaction.ID}, row...))
+		}
+	}
+	categories := export.Table{Name: "Category Spikes", Header: []string{"Category", "Amount", "Expected", "Ratio", "Explanation"}}
+	for _, c := range report.Categories {
+		categories.Rows = append(categories.Rows, []interface{}{c.Category, c.Amount, c.Expected, c.Ratio, c.Explanation})
+	}
+	return &export.Document{
+		Title:  "Unusual spending " + report.From + " - " + report.To,
+		Tables: []export.Table{transactions, categories},
+	}
+}
+
+func transactionTable(transactions []entity.Transaction) export.Table {
+	sort.SliceStable(transactions, func(i, j int) bool { return transactions[i].Date.Before(transactions[j].Date) })
+	table := export.Table{Name: "Transactions", Header: transactionHeader}
+	for _, tx := range transactions {
+		txType := entity.TypeExpense
+		if tx.IsIncome() {
+			txType = entity.TypeIncome
+		}
+		table.Rows = append(table.Rows, []interface{}{tx.Date.Format(dateLayout), tx.Category, txType, tx.Payee, tx.Description, tx.AccountID, tx.Amount})
+	}
+	return table
+}
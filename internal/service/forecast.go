@@ -0,0 +1,246 @@
+package service
+
+import (
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	forecastBaselineMonths = 6  // 計算非週期性支出平均時回溯的完整月數
+	maxForecastMonths      = 24 // 單次預測的最大月數
+	defaultForecastMonths  = 6
+)
+
+// ForecastReport 表示現金流預測報表
+type ForecastReport struct {
+	User       string           `json:"user"`
+	ReportType string           `json:"report_type"`
+	Months     []ForecastMonth  `json:"months"`
+	Baseline   ForecastBaseline `json:"baseline"`
+}
+
+// ForecastMonth 表示單月的預測收支與各帳戶月底餘額
+type ForecastMonth struct {
+	Month    string            `json:"month"` // YYYY-MM
+	Income   float64           `json:"income"`
+	Expense  float64           `json:"expense"`
+	Net      float64           `json:"net"`
+	Accounts []AccountForecast `json:"accounts"`
+}
+
+// AccountForecast 表示單一帳戶在某月的預測
+type AccountForecast struct {
+	AccountID        string  `json:"account_id"`
+	Income           float64 `json:"income"`
+	Expense          float64 `json:"expense"`
+	RecurringIncome  float64 `json:"recurring_income"`
+	RecurringExpense float64 `json:"recurring_expense"`
+	EndBalance       float64 `json:"end_balance"`
+}
+
+// ForecastBaseline 表示預測所依據的歷史基準
+type ForecastBaseline struct {
+	From            string                     `json:"from"`
+	To              string                     `json:"to"`
+	Months          int                        `json:"months"`
+	OpeningBalances map[string]float64         `json:"opening_balances"`
+	Categories      []CategoryAverage          `json:"categories"`
+	Schedules       []entity.RecurringSchedule `json:"schedules"`
+}
+
+// CategoryAverage 表示某帳戶某分類的非週期性每月平均金額
+type CategoryAverage struct {
+	AccountID      string  `json:"account_id"`
+	Category       string  `json:"category"`
+	Type           string  `json:"type"`
+	MonthlyAverage float64 `json:"monthly_average"`
+}
+
+// generateForecast 以週期性排程加上近期非週期性收支的分類平均，預測未來各月的收支與帳戶餘額
+// startDate 與 endDate 決定預測的月份範圍，早於本月的部分會從本月開始。本月依用戶時區決定
+func (s *transactionService) generateForecast(cal userCalendar, userID, startDate, endDate string) (*ForecastReport, error) {
+	// monthStart 為用戶時區本月的開始，用於查詢交易；currentMonth 為同一月份的日期，與排程日期比較
+	monthStart := cal.periodStart(time.Now(), db.PeriodMonth)
+	currentMonth := time.Date(monthStart.Year(), monthStart.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	first, last, err := forecastRange(startDate, endDate, currentMonth)
+	if err != nil {
+		return nil, err
+	}
+
+	baselineFrom := currentMonth.AddDate(0, -forecastBaselineMonths, 0)
+	baselineStart := monthStart.AddDate(0, -forecastBaselineMonths, 0)
+	history, err := s.repo.GetTransactions(userID, cal.dbTime(baselineStart), cal.dbTime(monthStart.Add(-time.Second)))
+	if err != nil {
+		return nil, err
+	}
+	schedules, err := s.repo.GetRecurringSchedules(userID)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.repo.GetAccounts(userID)
+	if err != nil {
+		return nil, err
+	}
+	recorded, err := s.repo.GetAccountBalances(userID, cal.dbTime(monthStart))
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]float64)
+	for _, account := range accounts {
+		balances[account.ID] = account.OpeningBalance + recorded[account.ID]
+	}
+	if unassigned := recorded[""]; unassigned != 0 {
+		balances[UnassignedAccount] = unassigned
+	}
+
+	averages := nonRecurringAverages(history, schedules, forecastBaselineMonths)
+	report := &ForecastReport{
+		User:       userID,
+		ReportType: ReportTypeForecast,
+		Baseline: ForecastBaseline{
+			From:            baselineFrom.Format(dateLayout),
+			To:              currentMonth.AddDate(0, 0, -1).Format(dateLayout),
+			Months:          forecastBaselineMonths,
+			OpeningBalances: copyBalances(balances),
+			Categories:      averages,
+			Schedules:       schedules,
+		},
+	}
+
+	// 從本月開始逐月推算，只輸出請求範圍內的月份
+	for month := currentMonth; !month.After(last); month = month.AddDate(0, 1, 0) {
+		monthEnd := month.AddDate(0, 1, -1)
+		perAccount := make(map[string]*AccountForecast)
+		forecastFor := func(accountID string) *AccountForecast {
+			if accountID == "" {
+				accountID = UnassignedAccount
+			}
+			if perAccount[accountID] == nil {
+				perAccount[accountID] = &AccountForecast{AccountID: accountID}
+			}
+			return perAccount[accountID]
+		}
+		for accountID := range balances {
+			forecastFor(accountID)
+		}
+
+		for _, schedule := range schedules {
+			for range schedule.Occurrences(month, monthEnd) {
+				f := forecastFor(schedule.AccountID)
+				if schedule.IsIncome() {
+					f.RecurringIncome += schedule.Amount
+				} else {
+					f.RecurringExpense += schedule.Amount
+				}
+			}
+		}
+		for _, avg := range averages {
+			f := forecastFor(avg.AccountID)
+			if avg.Type == entity.TypeIncome {
+				f.Income += avg.MonthlyAverage
+			} else {
+				f.Expense += avg.MonthlyAverage
+			}
+		}
+
+		entry := ForecastMonth{Month: month.Format("2006-01")}
+		for accountID, f := range perAccount {
+			f.Income = roundAmount(f.Income + f.RecurringIncome)
+			f.Expense = roundAmount(f.Expense + f.RecurringExpense)
+			f.RecurringIncome = roundAmount(f.RecurringIncome)
+			f.RecurringExpense = roundAmount(f.RecurringExpense)
+			balances[accountID] += f.Income - f.Expense
+			f.EndBalance = roundAmount(balances[accountID])
+
+			entry.Income += f.Income
+			entry.Expense += f.Expense
+			entry.Accounts = append(entry.Accounts, *f)
+		}
+		if month.Before(first) {
+			continue
+		}
+
+		sort.Slice(entry.Accounts, func(i, j int) bool { return entry.Accounts[i].AccountID < entry.Accounts[j].AccountID })
+		entry.Income = roundAmount(entry.Income)
+		entry.Expense = roundAmount(entry.Expense)
+		entry.Net = roundAmount(entry.Income - entry.Expense)
+		report.Months = append(report.Months, entry)
+	}
+	return report, nil
+}
+
+// forecastRange 解析預測月份範圍，未指定時預設為本月起 6 個月
+func forecastRange(startDate, endDate string, currentMonth time.Time) (time.Time, time.Time, error) {
+	first := currentMonth
+	if startDate != "" {
+		start, err := time.Parse(dateLayout, startDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: start_date must be in YYYY-MM-DD format", ErrInvalidReportRequest)
+		}
+		if month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC); month.After(first) {
+			first = month
+		}
+	}
+
+	last := first.AddDate(0, defaultForecastMonths-1, 0)
+	if endDate != "" {
+		end, err := time.Parse(dateLayout, endDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: end_date must be in YYYY-MM-DD format", ErrInvalidReportRequest)
+		}
+		last = time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	if last.Before(first) || last.After(currentMonth.AddDate(0, maxForecastMonths-1, 0)) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: forecast range must end after it starts and within %d months", ErrInvalidReportRequest, maxForecastMonths)
+	}
+	return first, last, nil
+}
+
+// nonRecurringAverages 計算基準期間內排除受管理排程後，各帳戶各分類的每月平均收支。
+// 排程依收款方與收支類型比對，同一收款方另一類型的交易仍計入平均
+func nonRecurringAverages(history []entity.Transaction, schedules []entity.RecurringSchedule, months int) []CategoryAverage {
+	scheduled := make(map[string]bool, len(schedules))
+	for _, schedule := range schedules {
+		scheduled[seriesKey(schedule.Payee, schedule.IsIncome())] = true
+	}
+
+	type key struct{ accountID, category, txType string }
+	totals := make(map[key]float64)
+	for _, tx := range history {
+		if scheduled[seriesKey(payeeOf(tx), tx.IsIncome())] {
+			continue
+		}
+		accountID := tx.AccountID
+		if accountID == "" {
+			accountID = UnassignedAccount
+		}
+		txType := entity.TypeExpense
+		if tx.IsIncome() {
+			txType = entity.TypeIncome
+		}
+		totals[key{accountID, tx.Category, txType}] += tx.Amount
+	}
+
+	averages := make([]CategoryAverage, 0, len(totals))
+	for k, total := range totals {
+		averages = append(averages, CategoryAverage{
+			AccountID:      k.accountID,
+			Category:       k.category,
+			Type:           k.txType,
+			MonthlyAverage: roundAmount(total / float64(months)),
+		})
+	}
+	sort.Slice(averages, func(i, j int) bool {
+		if averages[i].AccountID != averages[j].AccountID {
+			return averages[i].AccountID < averages[j].AccountID
+		}
+		return averages[i].Category < averages[j].Category
+	})
+	return averages
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubRepo 以記憶體資料實現測試所需的 DBClient 方法，未實現的方法會直接 panic
+type stubRepo struct {
+	db.DBClient
+	transactions []entity.Transaction
+	schedules    []entity.RecurringSchedule
+	accounts     []entity.Account
+	balances     map[string]float64
+}
+
+func (r *stubRepo) GetTransactions(userID, startDate, endDate string) ([]entity.Transaction, error) {
+	// 與 MySQL 的 BETWEEN 相同，只有日期的參數視為當天 00:00:00
+	if len(startDate) == len(dateLayout) {
+		startDate += " 00:00:00"
+	}
+	if len(endDate) == len(dateLayout) {
+		endDate += " 00:00:00"
+	}
+	var result []entity.Transaction
+	for _, tx := range r.transactions {
+		if date := tx.Date.UTC().Format(dbTimeLayout); tx.UserID == userID && date >= startDate && date <= endDate {
+			result = append(result, tx)
+		}
+	}
+	return result, nil
+}
+
+func (r *stubRepo) GetRecurringSchedules(userID string) ([]entity.RecurringSchedule, error) {
+	return r.schedules, nil
+}
+
+func (r *stubRepo) GetAccounts(userID string) ([]entity.Account, error) {
+	return r.accounts, nil
+}
+
+func (r *stubRepo) GetAccountBalances(userID, before string) (map[string]float64, error) {
+	return r.balances, nil
+}
+
+func TestGenerateForecast(t *testing.T) {
+	now := time.Now()
+	currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	repo := &stubRepo{
+		accounts: []entity.Account{{ID: "bank1", UserID: "user123", Type: entity.AccountTypeBank, OpeningBalance: 1000}},
+		balances: map[string]float64{"bank1": 500},
+		schedules: []entity.RecurringSchedule{
+			{ID: "s1", Payee: "ACME Corp", Type: entity.TypeIncome, Cadence: entity.CadenceMonthly, Amount: 50000, NextDate: currentMonth.AddDate(0, 0, 4), Active: true, AccountID: "bank1"},
+			{ID: "s2", Payee: "Netflix", Type: entity.TypeExpense, Cadence: entity.CadenceMonthly, Amount: 390, NextDate: currentMonth.AddDate(0, 0, 14), Active: true, AccountID: "bank1"},
+		},
+	}
+	for i := 1; i <= forecastBaselineMonths; i++ {
+		month := currentMonth.AddDate(0, -i, 0)
+		repo.transactions = append(repo.transactions,
+			entity.Transaction{UserID: "user123", Date: month.AddDate(0, 0, 2), Amount: 600, Category: "FOOD", AccountID: "bank1"},
+			// 已有受管理排程的收款方不應計入平均，避免重複計算
+			entity.Transaction{UserID: "user123", Date: month.AddDate(0, 0, 14), Amount: 390, Category: "SUBSCRIPTION", Payee: "Netflix", AccountID: "bank1"},
+		)
+	}
+
+	s := &transactionService{repo: repo}
+	report, err := s.generateForecast(utcCalendar, "user123", "", "")
+	assert.NoError(t, err)
+
+	assert.Len(t, report.Months, defaultForecastMonths)
+	assert.Equal(t, []CategoryAverage{{AccountID: "bank1", Category: "FOOD", Type: entity.TypeExpense, MonthlyAverage: 600}}, report.Baseline.Categories)
+	assert.Equal(t, 1500.0, report.Baseline.OpeningBalances["bank1"])
+
+	first := report.Months[0]
+	assert.Equal(t, currentMonth.Format("2006-01"), first.Month)
+	assert.Equal(t, 50000.0, first.Income)
+	assert.Equal(t, 990.0, first.Expense)
+	assert.Equal(t, 49010.0, first.Net)
+	assert.Equal(t, 50510.0, first.Accounts[0].EndBalance)
+	assert.Equal(t, 99520.0, report.Months[1].Accounts[0].EndBalance)
+}
+
+func TestForecastRange(t *testing.T) {
+	currentMonth := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	first, last, err := forecastRange("2024-01-15", "2024-09-30", currentMonth)
+	assert.NoError(t, err)
+	assert.Equal(t, currentMonth, first)
+	assert.Equal(t, time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC), last)
+
+	_, _, err = forecastRange("", "2027-01-01", currentMonth)
+	assert.True(t, errors.Is(err, ErrInvalidReportRequest))
+
+	_, _, err = forecastRange("2024-06", "", currentMonth)
+	assert.True(t, errors.Is(err, ErrInvalidReportRequest))
+}
+
+func TestForecastUsesUserTimeZone(t *testing.T) {
+	cal := userCalendar{loc: time.FixedZone("UTC+8", 8*60*60), fiscalStart: time.January}
+	monthStart := cal.periodStart(time.Now(), db.PeriodMonth)
+
+	repo := &stubRepo{transactions: []entity.Transaction{
+		// 用戶時區上個月的最後一分鐘計入基準，本月的第一分鐘不計入
+		{UserID: "user123", Date: monthStart.Add(-time.Minute), Amount: 600, Category: "FOOD"},
+		{UserID: "user123", Date: monthStart.Add(time.Minute), Amount: 900, Category: "FOOD"},
+	}}
+
+	s := &transactionService{repo: repo}
+	report, err := s.generateForecast(cal, "user123", "", "")
+	assert.NoError(t, err)
+
+	assert.Equal(t, monthStart.Format("2006-01"), report.Months[0].Month)
+	assert.Equal(t, []CategoryAverage{{AccountID: UnassignedAccount, Category: "FOOD", Type: entity.TypeExpense, MonthlyAverage: 100}}, report.Baseline.Categories)
+}
+
+func TestNonRecurringAveragesMatchesScheduleType(t *testing.T) {
+	month := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	schedules := []entity.RecurringSchedule{{Payee: "ACME Corp", Type: entity.TypeIncome}}
+	history := []entity.Transaction{
+		{Date: month, Amount: 50000, Type: entity.TypeIncome, Payee: "ACME Corp", Category: "SALARY"},
+		// 同一收款方的支出不屬於收入排程，仍計入平均
+		{Date: month, Amount: 300, Type: entity.TypeExpense, Payee: "acme corp", Category: "FOOD"},
+	}
+
+	averages := nonRecurringAverages(history, schedules, 1)
+	assert.Equal(t, []CategoryAverage{{AccountID: UnassignedAccount, Category: "FOOD", Type: entity.TypeExpense, MonthlyAverage: 300}}, averages)
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fintrack/internal/cache"
+	"fintrack/internal/entity"
+	"fmt"
+	"log"
+	"time"
+)
+
+var (
+	// ErrInvalidIdempotencyKey 表示冪等鍵為空或過長
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+	// ErrIdempotencyKeyReused 表示冪等鍵已用於內容不同的請求
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used with a different request")
+	// ErrIdempotencyInProgress 表示使用同一冪等鍵的請求仍在處理中
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+const (
+	idempotencyTTL          = 24 * time.Hour // 冪等鍵在 Redis 保留的時間
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyRecord 記錄冪等鍵對應的請求摘要與第一次的回應
+type idempotencyRecord struct {
+	RequestHash string      `json:"request_hash"`
+	Submission  *Submission `json:"submission,omitempty"` // 第一次請求返回的提交，處理中時為空
+}
+
+// idempotencyKey 返回用戶冪等鍵在 Redis 的鍵，不同用戶的冪等鍵互不影響
+func idempotencyKey(userID, key string) string {
+	return "idempotency:" + userID + ":" + key
+}
+
+// requestHash 返回交易內容的 SHA-256 摘要，JSON 的空白與欄位順序不影響結果
+func requestHash(tx entity.Transaction) (string, error) {
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AddTransactionOnce 以冪等鍵新增交易。同一用戶以相同內容重送時不再寫入，返回第一次的提交且 replayed 為 true；
+// 內容不同時返回 ErrIdempotencyKeyReused。送出失敗的請求不保留冪等鍵，客戶端可以同一個鍵重試
+func (s *transactionService) AddTransactionOnce(ctx context.Context, key string, tx entity.Transaction) (*Submission, bool, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, false, fmt.Errorf("%w: Idempotency-Key must be 1 to %d characters", ErrInvalidIdempotencyKey, maxIdempotencyKeyLength)
+	}
+	hash, err := requestHash(tx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 先佔用冪等鍵，同時到達的重送只有一個會送出交易
+	redisKey := idempotencyKey(tx.UserID, key)
+	record := idempotencyRecord{RequestHash: hash}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+	acquired, err := s.cache.SetNX(ctx, redisKey, data, idempotencyTTL)
+	if err != nil {
+		return nil, false, err
+	}
+	if !acquired {
+		submission, err := replayIdempotent(ctx, s.cache, redisKey, hash)
+		return submission, submission != nil, err
+	}
+
+	submission, err := s.AddTransaction(tx)
+	if err != nil {
+		_ = s.cache.Delete(ctx, redisKey)
+		return nil, false, err
+	}
+
+	record.Submission = submission
+	if data, err = json.Marshal(record); err == nil {
+		err = s.cache.Set(ctx, redisKey, data, idempotencyTTL)
+	}
+	if err != nil {
+		// 交易已送出，不能返回失敗讓客戶端重送；此後的重送會得到處理中的錯誤
+		log.Printf("Failed to save idempotency record %s: %v", redisKey, err)
+	}
+	return submission, false, nil
+}
+
+// replayIdempotent 返回已使用的冪等鍵第一次的提交
+func replayIdempotent(ctx context.Context, c cache.Cache, redisKey, hash string) (*Submission, error) {
+	data, err := c.Get(ctx, redisKey)
+	if errors.Is(err, cache.ErrNotFound) {
+		// 第一次請求剛失敗並釋放了冪等鍵
+		return nil, ErrIdempotencyInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record idempotencyRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return nil, err
+	}
+	if record.RequestHash != hash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if record.Submission == nil {
+		return nil, ErrIdempotencyInProgress
+	}
+	return record.Submission, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fintrack/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddTransactionOnce(t *testing.T) {
+	cache := &cacheStub{values: make(map[string]string)}
+	queue := &queueStub{}
+	s := &transactionService{cache: cache, producer: queue}
+	ctx := context.Background()
+	tx := entity.Transaction{ID: "tx1", UserID: "user123", Date: time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC), Amount: 100}
+
+	first, replayed, err := s.AddTransactionOnce(ctx, "key1", tx)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+
+	// 重送相同內容返回第一次的提交，不再送出消息
+	again, replayed, err := s.AddTransactionOnce(ctx, "key1", tx)
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, first.ID, again.ID)
+	assert.Len(t, queue.messages, 1)
+
+	// 同一冪等鍵用於不同內容
+	changed := tx
+	changed.Amount = 200
+	_, _, err = s.AddTransactionOnce(ctx, "key1", changed)
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	// 冪等鍵依用戶區分
+	other := tx
+	other.UserID = "user456"
+	_, replayed, err = s.AddTransactionOnce(ctx, "key1", other)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Len(t, queue.messages, 2)
+
+	_, _, err = s.AddTransactionOnce(ctx, "", tx)
+	assert.ErrorIs(t, err, ErrInvalidIdempotencyKey)
+}
+
+func TestAddTransactionOnceRetryAfterFailure(t *testing.T) {
+	cache := &cacheStub{values: make(map[string]string)}
+	queue := &queueStub{err: errors.New("connection closed")}
+	s := &transactionService{cache: cache, producer: queue}
+	ctx := context.Background()
+	tx := entity.Transaction{ID: "tx1", UserID: "user123", Date: time.Now(), Amount: 100}
+
+	_, _, err := s.AddTransactionOnce(ctx, "key1", tx)
+	assert.Error(t, err)
+
+	// 送出失敗後冪等鍵已釋放，重試會重新送出
+	queue.err = nil
+	_, replayed, err := s.AddTransactionOnce(ctx, "key1", tx)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Len(t, queue.messages, 1)
+
+	// 處理中的冪等鍵不能重複使用
+	redisKey := idempotencyKey("user123", "key2")
+	cache.values[redisKey] = `{"request_hash":"` + mustHash(t, tx) + `"}`
+	_, _, err = s.AddTransactionOnce(ctx, "key2", tx)
+	assert.ErrorIs(t, err, ErrIdempotencyInProgress)
+}
+
+func mustHash(t *testing.T, tx entity.Transaction) string {
+	hash, err := requestHash(tx)
+	assert.NoError(t, err)
+	return hash
+}
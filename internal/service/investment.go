@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fintrack/internal/cache"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidTrade     = errors.New("invalid trade")
+	ErrInvalidPriceFile = errors.New("invalid price file")
+)
+
+// Holding 表示某帳戶對某證券的持倉與市值
+type Holding struct {
+	AccountID      string  `json:"account_id"`
+	Symbol         string  `json:"symbol"`
+	Quantity       float64 `json:"quantity"`
+	CostBasis      float64 `json:"cost_basis"`
+	AverageCost    float64 `json:"average_cost"`
+	MarketPrice    float64 `json:"market_price"`
+	PriceDate      string  `json:"price_date,omitempty"` // 無價格資料時為空，市值以成本計算
+	MarketValue    float64 `json:"market_value"`
+	UnrealizedGain float64 `json:"unrealized_gain"`
+	Lots           []Lot   `json:"lots"`
+}
+
+// PortfolioReport 表示某日的投資組合市值與未實現損益
+type PortfolioReport struct {
+	User                string     `json:"user"`
+	AsOf                string     `json:"as_of"`
+	Method              CostMethod `json:"method"`
+	Holdings            []Holding  `json:"holdings"`
+	TotalMarketValue    float64    `json:"total_market_value"`
+	TotalCostBasis      float64    `json:"total_cost_basis"`
+	TotalUnrealizedGain float64    `json:"total_unrealized_gain"`
+}
+
+// GainsReport 表示期間內的已實現損益與股利收入
+type GainsReport struct {
+	User              string           `json:"user"`
+	From              string           `json:"from"`
+	To                string           `json:"to"`
+	Method            CostMethod       `json:"method"`
+	Realized          []RealizedGain   `json:"realized"`
+	TotalRealizedGain float64          `json:"total_realized_gain"`
+	Dividends         []DividendIncome `json:"dividends"`
+	TotalDividends    float64          `json:"total_dividends"`
+	DividendsByMonth  []MonthlyAmount  `json:"dividends_by_month"`
+}
+
+// MonthlyAmount 表示某月的金額
+type MonthlyAmount struct {
+	Month  string  `json:"month"` // YYYY-MM
+	Amount float64 `json:"amount"`
+}
+
+type InvestmentService interface {
+	SaveSecurity(security entity.Security) (*entity.Security, error)
+	GetSecurities() ([]entity.Security, error)
+	RecordTrade(trade entity.Trade) (*entity.Trade, error)
+	GetTrades(userID string) ([]entity.Trade, error)
+	ImportPrices(data io.Reader) (int, error)
+	GetPortfolio(userID string, asOf time.Time, method CostMethod) (*PortfolioReport, error)
+	GetGains(userID string, from, to time.Time, method CostMethod) (*GainsReport, error)
+}
+
+type investmentService struct {
+	repo  db.DBClient
+	cache cache.Cache
+}
+
+func NewInvestmentService(repo db.DBClient, cache cache.Cache) InvestmentService {
+	return &investmentService{repo: repo, cache: cache}
+}
+
+// SaveSecurity 新增或更新證券資料
+func (s *investmentService) SaveSecurity(security entity.Security) (*entity.Security, error) {
+	security.Symbol = strings.ToUpper(strings.TrimSpace(security.Symbol))
+	if security.Symbol == "" {
+		return nil, fmt.Errorf("%w: symbol is required", ErrInvalidTrade)
+	}
+	security.CreatedAt = time.Now()
+	if err := s.repo.SaveSecurity(security); err != nil {
+		return nil, err
+	}
+	return &security, nil
+}
+
+// GetSecurities 查詢所有證券
+func (s *investmentService) GetSecurities() ([]entity.Security, error) {
+	return s.repo.GetSecurities()
+}
+
+// allTradesBefore 為查詢全部投資交易時的日期上限
+const allTradesBefore = "9999-12-31"
+
+// RecordTrade 驗證並記錄一筆投資交易，加入後任何一筆賣出的股數都不得超過當時的持股
+func (s *investmentService) RecordTrade(trade entity.Trade) (*entity.Trade, error) {
+	trade.Symbol = strings.ToUpper(strings.TrimSpace(trade.Symbol))
+	if err := validateTrade(trade); err != nil {
+		return nil, err
+	}
+
+	account, err := s.repo.GetAccountByID(trade.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.UserID != trade.UserID {
+		return nil, db.ErrAccountNotFound
+	}
+	if account.Type != entity.AccountTypeInvestment {
+		return nil, fmt.Errorf("%w: account is not an investment account", ErrInvalidTrade)
+	}
+	if _, err := s.repo.GetSecurity(trade.Symbol); err != nil {
+		return nil, err
+	}
+
+	// 補登較早的賣出或分割會影響之後的持股，因此加入新交易後重播全部交易，任一時點都不得超賣
+	if trade.Type == entity.TradeSell || trade.Type == entity.TradeSplit {
+		trades, err := s.repo.GetTrades(trade.UserID, allTradesBefore)
+		if err != nil {
+			return nil, err
+		}
+		if sell, held := oversold(append(trades, trade), trade.AccountID, trade.Symbol); sell != nil {
+			return nil, fmt.Errorf("%w: cannot sell %g shares of %s on %s, only %g held", ErrInvalidTrade, sell.Quantity, trade.Symbol, sell.Date.Format(dateLayout), held)
+		}
+	}
+
+	trade.ID = uuid.NewString()
+	trade.CreatedAt = time.Now()
+	if err := s.repo.SaveTrade(trade); err != nil {
+		return nil, err
+	}
+	if err := invalidateNetWorthSnapshots(s.repo, trade.UserID, trade.Date); err != nil {
+		return nil, err
+	}
+	if err := bumpReportVersion(context.Background(), s.cache, trade.UserID); err != nil {
+		log.Printf("Failed to invalidate cached reports: %v", err)
+	}
+	return &trade, nil
+}
+
+// GetTrades 查詢用戶的所有投資交易
+func (s *investmentService) GetTrades(userID string) ([]entity.Trade, error) {
+	return s.repo.GetTrades(userID, time.Now().AddDate(0, 0, 1).Format(dateLayout))
+}
+
+// ImportPrices 從 CSV（symbol,date,close）匯入收盤價，第一列可為標題列
+func (s *investmentService) ImportPrices(data io.Reader) (int, error) {
+	reader := csv.NewReader(data)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	var prices []entity.SecurityPrice
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidPriceFile, err)
+		}
+		if line == 1 && strings.EqualFold(record[0], "symbol") {
+			continue
+		}
+
+		date, err := time.Parse(dateLayout, record[1])
+		if err != nil {
+			return 0, fmt.Errorf("%w: line %d: date must be in YYYY-MM-DD format", ErrInvalidPriceFile, line)
+		}
+		closePrice, err := strconv.ParseFloat(record[2], 64)
+		if err != nil || closePrice < 0 {
+			return 0, fmt.Errorf("%w: line %d: close must be a non-negative number", ErrInvalidPriceFile, line)
+		}
+		prices = append(prices, entity.SecurityPrice{
+			Symbol: strings.ToUpper(strings.TrimSpace(record[0])),
+			Date:   date,
+			Close:  closePrice,
+		})
+	}
+
+	if err := s.repo.SavePrices(prices); err != nil {
+		return 0, err
+	}
+
+	// 歷史股價會影響所有持有該證券用戶的月底持股市值
+	var earliest time.Time
+	symbols := make(map[string]bool)
+	for _, price := range prices {
+		if earliest.IsZero() || price.Date.Before(earliest) {
+			earliest = price.Date
+		}
+		symbols[price.Symbol] = true
+	}
+	if len(prices) > 0 {
+		if err := invalidateNetWorthSnapshots(s.repo, "", earliest); err != nil {
+			return 0, err
+		}
+		s.invalidateHolderReports(symbols)
+	}
+	return len(prices), nil
+}
+
+// invalidateHolderReports 使曾交易這些證券的用戶已快取的報表失效
+func (s *investmentService) invalidateHolderReports(symbols map[string]bool) {
+	if s.cache == nil {
+		return
+	}
+	list := make([]string, 0, len(symbols))
+	for symbol := range symbols {
+		list = append(list, symbol)
+	}
+	userIDs, err := s.repo.GetTradeUserIDs(list)
+	if err != nil {
+		log.Printf("Failed to invalidate cached reports: %v", err)
+		return
+	}
+	for _, userID := range userIDs {
+		if err := bumpReportVersion(context.Background(), s.cache, userID); err != nil {
+			log.Printf("Failed to invalidate cached reports: %v", err)
+		}
+	}
+}
+
+// GetPortfolio 計算指定日期的持倉市值與未實現損益
+func (s *investmentService) GetPortfolio(userID string, asOf time.Time, method CostMethod) (*PortfolioReport, error) {
+	return valuePortfolio(s.repo, userID, asOf, method)
+}
+
+// GetGains 計算期間內的已實現損益與股利收入
+func (s *investmentService) GetGains(userID string, from, to time.Time, method CostMethod) (*GainsReport, error) {
+	trades, err := s.repo.GetTrades(userID, to.AddDate(0, 0, 1).Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+	ledger := replayTrades(trades, method)
+
+	report := &GainsReport{
+		User:             userID,
+		From:             from.Format(dateLayout),
+		To:               to.Format(dateLayout),
+		Method:           method,
+		Realized:         []RealizedGain{},
+		Dividends:        []DividendIncome{},
+		DividendsByMonth: []MonthlyAmount{},
+	}
+	for _, gain := range ledger.realized {
+		if !gain.Date.Before(from) {
+			report.Realized = append(report.Realized, gain)
+			report.TotalRealizedGain += gain.Gain
+		}
+	}
+
+	byMonth := make(map[string]float64)
+	for _, dividend := range ledger.dividends {
+		if dividend.Date.Before(from) {
+			continue
+		}
+		report.Dividends = append(report.Dividends, dividend)
+		report.TotalDividends += dividend.Amount
+		byMonth[dividend.Date.Format("2006-01")] += dividend.Amount
+	}
+	for month, amount := range byMonth {
+		report.DividendsByMonth = append(report.DividendsByMonth, MonthlyAmount{Month: month, Amount: roundAmount(amount)})
+	}
+	sort.Slice(report.DividendsByMonth, func(i, j int) bool { return report.DividendsByMonth[i].Month < report.DividendsByMonth[j].Month })
+
+	report.TotalRealizedGain = roundAmount(report.TotalRealizedGain)
+	report.TotalDividends = roundAmount(report.TotalDividends)
+	return report, nil
+}
+
+// valuePortfolio 重播交易並以指定日期前的最新收盤價計算持倉市值，無價格資料時以成本計算
+func valuePortfolio(repo db.DBClient, userID string, asOf time.Time, method CostMethod) (*PortfolioReport, error) {
+	trades, err := repo.GetTrades(userID, asOf.AddDate(0, 0, 1).Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+	ledger := replayTrades(trades, method)
+
+	var symbols []string
+	for _, p := range ledger.positions {
+		if p.quantity > 0 {
+			symbols = append(symbols, p.symbol)
+		}
+	}
+	prices, err := repo.GetLatestPrices(symbols, asOf.Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PortfolioReport{User: userID, AsOf: asOf.Format(dateLayout), Method: method, Holdings: []Holding{}}
+	for _, p := range ledger.positions {
+		if p.quantity <= 0 {
+			continue
+		}
+		holding := Holding{
+			AccountID:   p.accountID,
+			Symbol:      p.symbol,
+			Quantity:    p.quantity,
+			CostBasis:   roundAmount(p.totalCost),
+			AverageCost: roundAmount(p.totalCost / p.quantity),
+			MarketValue: roundAmount(p.totalCost),
+			Lots:        p.lots,
+		}
+		if price, ok := prices[p.symbol]; ok {
+			holding.MarketPrice = price.Close
+			holding.PriceDate = price.Date.Format(dateLayout)
+			holding.MarketValue = roundAmount(p.quantity * price.Close)
+		}
+		holding.UnrealizedGain = roundAmount(holding.MarketValue - holding.CostBasis)
+
+		report.Holdings = append(report.Holdings, holding)
+		report.TotalMarketValue += holding.MarketValue
+		report.TotalCostBasis += holding.CostBasis
+	}
+
+	report.TotalMarketValue = roundAmount(report.TotalMarketValue)
+	report.TotalCostBasis = roundAmount(report.TotalCostBasis)
+	report.TotalUnrealizedGain = roundAmount(report.TotalMarketValue - report.TotalCostBasis)
+	return report, nil
+}
+
+// ParseCostMethod 解析成本計算方法，未指定時預設為 FIFO
+func ParseCostMethod(v string) (CostMethod, error) {
+	switch CostMethod(strings.ToUpper(v)) {
+	case "", CostMethodFIFO:
+		return CostMethodFIFO, nil
+	case CostMethodAverage:
+		return CostMethodAverage, nil
+	}
+	return "", fmt.Errorf("%w: method must be FIFO or AVERAGE", ErrInvalidTrade)
+}
+
+// validateTrade 依交易類型檢查必要欄位
+func validateTrade(trade entity.Trade) error {
+	if trade.UserID == "" || trade.AccountID == "" || trade.Symbol == "" || trade.Date.IsZero() {
+		return fmt.Errorf("%w: user_id, account_id, symbol and date are required", ErrInvalidTrade)
+	}
+	switch trade.Type {
+	case entity.TradeBuy, entity.TradeSell:
+		if trade.Quantity <= 0 || trade.Price < 0 || trade.Fee < 0 {
+			return fmt.Errorf("%w: quantity must be positive and price and fee non-negative", ErrInvalidTrade)
+		}
+	case entity.TradeDividend:
+		if trade.Amount <= 0 {
+			return fmt.Errorf("%w: dividend amount must be positive", ErrInvalidTrade)
+		}
+	case entity.TradeSplit:
+		if trade.Ratio <= 0 {
+			return fmt.Errorf("%w: split ratio must be positive", ErrInvalidTrade)
+		}
+	default:
+		return fmt.Errorf("%w: type must be BUY, SELL, DIVIDEND or SPLIT", ErrInvalidTrade)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// investmentRepo 以記憶體保存投資交易，並記錄刪除淨資產快照的起始日
+type investmentRepo struct {
+	db.DBClient
+	trades        []entity.Trade
+	snapshotsFrom []string
+}
+
+func (r *investmentRepo) GetAccountByID(accountID string) (*entity.Account, error) {
+	return &entity.Account{ID: accountID, UserID: "user123", Type: entity.AccountTypeInvestment}, nil
+}
+
+func (r *investmentRepo) GetSecurity(symbol string) (*entity.Security, error) {
+	return &entity.Security{Symbol: symbol}, nil
+}
+
+func (r *investmentRepo) GetTrades(userID, before string) ([]entity.Trade, error) {
+	var trades []entity.Trade
+	for _, trade := range r.trades {
+		if trade.Date.Format(dateLayout) < before {
+			trades = append(trades, trade)
+		}
+	}
+	return trades, nil
+}
+
+func (r *investmentRepo) SaveTrade(trade entity.Trade) error {
+	r.trades = append(r.trades, trade)
+	return nil
+}
+
+func (r *investmentRepo) GetTradeUserIDs(symbols []string) ([]string, error) {
+	var userIDs []string
+	for _, trade := range r.trades {
+		for _, symbol := range symbols {
+			if trade.Symbol == symbol && !slices.Contains(userIDs, trade.UserID) {
+				userIDs = append(userIDs, trade.UserID)
+			}
+		}
+	}
+	return userIDs, nil
+}
+
+func (r *investmentRepo) SavePrices(prices []entity.SecurityPrice) error {
+	return nil
+}
+
+func (r *investmentRepo) DeleteNetWorthSnapshots(userID, from string) error {
+	r.snapshotsFrom = append(r.snapshotsFrom, from)
+	return nil
+}
+
+func TestRecordTradeRejectsOversell(t *testing.T) {
+	repo := &investmentRepo{trades: []entity.Trade{
+		{ID: "1", UserID: "user123", AccountID: "inv1", Symbol: "2330", Type: entity.TradeBuy, Date: day(2024, 1, 2), Quantity: 100, Price: 500},
+		{ID: "2", UserID: "user123", AccountID: "inv1", Symbol: "2330", Type: entity.TradeSell, Date: day(2024, 3, 1), Quantity: 80, Price: 600},
+	}}
+	s := &investmentService{repo: repo}
+	sell := entity.Trade{UserID: "user123", AccountID: "inv1", Symbol: "2330", Type: entity.TradeSell, Quantity: 50, Price: 550}
+
+	// 補登在既有賣出之前：當天持有 100 股，但會使 3 月的賣出超賣
+	backdated := sell
+	backdated.Date = day(2024, 2, 1)
+	_, err := s.RecordTrade(backdated)
+	assert.ErrorIs(t, err, ErrInvalidTrade)
+	assert.Contains(t, err.Error(), "on 2024-03-01, only 50 held")
+
+	// 在既有賣出之後則只剩 20 股
+	late := sell
+	late.Date = day(2024, 4, 1)
+	_, err = s.RecordTrade(late)
+	assert.ErrorIs(t, err, ErrInvalidTrade)
+	assert.Len(t, repo.trades, 2)
+
+	// 反向分割同樣會減少之後的持股
+	reverse := entity.Trade{UserID: "user123", AccountID: "inv1", Symbol: "2330", Type: entity.TradeSplit, Date: day(2024, 2, 1), Ratio: 0.5}
+	_, err = s.RecordTrade(reverse)
+	assert.ErrorIs(t, err, ErrInvalidTrade)
+
+	// 補登後之後的賣出仍有足夠持股即可記錄
+	sell.Quantity = 20
+	sell.Date = day(2024, 2, 1)
+	_, err = s.RecordTrade(sell)
+	assert.NoError(t, err)
+	assert.Len(t, repo.trades, 3)
+}
+
+func TestOversold(t *testing.T) {
+	trades := append(sampleTrades(),
+		entity.Trade{AccountID: "inv2", Symbol: "2330", Type: entity.TradeBuy, Date: day(2024, 1, 1), Quantity: 500},
+		entity.Trade{AccountID: "inv1", Symbol: "2330", Type: entity.TradeSplit, Date: day(2024, 5, 1), Ratio: 2},
+		entity.Trade{AccountID: "inv1", Symbol: "2330", Type: entity.TradeSell, Date: day(2024, 6, 1), Quantity: 100},
+	)
+	sell, _ := oversold(trades, "inv1", "2330")
+	assert.Nil(t, sell)
+
+	trades = append(trades, entity.Trade{AccountID: "inv1", Symbol: "2330", Type: entity.TradeSell, Date: day(2024, 3, 1), Quantity: 160})
+	// 補登的賣出本身不超賣，但使 4 月的賣出超過持股
+	sell, held := oversold(trades, "inv1", "2330")
+	if assert.NotNil(t, sell) {
+		assert.Equal(t, day(2024, 4, 1), sell.Date)
+		assert.Equal(t, 40.0, held)
+	}
+}
+
+func TestInvestmentWritesBumpReportVersion(t *testing.T) {
+	repo := &investmentRepo{trades: []entity.Trade{
+		{ID: "1", UserID: "user123", AccountID: "inv1", Symbol: "2330", Type: entity.TradeBuy, Date: day(2024, 1, 2), Quantity: 100, Price: 500},
+		{ID: "2", UserID: "user456", AccountID: "inv2", Symbol: "0050", Type: entity.TradeBuy, Date: day(2024, 1, 2), Quantity: 10, Price: 100},
+	}}
+	c := &cacheStub{values: map[string]string{}}
+	s := &investmentService{repo: repo, cache: c}
+
+	// 新增交易使該用戶已快取的淨資產報表失效
+	_, err := s.RecordTrade(entity.Trade{UserID: "user123", AccountID: "inv1", Symbol: "2330", Type: entity.TradeSell, Date: day(2024, 2, 1), Quantity: 10, Price: 550})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", reportVersion(context.Background(), c, "user123"))
+	assert.Equal(t, "0", reportVersion(context.Background(), c, "user456"))
+
+	// 匯入股價只影響持有該證券的用戶
+	count, err := s.ImportPrices(strings.NewReader("symbol,date,close\n2330,2024-01-31,560\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "2", reportVersion(context.Background(), c, "user123"))
+	assert.Equal(t, "0", reportVersion(context.Background(), c, "user456"))
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fintrack/internal/cache"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fintrack/internal/mq"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidLoan 表示貸款或還款資料不正確
+var ErrInvalidLoan = errors.New("invalid loan")
+
+// LoanReport 表示貸款在某期間的還款摘要
+type LoanReport struct {
+	Loan                        entity.Loan          `json:"loan"`
+	From                        string               `json:"from"`
+	To                          string               `json:"to"`
+	PrincipalPaid               float64              `json:"principal_paid"`
+	InterestPaid                float64              `json:"interest_paid"`
+	TotalInterestPaid           float64              `json:"total_interest_paid"` // 撥款至期末累計
+	RemainingPrincipal          float64              `json:"remaining_principal"` // 期末剩餘本金
+	ScheduledRemainingPrincipal float64              `json:"scheduled_remaining_principal"`
+	Payments                    []entity.LoanPayment `json:"payments"`
+}
+
+type LoanService interface {
+	CreateLoan(loan entity.Loan) (*entity.Loan, error)
+	GetLoans(userID string) ([]entity.Loan, error)
+	GetSchedule(userID, loanID string) ([]entity.AmortizationRow, error)
+	RecordPayment(userID, loanID string, date time.Time, amount float64) (*entity.LoanPayment, error)
+	GetLoanReport(userID, loanID string, from, to time.Time) (*LoanReport, error)
+}
+
+type loanService struct {
+	repo     db.DBClient
+	cache    cache.Cache
+	producer mq.MQProducer
+}
+
+func NewLoanService(repo db.DBClient, cache cache.Cache, producer mq.MQProducer) LoanService {
+	return &loanService{repo: repo, cache: cache, producer: producer}
+}
+
+// CreateLoan 驗證並建立貸款
+func (s *loanService) CreateLoan(loan entity.Loan) (*entity.Loan, error) {
+	if loan.UserID == "" || loan.Name == "" {
+		return nil, fmt.Errorf("%w: user_id and name are required", ErrInvalidLoan)
+	}
+	if loan.Principal <= 0 || loan.AnnualRate < 0 || loan.TermMonths <= 0 || loan.PaymentDay < 1 || loan.PaymentDay > 31 || loan.StartDate.IsZero() {
+		return nil, fmt.Errorf("%w: principal and term_months must be positive, annual_rate non-negative, payment_day between 1 and 31 and start_date set", ErrInvalidLoan)
+	}
+
+	loan.ID = uuid.NewString()
+	loan.CreatedAt = time.Now()
+	if err := s.repo.SaveLoan(loan); err != nil {
+		return nil, err
+	}
+	// 淨資產報表包含貸款的剩餘本金，新貸款須使快取的報表失效
+	if err := bumpReportVersion(context.Background(), s.cache, loan.UserID); err != nil {
+		log.Printf("Failed to invalidate cached reports: %v", err)
+	}
+	return &loan, nil
+}
+
+// GetLoans 查詢用戶的所有貸款
+func (s *loanService) GetLoans(userID string) ([]entity.Loan, error) {
+	return s.repo.GetLoans(userID)
+}
+
+// GetSchedule 產生貸款的攤還表
+func (s *loanService) GetSchedule(userID, loanID string) ([]entity.AmortizationRow, error) {
+	loan, err := s.loan(userID, loanID)
+	if err != nil {
+		return nil, err
+	}
+	return loan.Schedule(), nil
+}
+
+// RecordPayment 記錄一筆還款，依剩餘本金自動拆分為本金與利息，並將兩筆交易以一次事務送至 RabbitMQ 寫入；
+// 送出失敗時刪除還款紀錄，避免剩餘本金與已寫入的交易不一致
+func (s *loanService) RecordPayment(userID, loanID string, date time.Time, amount float64) (*entity.LoanPayment, error) {
+	loan, err := s.loan(userID, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if amount <= 0 || date.IsZero() {
+		return nil, fmt.Errorf("%w: amount must be positive and date is required", ErrInvalidLoan)
+	}
+
+	payments, err := s.repo.GetLoanPayments(loanID)
+	if err != nil {
+		return nil, err
+	}
+	remaining := loan.Principal
+	if len(payments) > 0 {
+		last := payments[len(payments)-1]
+		// 利息依前一期剩餘本金計算，因此還款需依日期順序記錄
+		if date.Before(last.Date) {
+			return nil, fmt.Errorf("%w: payments must be recorded in date order", ErrInvalidLoan)
+		}
+		remaining = last.RemainingPrincipal
+	}
+	if remaining <= 0 {
+		return nil, fmt.Errorf("%w: loan is already paid off", ErrInvalidLoan)
+	}
+
+	principal, interest := loan.Split(amount, remaining)
+	payment := entity.LoanPayment{
+		ID:                 uuid.NewString(),
+		LoanID:             loanID,
+		UserID:             userID,
+		Date:               date,
+		Amount:             roundAmount(principal + interest),
+		Principal:          principal,
+		Interest:           interest,
+		RemainingPrincipal: roundAmount(remaining - principal),
+		CreatedAt:          time.Now(),
+	}
+	if err := s.repo.SaveLoanPayment(payment); err != nil {
+		return nil, err
+	}
+
+	// 交易的 ExternalID 為還款 ID 加上 -principal 或 -interest，用於對應還款紀錄
+	parts := []struct {
+		suffix   string
+		category string
+		amount   float64
+	}{
+		{"-principal", entity.CategoryLoanPrincipal, principal},
+		{"-interest", entity.CategoryLoanInterest, interest},
+	}
+	var events []TransactionEvent
+	for _, part := range parts {
+		if part.amount <= 0 {
+			continue
+		}
+		events = append(events, TransactionEvent{Event: EventTransactionCreated, UserID: userID, Transaction: entity.Transaction{
+			ID:          newTransactionID(),
+			UserID:      userID,
+			Date:        date,
+			Amount:      part.amount,
+			Category:    part.category,
+			Description: loan.Name,
+			Source:      "MANUAL",
+			Type:        entity.TypeExpense,
+			Payee:       loan.Name,
+			AccountID:   loan.AccountID,
+			ExternalID:  payment.ID + part.suffix,
+		}})
+	}
+	if _, err := sendEvents(context.Background(), s.cache, s.producer, events); err != nil {
+		if delErr := s.repo.DeleteLoanPayment(payment.ID); delErr != nil {
+			log.Printf("Failed to delete loan payment %s after publish failure: %v", payment.ID, delErr)
+		}
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// GetLoanReport 彙總期間內已還本金、利息，以及期末剩餘本金
+func (s *loanService) GetLoanReport(userID, loanID string, from, to time.Time) (*LoanReport, error) {
+	loan, err := s.loan(userID, loanID)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := s.repo.GetLoanPayments(loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LoanReport{
+		Loan:                        *loan,
+		From:                        from.Format(dateLayout),
+		To:                          to.Format(dateLayout),
+		RemainingPrincipal:          loan.Principal,
+		ScheduledRemainingPrincipal: loan.Principal,
+		Payments:                    []entity.LoanPayment{},
+	}
+	periodEnd := endOfDay(to)
+	for _, payment := range payments {
+		if payment.Date.After(periodEnd) {
+			break
+		}
+		report.TotalInterestPaid += payment.Interest
+		report.RemainingPrincipal = payment.RemainingPrincipal
+		if !payment.Date.Before(from) {
+			report.PrincipalPaid += payment.Principal
+			report.InterestPaid += payment.Interest
+			report.Payments = append(report.Payments, payment)
+		}
+	}
+	for _, row := range loan.Schedule() {
+		if row.Date.After(periodEnd) {
+			break
+		}
+		report.ScheduledRemainingPrincipal = row.RemainingPrincipal
+	}
+
+	report.PrincipalPaid = roundAmount(report.PrincipalPaid)
+	report.InterestPaid = roundAmount(report.InterestPaid)
+	report.TotalInterestPaid = roundAmount(report.TotalInterestPaid)
+	return report, nil
+}
+
+// loan 查詢屬於該用戶的貸款
+func (s *loanService) loan(userID, loanID string) (*entity.Loan, error) {
+	loan, err := s.repo.GetLoanByID(loanID)
+	if err != nil {
+		return nil, err
+	}
+	if loan.UserID != userID {
+		return nil, db.ErrLoanNotFound
+	}
+	return loan, nil
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// loanRepo 以記憶體保存貸款與依日期排序的還款紀錄
+type loanRepo struct {
+	db.DBClient
+	loans    map[string]entity.Loan
+	payments []entity.LoanPayment
+}
+
+func (r *loanRepo) GetLoanByID(loanID string) (*entity.Loan, error) {
+	loan, ok := r.loans[loanID]
+	if !ok {
+		return nil, db.ErrLoanNotFound
+	}
+	return &loan, nil
+}
+
+func (r *loanRepo) DeleteLoanPayment(paymentID string) error {
+	for i, payment := range r.payments {
+		if payment.ID == paymentID {
+			r.payments = append(r.payments[:i], r.payments[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *loanRepo) GetLoanPayments(loanID string) ([]entity.LoanPayment, error) {
+	var payments []entity.LoanPayment
+	for _, payment := range r.payments {
+		if payment.LoanID == loanID {
+			payments = append(payments, payment)
+		}
+	}
+	return payments, nil
+}
+
+func (r *loanRepo) SaveLoanPayment(payment entity.LoanPayment) error {
+	r.payments = append(r.payments, payment)
+	return nil
+}
+
+// newLoanRepo 返回一筆 12 期、年利率 6% 的貸款，月利率為 0.5%
+func newLoanRepo() *loanRepo {
+	return &loanRepo{loans: map[string]entity.Loan{
+		"loan1": {
+			ID:         "loan1",
+			UserID:     "user123",
+			Name:       "Car loan",
+			Principal:  120000,
+			AnnualRate: 6,
+			TermMonths: 12,
+			PaymentDay: 10,
+			StartDate:  time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			AccountID:  "bank1",
+		},
+	}}
+}
+
+func TestRecordPayment(t *testing.T) {
+	repo := newLoanRepo()
+	queue := &queueStub{}
+	s := &loanService{repo: repo, cache: &cacheStub{values: map[string]string{}}, producer: queue}
+
+	payment, err := s.RecordPayment("user123", "loan1", time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC), 10000)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 600.0, payment.Interest)
+	assert.Equal(t, 9400.0, payment.Principal)
+	assert.Equal(t, 110600.0, payment.RemainingPrincipal)
+	assert.Len(t, repo.payments, 1)
+
+	// 本金與利息各為一筆支出交易，以一次事務送出，ExternalID 對應還款紀錄
+	assert.Equal(t, 1, queue.batches)
+	if assert.Len(t, queue.messages, 2) {
+		principal, interest := decodeCreated(t, queue.messages[0]), decodeCreated(t, queue.messages[1])
+		assert.Equal(t, payment.ID+"-principal", principal.ExternalID)
+		assert.Equal(t, payment.ID+"-interest", interest.ExternalID)
+		assert.NotEqual(t, principal.ID, interest.ID)
+		assert.Equal(t, entity.CategoryLoanPrincipal, principal.Category)
+		assert.Equal(t, 9400.0, principal.Amount)
+		assert.Equal(t, entity.CategoryLoanInterest, interest.Category)
+		assert.Equal(t, 600.0, interest.Amount)
+		for _, tx := range []entity.Transaction{principal, interest} {
+			assert.Equal(t, "user123", tx.UserID)
+			assert.Equal(t, entity.TypeExpense, tx.Type)
+			assert.Equal(t, "bank1", tx.AccountID)
+			assert.Equal(t, "Car loan", tx.Payee)
+			assert.Equal(t, payment.Date, tx.Date)
+		}
+	}
+
+	// 下一期的利息依上一期的剩餘本金計算
+	payment, err = s.RecordPayment("user123", "loan1", time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), 10000)
+	assert.NoError(t, err)
+	assert.Equal(t, 553.0, payment.Interest)
+	assert.Equal(t, 9447.0, payment.Principal)
+	assert.Equal(t, 101153.0, payment.RemainingPrincipal)
+}
+
+func TestRecordPaymentBelowInterest(t *testing.T) {
+	repo := newLoanRepo()
+	queue := &queueStub{}
+	s := &loanService{repo: repo, cache: &cacheStub{values: map[string]string{}}, producer: queue}
+
+	// 還款不足利息時全數為利息，不產生本金交易
+	payment, err := s.RecordPayment("user123", "loan1", time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC), 400)
+	assert.NoError(t, err)
+	assert.Equal(t, 400.0, payment.Interest)
+	assert.Equal(t, 0.0, payment.Principal)
+	assert.Equal(t, 120000.0, payment.RemainingPrincipal)
+	if assert.Len(t, queue.messages, 1) {
+		assert.Equal(t, entity.CategoryLoanInterest, decodeCreated(t, queue.messages[0]).Category)
+	}
+}
+
+func TestRecordPaymentDeletesPaymentWhenPublishFails(t *testing.T) {
+	repo := newLoanRepo()
+	c := &cacheStub{values: map[string]string{}}
+	s := &loanService{repo: repo, cache: c, producer: &queueStub{err: errors.New("connection closed")}}
+
+	_, err := s.RecordPayment("user123", "loan1", time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC), 10000)
+	assert.Error(t, err)
+	assert.Empty(t, repo.payments)
+	assert.Empty(t, c.values)
+}
+
+// decodeCreated 解析新增交易事件，並確認交易 ID 為 ULID
+func decodeCreated(t *testing.T, message []byte) entity.Transaction {
+	var event TransactionEvent
+	assert.NoError(t, json.Unmarshal(message, &event))
+	assert.Equal(t, EventTransactionCreated, event.Event)
+	assert.NotEmpty(t, event.SubmissionID)
+	_, err := ulid.Parse(event.Transaction.ID)
+	assert.NoError(t, err)
+	return event.Transaction
+}
+
+func TestRecordPaymentErrors(t *testing.T) {
+	repo := newLoanRepo()
+	queue := &queueStub{}
+	s := &loanService{repo: repo, cache: &cacheStub{values: map[string]string{}}, producer: queue}
+	march := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	_, err := s.RecordPayment("user456", "loan1", march, 10000)
+	assert.ErrorIs(t, err, db.ErrLoanNotFound)
+	_, err = s.RecordPayment("user123", "loan1", march, 0)
+	assert.ErrorIs(t, err, ErrInvalidLoan)
+
+	// 超過剩餘本金的部分不計入，還清後不可再還款
+	payment, err := s.RecordPayment("user123", "loan1", march, 200000)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 120000.0, payment.Principal)
+		assert.Equal(t, 120600.0, payment.Amount)
+		assert.Equal(t, 0.0, payment.RemainingPrincipal)
+	}
+	_, err = s.RecordPayment("user123", "loan1", march.AddDate(0, 1, 0), 10000)
+	assert.ErrorIs(t, err, ErrInvalidLoan)
+	assert.Contains(t, err.Error(), "paid off")
+
+	// 利息依前一期剩餘本金計算，不可補記較早的還款
+	repo.payments = nil
+	_, err = s.RecordPayment("user123", "loan1", march, 10000)
+	assert.NoError(t, err)
+	_, err = s.RecordPayment("user123", "loan1", march.AddDate(0, 0, -1), 10000)
+	assert.ErrorIs(t, err, ErrInvalidLoan)
+	assert.Contains(t, err.Error(), "date order")
+	assert.Len(t, repo.payments, 1)
+}
+
+func TestGetLoanReport(t *testing.T) {
+	repo := newLoanRepo()
+	s := &loanService{repo: repo, cache: &cacheStub{values: map[string]string{}}, producer: &queueStub{}}
+	for month := time.February; month <= time.April; month++ {
+		_, err := s.RecordPayment("user123", "loan1", time.Date(2024, month, 10, 0, 0, 0, 0, time.UTC), 10000)
+		assert.NoError(t, err)
+	}
+
+	report, err := s.GetLoanReport("user123", "loan1", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "2024-03-01", report.From)
+	assert.Len(t, report.Payments, 1)
+	assert.Equal(t, 9447.0, report.PrincipalPaid)
+	assert.Equal(t, 553.0, report.InterestPaid)
+	// 累計利息與剩餘本金計算至期末，不包含期末之後的還款
+	assert.Equal(t, 1153.0, report.TotalInterestPaid)
+	assert.Equal(t, 101153.0, report.RemainingPrincipal)
+	assert.Equal(t, repo.loans["loan1"].Schedule()[1].RemainingPrincipal, report.ScheduledRemainingPrincipal)
+
+	_, err = s.GetLoanReport("user456", "loan1", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC))
+	assert.ErrorIs(t, err, db.ErrLoanNotFound)
+}
@@ -2,13 +2,32 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fintrack/internal/cache"
 	"fintrack/internal/db"
 	"fintrack/internal/entity"
+	"fintrack/internal/validation"
 	"log"
 )
 
+// 交易事件類型，新增、修改與刪除共用同一個隊列，依送出順序處理
+const (
+	EventTransactionCreated = "TRANSACTION_CREATED"
+	EventTransactionUpdated = "TRANSACTION_UPDATED"
+	EventTransactionDeleted = "TRANSACTION_DELETED"
+)
+
+// TransactionEvent 表示送往 RabbitMQ 的交易異動，修改與刪除時 Transaction 只需包含 ID
+type TransactionEvent struct {
+	Event        string             `json:"event"`
+	SubmissionID string             `json:"submission_id,omitempty"` // 處理結果記錄於此提交
+	UserID       string             `json:"user_id"`
+	Transaction  entity.Transaction `json:"transaction"`
+	Patch        *TransactionPatch  `json:"patch,omitempty"` // 修改的欄位，消費者處理時套用到當時的交易
+}
+
 type MessageService interface {
 	ProcessTransaction(body []byte) error
 	ValidateTransaction(tx *entity.Transaction) error
@@ -17,23 +36,71 @@ type MessageService interface {
 // MessageService 負責處理交易的業務邏輯
 type messageService struct {
 	dbClient db.DBClient
+	cache    cache.Cache
 }
 
 // NewMessageService 創建並返回 MessageService 實例
-func NewMessageService(dbClient db.DBClient) MessageService {
-	return &messageService{dbClient: dbClient}
+func NewMessageService(dbClient db.DBClient, cache cache.Cache) MessageService {
+	return &messageService{dbClient: dbClient, cache: cache}
 }
 
-// ProcessTransaction 處理 RabbitMQ 消息，解析並執行業務邏輯
+// ProcessTransaction 處理 RabbitMQ 消息，解析並執行業務邏輯。
+// 未帶事件類型的消息為舊版格式，內容即為新增的交易
 func (s *messageService) ProcessTransaction(body []byte) error {
-	var transaction entity.Transaction
-
-	// 解析消息，將 JSON 轉換為交易實體
-	if err := json.Unmarshal(body, &transaction); err != nil {
+	var event TransactionEvent
+	if err := json.Unmarshal(body, &event); err != nil {
 		log.Printf("Failed to unmarshal message: %v", err)
 		return err
 	}
+	if event.Event == "" {
+		event.Event = EventTransactionCreated
+		if err := json.Unmarshal(body, &event.Transaction); err != nil {
+			log.Printf("Failed to unmarshal message: %v", err)
+			return err
+		}
+	}
 
+	var err error
+	var saved *entity.Transaction
+	switch event.Event {
+	case EventTransactionCreated:
+		err = s.createTransaction(event.Transaction)
+	case EventTransactionUpdated:
+		// 沒有 Patch 的舊版修改事件以完整內容取代可編輯欄位
+		patch := event.Patch
+		if patch == nil {
+			full := replacement(event.Transaction)
+			patch = &full
+		}
+		saved, err = s.updateTransaction(event.UserID, event.Transaction.ID, *patch)
+	case EventTransactionDeleted:
+		err = s.deleteTransaction(event.UserID, event.Transaction.ID)
+	default:
+		log.Printf("Unknown transaction event: %s", event.Event)
+		err = errors.New("unknown transaction event " + event.Event)
+	}
+	if event.SubmissionID != "" {
+		if err := completeSubmission(context.Background(), s.cache, event.SubmissionID, saved, err); err != nil {
+			log.Printf("Failed to record submission %s: %v", event.SubmissionID, err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// 交易異動後，該用戶已快取的報表全部失效
+	userID := event.UserID
+	if userID == "" {
+		userID = event.Transaction.UserID
+	}
+	if err := bumpReportVersion(context.Background(), s.cache, userID); err != nil {
+		log.Printf("Failed to invalidate cached reports: %v", err)
+	}
+	return nil
+}
+
+// createTransaction 驗證並保存新增的交易
+func (s *messageService) createTransaction(transaction entity.Transaction) error {
 	// 執行交易數據的驗證邏輯
 	if err := s.ValidateTransaction(&transaction); err != nil {
 		log.Printf("Transaction validation failed: %v", err)
@@ -43,20 +110,146 @@ func (s *messageService) ProcessTransaction(body []byte) error {
 	// 設定對帳狀態
 	transaction.Reconciled = true
 
+	// 信用卡交易歸入對應的帳單週期
+	if err := s.assignStatementCycle(&transaction); err != nil {
+		log.Printf("Failed to assign statement cycle: %v", err)
+		return err
+	}
+
 	// 保存到資料庫
 	if err := s.dbClient.SaveTransaction(transaction); err != nil {
 		log.Printf("Failed to save transaction: %v", err)
 		return err
 	}
 
+	// 補登的交易會改變之後各月底的淨值
+	if err := invalidateNetWorthSnapshots(s.dbClient, transaction.UserID, transaction.Date); err != nil {
+		log.Printf("Failed to invalidate net worth snapshots: %v", err)
+		return err
+	}
+
 	log.Printf("Successfully processed transaction: %v", transaction)
 	return nil
 }
 
-// validateTransaction 驗證交易記錄的正確性
-func (s *messageService) ValidateTransaction(tx *entity.Transaction) error {
-	if tx.Amount <= 0 {
-		return errors.New("invalid transaction amount")
+// updateTransaction 將修改套用到用戶目前的交易並返回保存的內容，日期或帳戶變更時重新判斷信用卡帳單週期。
+// 以處理當時的資料為基礎，先前事件的修改不會被覆蓋
+func (s *messageService) updateTransaction(userID, txID string, patch TransactionPatch) (*entity.Transaction, error) {
+	existing, err := s.ownedTransaction(userID, txID)
+	if err != nil {
+		return nil, err
+	}
+	transaction := patch.apply(*existing)
+	if err := s.ValidateTransaction(&transaction); err != nil {
+		log.Printf("Transaction validation failed: %v", err)
+		return nil, err
+	}
+
+	if !transaction.Date.Equal(existing.Date) || transaction.AccountID != existing.AccountID || transaction.Source != existing.Source {
+		transaction.StatementCycle = ""
+	}
+	if err := s.assignStatementCycle(&transaction); err != nil {
+		log.Printf("Failed to assign statement cycle: %v", err)
+		return nil, err
+	}
+
+	if err := s.dbClient.UpdateTransaction(transaction); err != nil {
+		log.Printf("Failed to update transaction: %v", err)
+		return nil, err
+	}
+
+	// 淨值自修改前後較早的日期起失效
+	from := existing.Date
+	if transaction.Date.Before(from) {
+		from = transaction.Date
+	}
+	if err := invalidateNetWorthSnapshots(s.dbClient, existing.UserID, from); err != nil {
+		log.Printf("Failed to invalidate net worth snapshots: %v", err)
+		return nil, err
+	}
+
+	log.Printf("Successfully updated transaction: %v", transaction)
+	return &transaction, nil
+}
+
+// deleteTransaction 刪除用戶的交易
+func (s *messageService) deleteTransaction(userID, txID string) error {
+	existing, err := s.ownedTransaction(userID, txID)
+	if err != nil {
+		return err
+	}
+	if err := s.dbClient.DeleteTransactionByID(txID); err != nil {
+		log.Printf("Failed to delete transaction: %v", err)
+		return err
+	}
+	if err := invalidateNetWorthSnapshots(s.dbClient, existing.UserID, existing.Date); err != nil {
+		log.Printf("Failed to invalidate net worth snapshots: %v", err)
+		return err
+	}
+
+	log.Printf("Successfully deleted transaction: %s", txID)
+	return nil
+}
+
+// ownedTransaction 查詢屬於該用戶的交易，消費時再次確認以免送出後擁有者已改變
+func (s *messageService) ownedTransaction(userID, txID string) (*entity.Transaction, error) {
+	existing, err := s.dbClient.GetTransactionByID(txID)
+	if err == nil && existing.UserID != userID {
+		err = db.ErrTransactionNotFound
+	}
+	if err != nil {
+		log.Printf("Failed to find transaction %s: %v", txID, err)
+		return nil, err
+	}
+	return existing, nil
+}
+
+// reportVersionKey 返回用戶報表快取版本的鍵，版本號是報表快取鍵的一部分
+func reportVersionKey(userID string) string {
+	return "report:version:" + userID
+}
+
+// bumpReportVersion 遞增用戶的報表快取版本，使既有的報表快取不再被讀取並自然過期
+func bumpReportVersion(ctx context.Context, c cache.Cache, userID string) error {
+	if c == nil {
+		return nil
+	}
+	_, err := c.Incr(ctx, reportVersionKey(userID))
+	return err
+}
+
+// reportVersion 返回用戶目前的報表快取版本，尚未有異動時為 0
+func reportVersion(ctx context.Context, c cache.Cache, userID string) string {
+	version, err := c.Get(ctx, reportVersionKey(userID))
+	if err != nil || version == "" {
+		return "0"
+	}
+	return version
+}
+
+// assignStatementCycle 依信用卡結帳日設定交易所屬的帳單週期
+func (s *messageService) assignStatementCycle(tx *entity.Transaction) error {
+	if tx.Source != "CREDIT_CARD" || tx.AccountID == "" || tx.StatementCycle != "" {
+		return nil
+	}
+
+	account, err := s.dbClient.GetAccountByID(tx.AccountID)
+	if errors.Is(err, db.ErrAccountNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if account.IsLiability() && account.ClosingDay > 0 {
+		tx.StatementCycle = account.StatementClosingFor(tx.Date).Format(dateLayout)
 	}
 	return nil
 }
+
+// ValidateTransaction 以與 HTTP API 相同的規則驗證交易，並要求伺服器產生的 ID
+func (s *messageService) ValidateTransaction(tx *entity.Transaction) error {
+	var v validation.Validator
+	v.Required("ID", "id", tx.ID)
+	validation.Transaction(&v, *tx)
+	return invalidTransaction(&v)
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"fintrack/internal/cache"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fintrack/internal/validation"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// messageRepo 以記憶體保存交易，模擬消費者的寫入
+type messageRepo struct {
+	db.DBClient
+	transactions  map[string]entity.Transaction
+	snapshotsFrom []string
+}
+
+func (r *messageRepo) SaveTransaction(tx entity.Transaction) error {
+	r.transactions[tx.ID] = tx
+	return nil
+}
+
+func (r *messageRepo) GetTransactionByID(txID string) (*entity.Transaction, error) {
+	tx, ok := r.transactions[txID]
+	if !ok {
+		return nil, db.ErrTransactionNotFound
+	}
+	return &tx, nil
+}
+
+func (r *messageRepo) UpdateTransaction(tx entity.Transaction) error {
+	r.transactions[tx.ID] = tx
+	return nil
+}
+
+func (r *messageRepo) DeleteTransactionByID(txID string) error {
+	delete(r.transactions, txID)
+	return nil
+}
+
+func (r *messageRepo) DeleteNetWorthSnapshots(userID, from string) error {
+	r.snapshotsFrom = append(r.snapshotsFrom, from)
+	return nil
+}
+
+func (r *messageRepo) GetUserSettings(userID string) (*entity.UserSettings, error) {
+	settings := entity.DefaultUserSettings(userID)
+	return &settings, nil
+}
+
+// queueStub 記錄送出的消息，err 不為空時模擬送出失敗
+type queueStub struct {
+	messages [][]byte
+	batches  int
+	err      error
+}
+
+func (q *queueStub) SendMessage(body []byte) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.messages = append(q.messages, body)
+	return nil
+}
+
+func (q *queueStub) SendMessages(bodies [][]byte) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.messages = append(q.messages, bodies...)
+	q.batches++
+	return nil
+}
+
+func (q *queueStub) Close() error { return nil }
+
+// cacheStub 以記憶體實現快取，可供消費者與查詢同時使用
+type cacheStub struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (c *cacheStub) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = string(value.([]byte))
+	return nil
+}
+
+func (c *cacheStub) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.values[key]
+	if !ok {
+		return "", cache.ErrNotFound
+	}
+	return value, nil
+}
+
+func (c *cacheStub) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, key)
+	return nil
+}
+
+func (c *cacheStub) Incr(ctx context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, _ := strconv.ParseInt(c.values[key], 10, 64)
+	c.values[key] = strconv.FormatInt(n+1, 10)
+	return n + 1, nil
+}
+
+func (c *cacheStub) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.values[key]; ok {
+		return false, nil
+	}
+	c.values[key] = string(value.([]byte))
+	return true, nil
+}
+
+func (c *cacheStub) Close() error { return nil }
+
+func TestTransactionEvents(t *testing.T) {
+	created := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	repo := &messageRepo{transactions: make(map[string]entity.Transaction)}
+	queue := &queueStub{}
+	cache := &cacheStub{values: make(map[string]string)}
+	s := &transactionService{repo: repo, cache: cache, producer: queue}
+	consumer := NewMessageService(repo, cache)
+
+	// 舊版格式的消息內容即為交易
+	assert.NoError(t, consumer.ProcessTransaction([]byte(`{"ID":"tx1","UserID":"user123","Date":"2024-03-10T12:00:00Z","Amount":100,"Category":"FOOD"}`)))
+	assert.Equal(t, created, repo.transactions["tx1"].Date)
+	assert.True(t, repo.transactions["tx1"].Reconciled)
+	assert.Equal(t, "1", reportVersion(context.Background(), cache, "user123"))
+
+	// 只檢查修改的欄位，擁有者與交易是否存在由消費者檢查
+	amount := -5.0
+	_, err := s.PatchTransaction("user123", "tx1", TransactionPatch{Amount: &amount})
+	assert.ErrorIs(t, err, ErrInvalidTransaction)
+	_, err = s.DeleteTransaction("", "tx1")
+	assert.ErrorIs(t, err, ErrInvalidTransaction)
+	category := "GROCERY"
+	foreign, err := s.PatchTransaction("user456", "tx1", TransactionPatch{Category: &category})
+	assert.NoError(t, err)
+
+	// 兩次修改不同欄位，後者不會覆蓋前者
+	amount, moved := 250.0, created.AddDate(0, -1, 0)
+	updated, err := s.PatchTransaction("user123", "tx1", TransactionPatch{Amount: &amount, Date: &moved})
+	assert.NoError(t, err)
+	assert.Nil(t, updated.Transaction)
+	assert.Equal(t, &amount, updated.Patch.Amount)
+	recategorized, err := s.PatchTransaction("user123", "tx1", TransactionPatch{Category: &category})
+	assert.NoError(t, err)
+	deleted, err := s.DeleteTransaction("user123", "tx1")
+	assert.NoError(t, err)
+	assert.Len(t, queue.messages, 4)
+
+	// 修改與刪除依送出順序處理，淨值自修改前後較早的日期起失效
+	assert.ErrorIs(t, consumer.ProcessTransaction(queue.messages[0]), db.ErrTransactionNotFound)
+	assert.NoError(t, consumer.ProcessTransaction(queue.messages[1]))
+	assert.Equal(t, "2024-02-10", repo.snapshotsFrom[len(repo.snapshotsFrom)-1])
+	assert.NoError(t, consumer.ProcessTransaction(queue.messages[2]))
+	tx := repo.transactions["tx1"]
+	assert.Equal(t, 250.0, tx.Amount)
+	assert.Equal(t, moved, tx.Date)
+	assert.Equal(t, "GROCERY", tx.Category)
+	assert.Equal(t, "user123", tx.UserID)
+
+	submission, err := s.GetSubmission(context.Background(), "user123", recategorized.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, SubmissionSaved, submission.Status)
+	assert.Equal(t, &tx, submission.Transaction)
+	submission, err = s.GetSubmission(context.Background(), "user456", foreign.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, SubmissionRejected, submission.Status)
+	assert.Equal(t, validation.CodeNotFound, submission.Code)
+
+	assert.NoError(t, consumer.ProcessTransaction(queue.messages[3]))
+	assert.Empty(t, repo.transactions)
+	assert.Equal(t, "4", reportVersion(context.Background(), cache, "user123"))
+
+	// 刪除後再修改的事件找不到交易
+	assert.ErrorIs(t, consumer.ProcessTransaction(queue.messages[1]), db.ErrTransactionNotFound)
+
+	submission, err = s.GetSubmission(context.Background(), "user123", deleted.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, SubmissionSaved, submission.Status)
+	submission, err = s.GetSubmission(context.Background(), "user123", updated.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, SubmissionRejected, submission.Status)
+	assert.Equal(t, "transaction not found", submission.Reason)
+	_, err = s.GetSubmission(context.Background(), "user456", updated.ID, false)
+	assert.ErrorIs(t, err, ErrSubmissionNotFound)
+}
+
+func TestEditQueuedTransaction(t *testing.T) {
+	repo := &messageRepo{transactions: make(map[string]entity.Transaction)}
+	queue := &queueStub{}
+	cache := &cacheStub{values: make(map[string]string)}
+	s := &transactionService{repo: repo, cache: cache, producer: queue}
+	consumer := NewMessageService(repo, cache)
+
+	// 新增尚未寫入資料庫時即可修改，修改排在新增之後處理
+	date := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	created, err := s.AddTransaction(entity.Transaction{UserID: "user123", Date: date, Amount: 100, Category: "FOOD"})
+	assert.NoError(t, err)
+	_, err = s.UpdateTransaction("user123", created.TransactionID, entity.Transaction{Date: date, Amount: 120, Payee: "Cafe"})
+	assert.NoError(t, err)
+	for _, message := range queue.messages {
+		assert.NoError(t, consumer.ProcessTransaction(message))
+	}
+	tx := repo.transactions[created.TransactionID]
+	assert.Equal(t, 120.0, tx.Amount)
+	assert.Equal(t, "Cafe", tx.Payee)
+	assert.Empty(t, tx.Category) // PUT 清除未提供的欄位
+
+	// 沒有 patch 的舊版修改事件以事件中的交易取代可編輯欄位
+	legacy := `{"event":"TRANSACTION_UPDATED","user_id":"user123","transaction":{"ID":"` + created.TransactionID + `","Date":"2024-03-11T00:00:00Z","Amount":80,"Category":"FOOD"}}`
+	assert.NoError(t, consumer.ProcessTransaction([]byte(legacy)))
+	tx = repo.transactions[created.TransactionID]
+	assert.Equal(t, 80.0, tx.Amount)
+	assert.Equal(t, "FOOD", tx.Category)
+	assert.Equal(t, "user123", tx.UserID)
+}
+
+func TestAddTransactionGeneratesID(t *testing.T) {
+	repo := &messageRepo{transactions: make(map[string]entity.Transaction)}
+	cache := &cacheStub{values: make(map[string]string)}
+	queue := &queueStub{}
+	s := &transactionService{repo: repo, cache: cache, producer: queue}
+	consumer := NewMessageService(repo, cache)
+
+	// 客戶端的 ID 只保留為外部參照，兩位用戶使用相同 ID 不會互相覆蓋
+	first, err := s.AddTransaction(entity.Transaction{ID: "1", UserID: "user123", Date: time.Now(), Amount: 100})
+	assert.NoError(t, err)
+	second, err := s.AddTransaction(entity.Transaction{ID: "1", UserID: "user456", Date: time.Now(), Amount: 200})
+	assert.NoError(t, err)
+	assert.Len(t, first.TransactionID, 26)
+	assert.Less(t, first.TransactionID, second.TransactionID)
+	assert.Equal(t, "1", first.Transaction.ExternalID)
+
+	for _, message := range queue.messages {
+		assert.NoError(t, consumer.ProcessTransaction(message))
+	}
+	assert.Len(t, repo.transactions, 2)
+	assert.Equal(t, "user123", repo.transactions[first.TransactionID].UserID)
+
+	// 未提供 ID 也會產生
+	third, err := s.AddTransaction(entity.Transaction{UserID: "user123", Date: time.Now(), Amount: 50})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, third.TransactionID)
+	assert.Empty(t, third.Transaction.ExternalID)
+
+	// 沒有 ID 的舊版消息被拒絕
+	assert.ErrorIs(t, consumer.ProcessTransaction([]byte(`{"UserID":"user123","Amount":10}`)), ErrInvalidTransaction)
+}
+
+func TestGetSubmissionWait(t *testing.T) {
+	repo := &messageRepo{transactions: make(map[string]entity.Transaction)}
+	cache := &cacheStub{values: make(map[string]string)}
+	queue := &queueStub{}
+	s := &transactionService{repo: repo, cache: cache, producer: queue}
+	consumer := NewMessageService(repo, cache)
+
+	// 新增時已會拒絕，直接送出事件以驗證消費者的檢查
+	_, err := s.AddTransaction(entity.Transaction{ID: "tx1", UserID: "user123", Date: time.Now(), Amount: -10})
+	assert.ErrorIs(t, err, ErrInvalidTransaction)
+	submission, err := s.sendEvent(TransactionEvent{Event: EventTransactionCreated, UserID: "user123", Transaction: entity.Transaction{ID: "tx1", UserID: "user123", Date: time.Now(), Amount: -10}})
+	assert.NoError(t, err)
+	assert.Equal(t, SubmissionPending, submission.Status)
+
+	// 消費者稍後才處理，wait 等待至處理完成
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		time.Sleep(2 * submissionPollInterval)
+		_ = consumer.ProcessTransaction(queue.messages[0])
+	}()
+	result, err := s.GetSubmission(context.Background(), "user123", submission.ID, true)
+	<-done
+	assert.NoError(t, err)
+	assert.Equal(t, SubmissionRejected, result.Status)
+	assert.Equal(t, "invalid transaction: amount must be greater than zero", result.Reason)
+	assert.Equal(t, validation.CodeValidationFailed, result.Code)
+	assert.Equal(t, []validation.FieldError{{Code: validation.CodeMustBePositive, Message: "amount must be greater than zero", Field: "Amount", Value: -10.0}}, result.Details)
+	assert.Empty(t, repo.transactions)
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	defaultNetWorthMonths = 12  // 未指定範圍時回溯的月數（含本月）
+	maxNetWorthMonths     = 120 // 單次查詢的最大月數
+)
+
+// 淨值報表中的項目類型，帳戶沿用帳戶類型
+const (
+	NetWorthItemHoldings = "HOLDINGS" // 投資帳戶的持股市值
+	NetWorthItemLoan     = "LOAN"
+)
+
+// NetWorthReport 表示各月底的資產、負債與淨值
+type NetWorthReport struct {
+	User       string          `json:"user"`
+	ReportType string          `json:"report_type"`
+	Months     []NetWorthMonth `json:"months"`
+}
+
+// NetWorthMonth 表示某月底的淨值，本月以今日計算
+type NetWorthMonth struct {
+	Month       string         `json:"month"` // YYYY-MM
+	AsOf        string         `json:"as_of"`
+	Assets      float64        `json:"assets"`
+	Liabilities float64        `json:"liabilities"`
+	NetWorth    float64        `json:"net_worth"`
+	Items       []NetWorthItem `json:"items"`
+}
+
+// NetWorthItem 表示單一帳戶、持股或貸款的餘額，資產為正、負債為負
+type NetWorthItem struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Liability bool    `json:"liability"`
+	Balance   float64 `json:"balance"`
+}
+
+// generateNetWorth 計算期間內各月底的淨值
+// 已結束月份的帳戶餘額與持股市值會存為快照，之後只需從最近的快照累加新增的交易
+func (s *transactionService) generateNetWorth(userID, startDate, endDate string) (*NetWorthReport, error) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	first, last, err := netWorthRange(startDate, endDate, today)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.repo.GetAccounts(userID)
+	if err != nil {
+		return nil, err
+	}
+	loans, err := s.repo.GetLoans(userID)
+	if err != nil {
+		return nil, err
+	}
+	payments := make(map[string][]entity.LoanPayment, len(loans))
+	for _, loan := range loans {
+		if payments[loan.ID], err = s.repo.GetLoanPayments(loan.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	stored, err := s.repo.GetNetWorthSnapshots(userID, monthEnd(first).Format(dateLayout), monthEnd(last).Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make(map[string][]entity.NetWorthSnapshot)
+	for _, snapshot := range stored {
+		key := snapshot.Month.Format(dateLayout)
+		snapshots[key] = append(snapshots[key], snapshot)
+	}
+
+	// running 為截至 runningBefore（不含）的各帳戶交易淨額，缺少快照時以此為基礎累加
+	var running map[string]float64
+	var runningBefore time.Time
+
+	report := &NetWorthReport{User: userID, ReportType: ReportTypeNetWorth}
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		asOf := monthEnd(month)
+		if asOf.After(today) {
+			asOf = today
+		}
+		key := asOf.Format(dateLayout)
+		before := asOf.AddDate(0, 0, 1)
+
+		rows, ok := snapshots[key]
+		if !ok {
+			if running == nil {
+				if running, runningBefore, err = s.netWorthBase(userID, asOf); err != nil {
+					return nil, err
+				}
+			}
+			activity, err := s.repo.GetAccountActivity(userID, runningBefore.Format(dateLayout), before.Format(dateLayout))
+			if err != nil {
+				return nil, err
+			}
+			for accountID, amount := range activity {
+				running[accountID] += amount
+			}
+			runningBefore = before
+
+			portfolio, err := valuePortfolio(s.repo, userID, asOf, CostMethodFIFO)
+			if err != nil {
+				return nil, err
+			}
+			rows = snapshotRows(userID, asOf, running, portfolio)
+			// 只保存已結束月份的快照，本月的餘額仍會變動
+			if asOf.Before(today) {
+				if err := s.repo.SaveNetWorthSnapshots(rows); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			running = make(map[string]float64, len(rows))
+			for _, row := range rows {
+				running[row.AccountID] = row.Balance
+			}
+			runningBefore = before
+		}
+
+		report.Months = append(report.Months, netWorthMonth(month, asOf, rows, accounts, loans, payments))
+	}
+	return report, nil
+}
+
+// netWorthBase 以指定日期之前最近的快照作為累加起點，沒有快照時從頭彙總所有交易
+func (s *transactionService) netWorthBase(userID string, asOf time.Time) (map[string]float64, time.Time, error) {
+	latest, err := s.repo.GetLatestNetWorthSnapshot(userID, asOf.Format(dateLayout))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	balances := make(map[string]float64, len(latest))
+	if len(latest) > 0 {
+		for _, row := range latest {
+			balances[row.AccountID] = row.Balance
+		}
+		return balances, latest[0].Month.AddDate(0, 0, 1), nil
+	}
+	// 零值日期讓第一次累加涵蓋所有歷史交易
+	return balances, time.Time{}, nil
+}
+
+// snapshotRows 將各帳戶交易淨額與持股市值轉為快照，未指定帳戶的列一律保存，作為該月已有快照的標記
+func snapshotRows(userID string, asOf time.Time, balances map[string]float64, portfolio *PortfolioReport) []entity.NetWorthSnapshot {
+	rows := map[string]*entity.NetWorthSnapshot{"": {}}
+	rowFor := func(accountID string) *entity.NetWorthSnapshot {
+		if rows[accountID] == nil {
+			rows[accountID] = &entity.NetWorthSnapshot{}
+		}
+		return rows[accountID]
+	}
+	for accountID, balance := range balances {
+		rowFor(accountID).Balance = roundAmount(balance)
+	}
+	for _, holding := range portfolio.Holdings {
+		rowFor(holding.AccountID).Holdings += holding.MarketValue
+	}
+
+	now := time.Now()
+	result := make([]entity.NetWorthSnapshot, 0, len(rows))
+	for accountID, row := range rows {
+		row.UserID = userID
+		row.Month = asOf
+		row.AccountID = accountID
+		row.Holdings = roundAmount(row.Holdings)
+		row.CreatedAt = now
+		result = append(result, *row)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].AccountID < result[j].AccountID })
+	return result
+}
+
+// netWorthMonth 結合快照、帳戶期初餘額與貸款剩餘本金計算某月底的淨值
+func netWorthMonth(month, asOf time.Time, rows []entity.NetWorthSnapshot, accounts []entity.Account, loans []entity.Loan, payments map[string][]entity.LoanPayment) NetWorthMonth {
+	entry := NetWorthMonth{Month: month.Format("2006-01"), AsOf: asOf.Format(dateLayout), Items: []NetWorthItem{}}
+	add := func(item NetWorthItem) {
+		item.Balance = roundAmount(item.Balance)
+		if item.Liability {
+			entry.Liabilities -= item.Balance
+		} else {
+			entry.Assets += item.Balance
+		}
+		entry.Items = append(entry.Items, item)
+	}
+
+	byAccount := make(map[string]entity.NetWorthSnapshot, len(rows))
+	for _, row := range rows {
+		byAccount[row.AccountID] = row
+	}
+	for _, account := range accounts {
+		row := byAccount[account.ID]
+		delete(byAccount, account.ID)
+		add(NetWorthItem{ID: account.ID, Name: account.Name, Type: account.Type, Liability: account.IsLiability(), Balance: account.OpeningBalance + row.Balance})
+		if row.Holdings != 0 {
+			add(NetWorthItem{ID: account.ID, Name: account.Name, Type: NetWorthItemHoldings, Balance: row.Holdings})
+		}
+	}
+	// 未指定帳戶或帳戶已不存在的交易彙總為一項
+	var unassigned float64
+	for _, row := range byAccount {
+		unassigned += row.Balance + row.Holdings
+	}
+	if roundAmount(unassigned) != 0 {
+		add(NetWorthItem{ID: UnassignedAccount, Name: UnassignedAccount, Type: UnassignedAccount, Balance: unassigned})
+	}
+
+	for _, loan := range loans {
+		if remaining := remainingPrincipalAt(loan, payments[loan.ID], asOf); remaining > 0 {
+			add(NetWorthItem{ID: loan.ID, Name: loan.Name, Type: NetWorthItemLoan, Liability: true, Balance: -remaining})
+		}
+	}
+
+	entry.Assets = roundAmount(entry.Assets)
+	entry.Liabilities = roundAmount(entry.Liabilities)
+	entry.NetWorth = roundAmount(entry.Assets - entry.Liabilities)
+	return entry
+}
+
+// remainingPrincipalAt 返回貸款在指定日期結束時的剩餘本金，尚未撥款時為 0
+func remainingPrincipalAt(loan entity.Loan, payments []entity.LoanPayment, at time.Time) float64 {
+	end := endOfDay(at)
+	if loan.StartDate.After(end) {
+		return 0
+	}
+	remaining := loan.Principal
+	for _, payment := range payments {
+		if payment.Date.After(end) {
+			break
+		}
+		remaining = payment.RemainingPrincipal
+	}
+	return remaining
+}
+
+// netWorthRange 解析淨值報表的月份範圍，未指定時預設為本月及之前 11 個月
+func netWorthRange(startDate, endDate string, today time.Time) (time.Time, time.Time, error) {
+	currentMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	last := currentMonth
+	if endDate != "" {
+		end, err := time.Parse(dateLayout, endDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: end_date must be in YYYY-MM-DD format", ErrInvalidReportRequest)
+		}
+		last = time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	first := last.AddDate(0, -(defaultNetWorthMonths - 1), 0)
+	if startDate != "" {
+		start, err := time.Parse(dateLayout, startDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: start_date must be in YYYY-MM-DD format", ErrInvalidReportRequest)
+		}
+		first = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	if last.Before(first) || last.After(currentMonth) || first.AddDate(0, maxNetWorthMonths-1, 0).Before(last) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: net worth range must end after it starts, not after the current month and span at most %d months", ErrInvalidReportRequest, maxNetWorthMonths)
+	}
+	return first, last, nil
+}
+
+// monthEnd 返回該月最後一天
+func monthEnd(month time.Time) time.Time {
+	return time.Date(month.Year(), month.Month()+1, 0, 0, 0, 0, 0, month.Location())
+}
+
+// invalidateNetWorthSnapshots 補登本月之前的資料時，刪除該日期之後已保存的月底快照
+func invalidateNetWorthSnapshots(repo db.DBClient, userID string, date time.Time) error {
+	now := time.Now()
+	if !date.Before(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, date.Location())) {
+		return nil
+	}
+	return repo.DeleteNetWorthSnapshots(userID, date.Format(dateLayout))
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fintrack/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// netWorthRepo 在 stubRepo 之上以記憶體保存快照，並記錄彙總交易的次數
+type netWorthRepo struct {
+	*stubRepo
+	loans         []entity.Loan
+	payments      map[string][]entity.LoanPayment
+	snapshots     []entity.NetWorthSnapshot
+	activityCalls int
+}
+
+func (r *netWorthRepo) GetAccountActivity(userID, from, before string) (map[string]float64, error) {
+	r.activityCalls++
+	balances := make(map[string]float64)
+	for _, tx := range r.transactions {
+		if date := tx.Date.Format(dateLayout); date >= from && date < before {
+			balances[tx.AccountID] += tx.SignedAmount()
+		}
+	}
+	return balances, nil
+}
+
+func (r *netWorthRepo) GetLoans(userID string) ([]entity.Loan, error) {
+	return r.loans, nil
+}
+
+func (r *netWorthRepo) GetLoanPayments(loanID string) ([]entity.LoanPayment, error) {
+	return r.payments[loanID], nil
+}
+
+func (r *netWorthRepo) GetTrades(userID, before string) ([]entity.Trade, error) {
+	return nil, nil
+}
+
+func (r *netWorthRepo) GetLatestPrices(symbols []string, asOf string) (map[string]entity.SecurityPrice, error) {
+	return nil, nil
+}
+
+func (r *netWorthRepo) SaveNetWorthSnapshots(snapshots []entity.NetWorthSnapshot) error {
+	r.snapshots = append(r.snapshots, snapshots...)
+	return nil
+}
+
+func (r *netWorthRepo) GetNetWorthSnapshots(userID, from, to string) ([]entity.NetWorthSnapshot, error) {
+	var result []entity.NetWorthSnapshot
+	for _, snapshot := range r.snapshots {
+		if month := snapshot.Month.Format(dateLayout); month >= from && month <= to {
+			result = append(result, snapshot)
+		}
+	}
+	return result, nil
+}
+
+func (r *netWorthRepo) GetLatestNetWorthSnapshot(userID, before string) ([]entity.NetWorthSnapshot, error) {
+	var latest string
+	for _, snapshot := range r.snapshots {
+		if month := snapshot.Month.Format(dateLayout); month < before && month > latest {
+			latest = month
+		}
+	}
+	return r.GetNetWorthSnapshots(userID, latest, latest)
+}
+
+func (r *netWorthRepo) DeleteNetWorthSnapshots(userID, from string) error {
+	var kept []entity.NetWorthSnapshot
+	for _, snapshot := range r.snapshots {
+		if snapshot.Month.Format(dateLayout) < from {
+			kept = append(kept, snapshot)
+		}
+	}
+	r.snapshots = kept
+	return nil
+}
+
+// netWorthWriteRepo 另外保存新增的帳戶與貸款，供快取失效的測試使用
+type netWorthWriteRepo struct {
+	*netWorthRepo
+}
+
+func (r *netWorthWriteRepo) GetUserSettings(userID string) (*entity.UserSettings, error) {
+	settings := entity.DefaultUserSettings(userID)
+	return &settings, nil
+}
+
+func (r *netWorthWriteRepo) SaveAccount(account entity.Account) error {
+	r.accounts = append(r.accounts, account)
+	return nil
+}
+
+func (r *netWorthWriteRepo) SaveLoan(loan entity.Loan) error {
+	r.loans = append(r.loans, loan)
+	return nil
+}
+
+func TestNetWorthIncludesNewAccountsAndLoans(t *testing.T) {
+	now := time.Now().UTC()
+	repo := &netWorthWriteRepo{&netWorthRepo{stubRepo: &stubRepo{
+		accounts: []entity.Account{{ID: "bank1", UserID: "user123", Name: "Bank", Type: entity.AccountTypeBank, OpeningBalance: 1000}},
+	}}}
+	c := &cacheStub{values: map[string]string{}}
+	reports := &transactionService{repo: repo, cache: c}
+
+	// 產生報表並返回本月的淨值，第二次起可能命中快取
+	netWorth := func() float64 {
+		generated, err := reports.GenerateReport(context.Background(), "user123", ReportTypeNetWorth, "", "")
+		assert.NoError(t, err)
+		data, err := json.Marshal(generated)
+		assert.NoError(t, err)
+		var report NetWorthReport
+		assert.NoError(t, json.Unmarshal(data, &report))
+		return report.Months[len(report.Months)-1].NetWorth
+	}
+	assert.Equal(t, 1000.0, netWorth())
+
+	loans := &loanService{repo: repo, cache: c}
+	_, err := loans.CreateLoan(entity.Loan{UserID: "user123", Name: "Car loan", Principal: 10000, TermMonths: 12, PaymentDay: 10, StartDate: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)})
+	assert.NoError(t, err)
+	assert.Equal(t, -9000.0, netWorth())
+
+	accounts := &accountService{repo: repo, cache: c}
+	_, err = accounts.CreateAccount(entity.Account{UserID: "user123", Name: "Cash", Type: entity.AccountTypeCash, OpeningBalance: 500})
+	assert.NoError(t, err)
+	assert.Equal(t, -8500.0, netWorth())
+}
+
+func TestGenerateNetWorth(t *testing.T) {
+	now := time.Now().UTC()
+	currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	twoMonthsAgo := currentMonth.AddDate(0, -2, 0)
+	lastMonth := currentMonth.AddDate(0, -1, 0)
+
+	repo := &netWorthRepo{
+		stubRepo: &stubRepo{
+			accounts: []entity.Account{
+				{ID: "bank1", Name: "Bank", Type: entity.AccountTypeBank, OpeningBalance: 1000},
+				{ID: "card1", Name: "Card", Type: entity.AccountTypeCreditCard},
+			},
+			transactions: []entity.Transaction{
+				{UserID: "user123", Date: twoMonthsAgo.AddDate(0, 0, 4), Amount: 5000, Type: entity.TypeIncome, AccountID: "bank1"},
+				{UserID: "user123", Date: lastMonth.AddDate(0, 0, 9), Amount: 300, Type: entity.TypeExpense, AccountID: "card1"},
+			},
+		},
+		loans: []entity.Loan{{ID: "loan1", Name: "Mortgage", Principal: 10000, StartDate: twoMonthsAgo.AddDate(0, -1, 0)}},
+		payments: map[string][]entity.LoanPayment{
+			"loan1": {{LoanID: "loan1", Date: lastMonth.AddDate(0, 0, 14), RemainingPrincipal: 9000}},
+		},
+	}
+	s := &transactionService{repo: repo}
+	start := twoMonthsAgo.Format(dateLayout)
+
+	report, err := s.generateNetWorth("user123", start, "")
+	assert.NoError(t, err)
+	assert.Len(t, report.Months, 3)
+	assert.Equal(t, 3, repo.activityCalls)
+
+	first := report.Months[0]
+	assert.Equal(t, monthEnd(twoMonthsAgo).Format(dateLayout), first.AsOf)
+	assert.Equal(t, 6000.0, first.Assets)
+	assert.Equal(t, 10000.0, first.Liabilities)
+	assert.Equal(t, -4000.0, first.NetWorth)
+
+	second := report.Months[1]
+	assert.Equal(t, 6000.0, second.Assets)
+	assert.Equal(t, 9300.0, second.Liabilities)
+	assert.Equal(t, -3300.0, second.NetWorth)
+
+	// 只保存已結束月份的快照，再次查詢時僅需彙總本月的交易
+	assert.Len(t, repo.snapshots, 5)
+	repo.activityCalls = 0
+	again, err := s.generateNetWorth("user123", start, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, repo.activityCalls)
+	assert.Equal(t, report.Months, again.Months)
+
+	// 補登上個月的交易後，該月起的快照需重新計算
+	backdated := entity.Transaction{UserID: "user123", Date: lastMonth.AddDate(0, 0, 1), Amount: 200, Type: entity.TypeExpense, AccountID: "bank1"}
+	repo.transactions = append(repo.transactions, backdated)
+	assert.NoError(t, invalidateNetWorthSnapshots(repo, "user123", backdated.Date))
+	assert.Len(t, repo.snapshots, 2)
+
+	updated, err := s.generateNetWorth("user123", start, "")
+	assert.NoError(t, err)
+	assert.Equal(t, -4000.0, updated.Months[0].NetWorth)
+	assert.Equal(t, -3500.0, updated.Months[1].NetWorth)
+}
+
+func TestNetWorthRange(t *testing.T) {
+	today := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	first, last, err := netWorthRange("", "", today)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC), first)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), last)
+
+	_, _, err = netWorthRange("2024-01-01", "2024-07-31", today)
+	assert.ErrorIs(t, err, ErrInvalidReportRequest)
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fintrack/internal/export"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidArchive 表示匯入的封存檔格式不正確或內容與清單不符
+var (
+	ErrInvalidArchive  = errors.New("invalid archive")
+	ErrArchiveConflict = errors.New("archive conflicts with another user's data")
+)
+
+// 用戶資料封存檔的格式
+const (
+	ArchiveFormat        = "fintrack-user-archive"
+	ArchiveFormatVersion = 1
+	archiveManifest      = "manifest.json"
+	maxArchiveFileSize   = 512 << 20 // 單一檔案解壓縮後的上限，避免壓縮炸彈
+)
+
+// 封存檔內的資料檔，匯入時依此讀取；CSV 檔僅供閱讀，匯入時忽略
+const (
+	ArchiveTransactions       = "transactions.json"
+	ArchiveAccounts           = "accounts.json"
+	ArchiveRecurringSchedules = "recurring_schedules.json"
+	ArchiveInstallmentPlans   = "installment_plans.json"
+	ArchiveLoans              = "loans.json"
+	ArchiveLoanPayments       = "loan_payments.json"
+	ArchiveTrades             = "trades.json"
+	ArchiveSecurities         = "securities.json"
+	ArchiveSecurityPrices     = "security_prices.json"
+	ArchiveTaxMappings        = "tax_mappings.json"
+	ArchiveUserSettings       = "user_settings.json"
+	ArchiveTransactionsCSV    = "transactions.csv"
+	ArchiveCategoriesCSV      = "categories.csv"
+)
+
+// ArchiveManifest 描述封存檔的來源與各資料檔的筆數
+type ArchiveManifest struct {
+	Format        string         `json:"format"`
+	FormatVersion int            `json:"format_version"`
+	UserID        string         `json:"user_id"`
+	ExportedAt    time.Time      `json:"exported_at"`
+	Files         map[string]int `json:"files"` // 資料檔名與紀錄筆數
+}
+
+// ImportSummary 表示匯入結果
+type ImportSummary struct {
+	SourceUserID  string         `json:"source_user_id"`
+	UserID        string         `json:"user_id"`
+	FormatVersion int            `json:"format_version"`
+	RemappedIDs   bool           `json:"remapped_ids"` // 匯入至不同用戶時重新產生所有 ID
+	Files         map[string]int `json:"files"`
+}
+
+type PortabilityService interface {
+	ExportUser(userID string, w io.Writer) (*ArchiveManifest, error)
+	ImportUser(r io.ReaderAt, size int64, userID string) (*ImportSummary, error)
+}
+
+type portabilityService struct {
+	repo db.DBClient
+}
+
+func NewPortabilityService(repo db.DBClient) PortabilityService {
+	return &portabilityService{repo: repo}
+}
+
+// archiveFile 將資料檔名對應至 UserData 的欄位
+type archiveFile struct {
+	name string
+	rows interface{} // 指向 UserData 欄位的指標
+	n    func() int
+}
+
+func archiveFiles(data *db.UserData) []archiveFile {
+	return []archiveFile{
+		{ArchiveTransactions, &data.Transactions, func() int { return len(data.Transactions) }},
+		{ArchiveAccounts, &data.Accounts, func() int { return len(data.Accounts) }},
+		{ArchiveRecurringSchedules, &data.RecurringSchedules, func() int { return len(data.RecurringSchedules) }},
+		{ArchiveInstallmentPlans, &data.InstallmentPlans, func() int { return len(data.InstallmentPlans) }},
+		{ArchiveLoans, &data.Loans, func() int { return len(data.Loans) }},
+		{ArchiveLoanPayments, &data.LoanPayments, func() int { return len(data.LoanPayments) }},
+		{ArchiveTrades, &data.Trades, func() int { return len(data.Trades) }},
+		{ArchiveSecurities, &data.Securities, func() int { return len(data.Securities) }},
+		{ArchiveSecurityPrices, &data.SecurityPrices, func() int { return len(data.SecurityPrices) }},
+		{ArchiveTaxMappings, &data.TaxMappings, func() int { return len(data.TaxMappings) }},
+		{ArchiveUserSettings, &data.UserSettings, func() int { return len(data.UserSettings) }},
+	}
+}
+
+// ExportUser 將用戶的所有資料寫成 zip 封存檔，包含清單、各資料表的 JSON 檔與方便閱讀的 CSV 檔
+func (s *portabilityService) ExportUser(userID string, w io.Writer) (*ArchiveManifest, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("%w: user_id is required", ErrInvalidArchive)
+	}
+	data, err := s.repo.GetUserData(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &ArchiveManifest{
+		Format:        ArchiveFormat,
+		FormatVersion: ArchiveFormatVersion,
+		UserID:        userID,
+		ExportedAt:    time.Now().UTC(),
+		Files:         make(map[string]int),
+	}
+	zw := zip.NewWriter(w)
+	for _, file := range archiveFiles(data) {
+		manifest.Files[file.name] = file.n()
+		if err := writeArchiveJSON(zw, file.name, file.rows); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeArchiveJSON(zw, archiveManifest, manifest); err != nil {
+		return nil, err
+	}
+
+	f, err := zw.Create(ArchiveTransactionsCSV)
+	if err != nil {
+		return nil, err
+	}
+	if err := export.WriteCSV(f, &export.Document{Tables: []export.Table{transactionTable(data.Transactions)}}); err != nil {
+		return nil, err
+	}
+	if f, err = zw.Create(ArchiveCategoriesCSV); err != nil {
+		return nil, err
+	}
+	if err := writeCategories(f, data.Transactions); err != nil {
+		return nil, err
+	}
+	return manifest, zw.Close()
+}
+
+func writeArchiveJSON(zw *zip.Writer, name string, v interface{}) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeCategories 輸出用戶使用過的分類與筆數；分類為交易上的文字欄位，沒有獨立的資料表
+func writeCategories(w io.Writer, transactions []entity.Transaction) error {
+	type key struct{ category, txType string }
+	counts := make(map[key]int)
+	for _, tx := range transactions {
+		txType := entity.TypeExpense
+		if tx.IsIncome() {
+			txType = entity.TypeIncome
+		}
+		counts[key{tx.Category, txType}]++
+	}
+	keys := make([]key, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].txType != keys[j].txType {
+			return keys[i].txType < keys[j].txType
+		}
+		return keys[i].category < keys[j].category
+	})
+
+	table := export.Table{Header: []string{"Category", "Type", "Transactions"}}
+	for _, k := range keys {
+		table.Rows = append(table.Rows, []interface{}{k.category, k.txType, counts[k]})
+	}
+	return export.WriteCSV(w, &export.Document{Tables: []export.Table{table}})
+}
+
+// ImportUser 讀取封存檔並寫入指定用戶，所有資料在同一個資料庫交易中寫入。
+// 匯入至原用戶時保留原 ID，重複匯入會覆蓋相同紀錄；匯入至其他用戶時重新產生 ID，避免覆蓋原用戶的資料。
+// 保留的 ID 已屬於其他用戶時拒絕匯入
+func (s *portabilityService) ImportUser(r io.ReaderAt, size int64, userID string) (*ImportSummary, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("%w: user_id is required", ErrInvalidArchive)
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var manifest ArchiveManifest
+	if err := readArchiveJSON(files, archiveManifest, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Format != ArchiveFormat || manifest.FormatVersion < 1 || manifest.FormatVersion > ArchiveFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format %q version %d", ErrInvalidArchive, manifest.Format, manifest.FormatVersion)
+	}
+
+	data := &db.UserData{}
+	for _, file := range archiveFiles(data) {
+		expected, listed := manifest.Files[file.name]
+		if !listed {
+			continue
+		}
+		if err := readArchiveJSON(files, file.name, file.rows); err != nil {
+			return nil, err
+		}
+		if file.n() != expected {
+			return nil, fmt.Errorf("%w: %s has %d records, manifest lists %d", ErrInvalidArchive, file.name, file.n(), expected)
+		}
+	}
+
+	remap := manifest.UserID != userID
+	if remap {
+		remapUserData(data)
+	}
+	assignUser(data, userID)
+	if err := s.repo.RestoreUserData(userID, data); err != nil {
+		if errors.Is(err, db.ErrForeignRecord) {
+			return nil, fmt.Errorf("%w: %v", ErrArchiveConflict, err)
+		}
+		return nil, err
+	}
+	// 匯入的交易可能早於既有的月底快照，淨值需重新計算
+	if err := s.repo.DeleteNetWorthSnapshots(userID, "0001-01-01"); err != nil {
+		return nil, err
+	}
+
+	summary := &ImportSummary{
+		SourceUserID:  manifest.UserID,
+		UserID:        userID,
+		FormatVersion: manifest.FormatVersion,
+		RemappedIDs:   remap,
+		Files:         make(map[string]int),
+	}
+	for _, file := range archiveFiles(data) {
+		summary.Files[file.name] = file.n()
+	}
+	return summary, nil
+}
+
+func readArchiveJSON(files map[string]*zip.File, name string, v interface{}) error {
+	f := files[name]
+	if f == nil {
+		return fmt.Errorf("%w: missing %s", ErrInvalidArchive, name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidArchive, name, err)
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(io.LimitReader(rc, maxArchiveFileSize)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidArchive, name, err)
+	}
+	return nil
+}
+
+// assignUser 將所有紀錄歸屬至匯入的用戶
+func assignUser(data *db.UserData, userID string) {
+	for i := range data.Transactions {
+		data.Transactions[i].UserID = userID
+	}
+	for i := range data.Accounts {
+		data.Accounts[i].UserID = userID
+	}
+	for i := range data.RecurringSchedules {
+		data.RecurringSchedules[i].UserID = userID
+	}
+	for i := range data.InstallmentPlans {
+		data.InstallmentPlans[i].UserID = userID
+	}
+	for i := range data.Loans {
+		data.Loans[i].UserID = userID
+	}
+	for i := range data.LoanPayments {
+		data.LoanPayments[i].UserID = userID
+	}
+	for i := range data.Trades {
+		data.Trades[i].UserID = userID
+	}
+	for i := range data.TaxMappings {
+		data.TaxMappings[i].UserID = userID
+	}
+	for i := range data.UserSettings {
+		data.UserSettings[i].UserID = userID
+	}
+}
+
+// remapUserData 重新產生所有紀錄的 ID 並更新彼此的參照，交易 ID 與新增交易相同使用 ULID。
+// 貸款還款交易的 ExternalID 為還款 ID 加上 "-" 後綴，替換前綴後保留原本的後綴
+func remapUserData(data *db.UserData) {
+	ids := make(map[string]string)
+	remap := func(old string, generate func() string) string {
+		if old == "" {
+			return ""
+		}
+		if id, ok := ids[old]; ok {
+			return id
+		}
+		id := generate()
+		ids[old] = id
+		return id
+	}
+	newID := func(old string) string {
+		return remap(old, uuid.NewString)
+	}
+	ref := func(old string) string {
+		if id, ok := ids[old]; ok {
+			return id
+		}
+		return old
+	}
+	derivedRef := func(old string) string {
+		if i := strings.LastIndex(old, "-"); i > 0 {
+			if id, ok := ids[old[:i]]; ok {
+				return id + old[i:]
+			}
+		}
+		return ref(old)
+	}
+
+	for i := range data.Accounts {
+		data.Accounts[i].ID = newID(data.Accounts[i].ID)
+	}
+	for i := range data.Accounts {
+		data.Accounts[i].PaymentAccountID = ref(data.Accounts[i].PaymentAccountID)
+	}
+	for i := range data.InstallmentPlans {
+		p := &data.InstallmentPlans[i]
+		p.ID, p.AccountID = newID(p.ID), ref(p.AccountID)
+	}
+	for i := range data.Loans {
+		l := &data.Loans[i]
+		l.ID, l.AccountID = newID(l.ID), ref(l.AccountID)
+	}
+	for i := range data.LoanPayments {
+		p := &data.LoanPayments[i]
+		p.ID, p.LoanID = newID(p.ID), ref(p.LoanID)
+	}
+	for i := range data.RecurringSchedules {
+		s := &data.RecurringSchedules[i]
+		s.ID, s.AccountID = newID(s.ID), ref(s.AccountID)
+	}
+	for i := range data.Trades {
+		t := &data.Trades[i]
+		t.ID, t.AccountID = newID(t.ID), ref(t.AccountID)
+	}
+	for i := range data.Transactions {
+		tx := &data.Transactions[i]
+		tx.ID = remap(tx.ID, newTransactionID)
+		tx.AccountID, tx.InstallmentPlanID = ref(tx.AccountID), ref(tx.InstallmentPlanID)
+		tx.ExternalID = derivedRef(tx.ExternalID)
+	}
+}
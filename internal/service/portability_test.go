@@ -0,0 +1,147 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// portabilityRepo 以記憶體保存匯出與匯入的用戶資料
+type portabilityRepo struct {
+	db.DBClient
+	data     *db.UserData
+	restored *db.UserData
+	deleted  []string
+	owners   map[string]string // 既有交易 ID 與其所屬用戶
+}
+
+func (r *portabilityRepo) GetUserData(userID string) (*db.UserData, error) {
+	return r.data, nil
+}
+
+func (r *portabilityRepo) RestoreUserData(userID string, data *db.UserData) error {
+	for _, tx := range data.Transactions {
+		if owner, ok := r.owners[tx.ID]; ok && owner != userID {
+			return fmt.Errorf("%w: %s", db.ErrForeignRecord, tx.ID)
+		}
+	}
+	r.restored = data
+	return nil
+}
+
+func (r *portabilityRepo) DeleteNetWorthSnapshots(userID, from string) error {
+	r.deleted = append(r.deleted, userID)
+	return nil
+}
+
+func TestExportImportUser(t *testing.T) {
+	date := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	repo := &portabilityRepo{data: &db.UserData{
+		Accounts: []entity.Account{
+			{ID: "bank1", UserID: "user123", Name: "薪轉戶", Type: entity.AccountTypeBank},
+			{ID: "card1", UserID: "user123", Name: "信用卡", Type: entity.AccountTypeCreditCard, PaymentAccountID: "bank1"},
+		},
+		InstallmentPlans: []entity.InstallmentPlan{{ID: "plan1", UserID: "user123", AccountID: "card1"}},
+		Loans:            []entity.Loan{{ID: "loan1", UserID: "user123", AccountID: "bank1"}},
+		LoanPayments:     []entity.LoanPayment{{ID: "pay1", LoanID: "loan1", UserID: "user123"}},
+		Transactions: []entity.Transaction{
+			{ID: "tx1", UserID: "user123", Date: date, Amount: 120, Category: "FOOD", AccountID: "card1"},
+			{ID: "plan1-2", UserID: "user123", Date: date, Amount: 1000, Category: "SHOPPING", AccountID: "card1", InstallmentPlanID: "plan1", InstallmentNo: 2},
+			{ID: "pay1-interest", UserID: "user123", Date: date, Amount: 50, Category: entity.CategoryLoanInterest, AccountID: "bank1", ExternalID: "pay1-interest"},
+		},
+	}}
+	s := NewPortabilityService(repo)
+
+	var buf bytes.Buffer
+	manifest, err := s.ExportUser("user123", &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, manifest.Files[ArchiveTransactions])
+	assert.Equal(t, 0, manifest.Files[ArchiveTrades])
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Contains(t, names, "manifest.json")
+	assert.Contains(t, names, ArchiveTransactionsCSV)
+	assert.Contains(t, names, ArchiveCategoriesCSV)
+
+	// 匯入至原用戶時保留 ID
+	summary, err := s.ImportUser(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "user123")
+	assert.NoError(t, err)
+	assert.False(t, summary.RemappedIDs)
+	assert.Equal(t, repo.data.Transactions, repo.restored.Transactions)
+
+	// 匯入至其他用戶時重新產生 ID，並保持彼此的參照與還款交易 ExternalID 的後綴
+	summary, err = s.ImportUser(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "user456")
+	assert.NoError(t, err)
+	assert.True(t, summary.RemappedIDs)
+	assert.Equal(t, 3, summary.Files[ArchiveTransactions])
+	assert.Equal(t, []string{"user123", "user456"}, repo.deleted)
+
+	restored := repo.restored
+	bank, card := restored.Accounts[0], restored.Accounts[1]
+	assert.NotEqual(t, "bank1", bank.ID)
+	assert.Equal(t, "user456", bank.UserID)
+	assert.Equal(t, bank.ID, card.PaymentAccountID)
+	assert.Equal(t, card.ID, restored.InstallmentPlans[0].AccountID)
+	assert.Equal(t, restored.Loans[0].ID, restored.LoanPayments[0].LoanID)
+
+	plan, payment := restored.InstallmentPlans[0], restored.LoanPayments[0]
+	assert.Equal(t, card.ID, restored.Transactions[0].AccountID)
+	assert.Equal(t, plan.ID, restored.Transactions[1].InstallmentPlanID)
+	assert.Equal(t, payment.ID+"-interest", restored.Transactions[2].ExternalID)
+	// 交易 ID 與新增交易相同為 ULID，包含舊版的分期與還款交易
+	for _, tx := range restored.Transactions {
+		_, err := ulid.ParseStrict(tx.ID)
+		assert.NoError(t, err, tx.ID)
+	}
+	assert.Equal(t, "user456", restored.Transactions[2].UserID)
+}
+
+func TestImportUserRejectsForeignIDs(t *testing.T) {
+	// 封存檔的用戶與匯入目標相同，但交易 ID 屬於另一位用戶
+	repo := &portabilityRepo{
+		data: &db.UserData{Transactions: []entity.Transaction{
+			{ID: "tx-of-user456", UserID: "user123", Date: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), Amount: 1},
+		}},
+		owners: map[string]string{"tx-of-user456": "user456"},
+	}
+	s := NewPortabilityService(repo)
+
+	var buf bytes.Buffer
+	_, err := s.ExportUser("user123", &buf)
+	assert.NoError(t, err)
+
+	_, err = s.ImportUser(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "user123")
+	assert.ErrorIs(t, err, ErrArchiveConflict)
+	assert.Nil(t, repo.restored)
+	assert.Empty(t, repo.deleted)
+}
+
+func TestImportUserRejectsInvalidArchive(t *testing.T) {
+	s := NewPortabilityService(&portabilityRepo{})
+
+	_, err := s.ImportUser(bytes.NewReader([]byte("not a zip")), 9, "user123")
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, _ := zw.Create("manifest.json")
+	_, _ = f.Write([]byte(`{"format":"fintrack-user-archive","format_version":1,"user_id":"user123","files":{"transactions.json":2}}`))
+	f, _ = zw.Create(ArchiveTransactions)
+	_, _ = f.Write([]byte(`[{"ID":"tx1"}]`))
+	assert.NoError(t, zw.Close())
+
+	_, err = s.ImportUser(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "user123")
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"fintrack/internal/entity"
+	"math"
+	"sort"
+	"time"
+)
+
+// CostMethod 表示計算持有成本與已實現損益的方法
+type CostMethod string
+
+const (
+	CostMethodFIFO    CostMethod = "FIFO"    // 先進先出
+	CostMethodAverage CostMethod = "AVERAGE" // 平均成本
+)
+
+// Lot 表示一批買入且尚未賣出的持股
+type Lot struct {
+	AcquiredOn   time.Time `json:"acquired_on"`
+	Quantity     float64   `json:"quantity"`
+	CostPerShare float64   `json:"cost_per_share"`
+}
+
+// RealizedGain 表示一筆賣出的已實現損益
+type RealizedGain struct {
+	AccountID string    `json:"account_id"`
+	Symbol    string    `json:"symbol"`
+	Date      time.Time `json:"date"`
+	Quantity  float64   `json:"quantity"`
+	Proceeds  float64   `json:"proceeds"` // 扣除費用後的賣出收入
+	CostBasis float64   `json:"cost_basis"`
+	Gain      float64   `json:"gain"`
+}
+
+// DividendIncome 表示一筆現金股利
+type DividendIncome struct {
+	AccountID string    `json:"account_id"`
+	Symbol    string    `json:"symbol"`
+	Date      time.Time `json:"date"`
+	Amount    float64   `json:"amount"`
+}
+
+// position 表示某帳戶對某證券的持倉
+type position struct {
+	accountID string
+	symbol    string
+	lots      []Lot   // 依買入順序排列，用於 FIFO 與顯示
+	quantity  float64 // 目前持有股數
+	totalCost float64 // 依成本計算方法得出的持有成本
+}
+
+// portfolioLedger 為重播投資交易後的結果
+type portfolioLedger struct {
+	positions []*position
+	realized  []RealizedGain
+	dividends []DividendIncome
+}
+
+// quantityEpsilon 用於判斷浮點數股數是否已歸零
+const quantityEpsilon = 1e-9
+
+// replayTrades 依日期順序重播投資交易，計算持倉、已實現損益與股利
+func replayTrades(trades []entity.Trade, method CostMethod) *portfolioLedger {
+	ledger := &portfolioLedger{}
+	index := make(map[string]*position)
+	get := func(accountID, symbol string) *position {
+		key := accountID + "|" + symbol
+		if index[key] == nil {
+			index[key] = &position{accountID: accountID, symbol: symbol}
+			ledger.positions = append(ledger.positions, index[key])
+		}
+		return index[key]
+	}
+
+	sorted := append([]entity.Trade(nil), trades...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	for _, trade := range sorted {
+		p := get(trade.AccountID, trade.Symbol)
+		switch trade.Type {
+		case entity.TradeBuy:
+			p.buy(trade)
+		case entity.TradeSell:
+			if gain, ok := p.sell(trade, method); ok {
+				ledger.realized = append(ledger.realized, gain)
+			}
+		case entity.TradeDividend:
+			ledger.dividends = append(ledger.dividends, DividendIncome{
+				AccountID: trade.AccountID,
+				Symbol:    trade.Symbol,
+				Date:      trade.Date,
+				Amount:    trade.Amount,
+			})
+		case entity.TradeSplit:
+			p.split(trade.Ratio)
+		}
+	}
+	return ledger
+}
+
+// holdingQuantity 返回重播後某帳戶對某證券的持有股數
+func (l *portfolioLedger) holdingQuantity(accountID, symbol string) float64 {
+	for _, p := range l.positions {
+		if p.accountID == accountID && p.symbol == symbol {
+			return p.quantity
+		}
+	}
+	return 0
+}
+
+// oversold 依日期順序重播某帳戶對某證券的持股數，返回第一筆超過當時持股的賣出及賣出前的持股，沒有時返回 nil。
+// 同一日期的交易依傳入順序處理
+func oversold(trades []entity.Trade, accountID, symbol string) (*entity.Trade, float64) {
+	sorted := append([]entity.Trade(nil), trades...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	var held float64
+	for i, trade := range sorted {
+		if trade.AccountID != accountID || trade.Symbol != symbol {
+			continue
+		}
+		switch trade.Type {
+		case entity.TradeBuy:
+			held += trade.Quantity
+		case entity.TradeSell:
+			if trade.Quantity > held+quantityEpsilon {
+				return &sorted[i], held
+			}
+			held -= trade.Quantity
+		case entity.TradeSplit:
+			held *= trade.Ratio
+		}
+	}
+	return nil, 0
+}
+
+func (p *position) buy(trade entity.Trade) {
+	if trade.Quantity <= 0 {
+		return
+	}
+	cost := trade.Quantity*trade.Price + trade.Fee
+	p.lots = append(p.lots, Lot{AcquiredOn: trade.Date, Quantity: trade.Quantity, CostPerShare: cost / trade.Quantity})
+	p.quantity += trade.Quantity
+	p.totalCost += cost
+}
+
+// sell 賣出持股並計算已實現損益，賣出股數超過持有股數時以持有股數為準
+func (p *position) sell(trade entity.Trade, method CostMethod) (RealizedGain, bool) {
+	quantity := math.Min(trade.Quantity, p.quantity)
+	if quantity <= 0 {
+		return RealizedGain{}, false
+	}
+
+	var fifoCost float64
+	remaining := quantity
+	for remaining > quantityEpsilon && len(p.lots) > 0 {
+		lot := &p.lots[0]
+		used := math.Min(remaining, lot.Quantity)
+		fifoCost += used * lot.CostPerShare
+		lot.Quantity -= used
+		remaining -= used
+		if lot.Quantity <= quantityEpsilon {
+			p.lots = p.lots[1:]
+		}
+	}
+
+	cost := fifoCost
+	if method == CostMethodAverage {
+		cost = p.totalCost * quantity / p.quantity
+	}
+	p.quantity -= quantity
+	p.totalCost -= cost
+	if p.quantity <= quantityEpsilon {
+		p.quantity, p.totalCost, p.lots = 0, 0, nil
+	}
+
+	// 按比例分攤手續費，避免超賣時多扣費用
+	proceeds := quantity*trade.Price - trade.Fee*quantity/trade.Quantity
+	return RealizedGain{
+		AccountID: p.accountID,
+		Symbol:    p.symbol,
+		Date:      trade.Date,
+		Quantity:  quantity,
+		Proceeds:  roundAmount(proceeds),
+		CostBasis: roundAmount(cost),
+		Gain:      roundAmount(proceeds - cost),
+	}, true
+}
+
+// split 股票分割，持股數乘以比例，每股成本相應降低，總成本不變
+func (p *position) split(ratio float64) {
+	if ratio <= 0 {
+		return
+	}
+	for i := range p.lots {
+		p.lots[i].Quantity *= ratio
+		p.lots[i].CostPerShare /= ratio
+	}
+	p.quantity *= ratio
+}
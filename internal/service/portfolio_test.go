@@ -0,0 +1,62 @@
+package service
+
+import (
+	"fintrack/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func sampleTrades() []entity.Trade {
+	return []entity.Trade{
+		{AccountID: "inv1", Symbol: "2330", Type: entity.TradeBuy, Date: day(2024, 1, 2), Quantity: 100, Price: 500, Fee: 100},
+		{AccountID: "inv1", Symbol: "2330", Type: entity.TradeBuy, Date: day(2024, 2, 1), Quantity: 100, Price: 600},
+		{AccountID: "inv1", Symbol: "2330", Type: entity.TradeDividend, Date: day(2024, 3, 15), Amount: 700},
+		{AccountID: "inv1", Symbol: "2330", Type: entity.TradeSell, Date: day(2024, 4, 1), Quantity: 150, Price: 700, Fee: 150},
+	}
+}
+
+func TestReplayTradesFIFO(t *testing.T) {
+	ledger := replayTrades(sampleTrades(), CostMethodFIFO)
+
+	if assert.Len(t, ledger.realized, 1) {
+		gain := ledger.realized[0]
+		assert.Equal(t, 104850.0, gain.Proceeds)
+		// 100 股 x 501 + 50 股 x 600
+		assert.Equal(t, 80100.0, gain.CostBasis)
+		assert.Equal(t, 24750.0, gain.Gain)
+	}
+	assert.Equal(t, []DividendIncome{{AccountID: "inv1", Symbol: "2330", Date: day(2024, 3, 15), Amount: 700}}, ledger.dividends)
+
+	p := ledger.positions[0]
+	assert.Equal(t, 50.0, p.quantity)
+	assert.Equal(t, 30000.0, p.totalCost)
+	assert.Equal(t, []Lot{{AcquiredOn: day(2024, 2, 1), Quantity: 50, CostPerShare: 600}}, p.lots)
+}
+
+func TestReplayTradesAverageCost(t *testing.T) {
+	ledger := replayTrades(sampleTrades(), CostMethodAverage)
+
+	// 平均成本 (50100 + 60000) / 200 = 550.5
+	assert.Equal(t, 82575.0, ledger.realized[0].CostBasis)
+	assert.Equal(t, 22275.0, ledger.realized[0].Gain)
+	assert.Equal(t, 27525.0, ledger.positions[0].totalCost)
+}
+
+func TestReplayTradesSplit(t *testing.T) {
+	trades := []entity.Trade{
+		{AccountID: "inv1", Symbol: "0050", Type: entity.TradeBuy, Date: day(2024, 1, 2), Quantity: 10, Price: 180},
+		{AccountID: "inv1", Symbol: "0050", Type: entity.TradeSplit, Date: day(2024, 6, 18), Ratio: 4},
+		{AccountID: "inv1", Symbol: "0050", Type: entity.TradeSell, Date: day(2024, 7, 1), Quantity: 20, Price: 50},
+	}
+	ledger := replayTrades(trades, CostMethodFIFO)
+
+	assert.Equal(t, 20.0, ledger.holdingQuantity("inv1", "0050"))
+	assert.Equal(t, 900.0, ledger.realized[0].CostBasis)
+	assert.Equal(t, 100.0, ledger.realized[0].Gain)
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fintrack/internal/validation"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidTransactionQuery 表示交易列表的查詢參數不正確
+var ErrInvalidTransactionQuery = errors.New("invalid transaction query")
+
+// 交易列表的排序方向
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// transactionSources 為交易來源的有效值
+var transactionSources = []string{"MANUAL", "BANK", "CREDIT_CARD"}
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// TransactionListRequest 表示交易列表的篩選、排序與分頁參數，多值的篩選符合任一值即可，標籤須全部符合
+type TransactionListRequest struct {
+	UserID       string
+	Categories   []string
+	StartDate    string // 只指定一端時為開放範圍
+	EndDate      string
+	MinAmount    *float64
+	MaxAmount    *float64
+	Sources      []string
+	Reconciled   *bool
+	AccountID    string
+	Payee        string
+	Tags         []string
+	Description  string // 說明包含此字串，不分大小寫
+	Sort         string // date、amount、category 或 payee，預設為 date
+	Order        string // asc 或 desc，預設為 desc
+	Cursor       string // 上一頁返回的 next_cursor
+	PageSize     int
+	IncludeTotal bool // 是否計算符合條件的總筆數
+}
+
+// TransactionPage 表示一頁交易，NextCursor 為空時已是最後一頁
+type TransactionPage struct {
+	Transactions []entity.Transaction `json:"transactions"`
+	NextCursor   string               `json:"next_cursor,omitempty"`
+	Total        *int64               `json:"total,omitempty"`
+}
+
+// transactionCursor 為分頁位置的內容，編碼為不透明字串返回客戶端
+type transactionCursor struct {
+	Sort  string          `json:"s"`
+	Order string          `json:"o"`
+	Value json.RawMessage `json:"v"`
+	ID    string          `json:"id"`
+}
+
+// listQuery 驗證排序與分頁參數並轉換為資料庫查詢，日期範圍由呼叫端轉換後傳入
+func (req TransactionListRequest) listQuery(from, to string) (db.TransactionQuery, error) {
+	var v validation.Validator
+	sort := strings.ToLower(req.Sort)
+	if sort == "" {
+		sort = db.SortByDate
+	}
+	v.OneOf("sort", "sort", sort, db.SortByDate, db.SortByAmount, db.SortByCategory, db.SortByPayee)
+	order := strings.ToLower(req.Order)
+	if order == "" {
+		order = OrderDesc
+	}
+	v.OneOf("order", "order", order, OrderAsc, OrderDesc)
+	pageSize := req.PageSize
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize < 1 || pageSize > maxPageSize {
+		v.Add("page_size", validation.CodeInvalidValue, req.PageSize, "page_size must be between 1 and %d", maxPageSize)
+	}
+
+	if from != "" && to != "" && from > to {
+		v.Add("start_date", validation.CodeInvalidValue, req.StartDate, "start_date must not be after end_date")
+	}
+	if req.MinAmount != nil && *req.MinAmount < 0 {
+		v.Add("min_amount", validation.CodeInvalidValue, *req.MinAmount, "amounts must not be negative")
+	}
+	if req.MaxAmount != nil && *req.MaxAmount < 0 {
+		v.Add("max_amount", validation.CodeInvalidValue, *req.MaxAmount, "amounts must not be negative")
+	}
+	if req.MinAmount != nil && req.MaxAmount != nil && *req.MinAmount > *req.MaxAmount {
+		v.Add("min_amount", validation.CodeInvalidValue, *req.MinAmount, "min_amount must not be greater than max_amount")
+	}
+	var sources []string
+	for _, source := range req.Sources {
+		source = strings.ToUpper(source)
+		v.OneOf("source", "source", source, transactionSources...)
+		sources = append(sources, source)
+	}
+	if err := v.Err(); err != nil {
+		return db.TransactionQuery{}, fmt.Errorf("%w: %w", ErrInvalidTransactionQuery, err)
+	}
+
+	query := db.TransactionQuery{
+		UserID:      req.UserID,
+		Categories:  req.Categories,
+		StartDate:   from,
+		EndDate:     to,
+		MinAmount:   req.MinAmount,
+		MaxAmount:   req.MaxAmount,
+		Sources:     sources,
+		Reconciled:  req.Reconciled,
+		AccountID:   req.AccountID,
+		Payee:       req.Payee,
+		Tags:        req.Tags,
+		Description: req.Description,
+		SortField:   sort,
+		Descending:  order == OrderDesc,
+		Limit:       pageSize,
+	}
+	if req.Cursor != "" {
+		after, err := decodeCursor(req.Cursor, sort, order)
+		if err != nil {
+			return db.TransactionQuery{}, err
+		}
+		query.After = after
+	}
+	return query, nil
+}
+
+// encodeCursor 以最後一筆交易的排序值與 ID 產生下一頁的游標
+func encodeCursor(tx entity.Transaction, sort, order string) string {
+	var value interface{}
+	switch sort {
+	case db.SortByAmount:
+		value = tx.Amount
+	case db.SortByCategory:
+		value = tx.Category
+	case db.SortByPayee:
+		value = tx.Payee
+	default:
+		value = tx.Date.UTC()
+	}
+	raw, _ := json.Marshal(value)
+	data, _ := json.Marshal(transactionCursor{Sort: sort, Order: order, Value: raw, ID: tx.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解析游標，游標必須來自相同排序條件的查詢
+func decodeCursor(cursor, sort, order string) (*db.TransactionKey, error) {
+	invalid := fmt.Errorf("%w: cursor is invalid", ErrInvalidTransactionQuery)
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	var c transactionCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, invalid
+	}
+	if c.Sort != sort || c.Order != order {
+		return nil, fmt.Errorf("%w: cursor was created with a different sort or order", ErrInvalidTransactionQuery)
+	}
+
+	key := &db.TransactionKey{ID: c.ID}
+	switch sort {
+	case db.SortByDate:
+		var date time.Time
+		err = json.Unmarshal(c.Value, &date)
+		key.Value = date.UTC()
+	case db.SortByAmount:
+		var amount float64
+		err = json.Unmarshal(c.Value, &amount)
+		key.Value = amount
+	default:
+		var text string
+		err = json.Unmarshal(c.Value, &text)
+		key.Value = text
+	}
+	if err != nil {
+		return nil, invalid
+	}
+	return key, nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// listRepo 記錄交易列表的查詢條件
+type listRepo struct {
+	db.DBClient
+	transactions []entity.Transaction
+	queries      []db.TransactionQuery
+}
+
+func (r *listRepo) GetFilteredTransactions(query db.TransactionQuery) ([]entity.Transaction, error) {
+	r.queries = append(r.queries, query)
+	if len(r.transactions) > query.Limit {
+		return r.transactions[:query.Limit], nil
+	}
+	return r.transactions, nil
+}
+
+func (r *listRepo) SearchTransactions(query db.TransactionQuery, text string) ([]db.TransactionMatch, error) {
+	r.queries = append(r.queries, query)
+	matches := make([]db.TransactionMatch, len(r.transactions))
+	for i, tx := range r.transactions {
+		matches[i] = db.TransactionMatch{Transaction: tx, Score: float64(len(r.transactions) - i)}
+	}
+	return matches, nil
+}
+
+func (r *listRepo) CountFilteredTransactions(query db.TransactionQuery) (int64, error) {
+	return int64(len(r.transactions)), nil
+}
+
+func (r *listRepo) GetUserSettings(userID string) (*entity.UserSettings, error) {
+	settings := entity.DefaultUserSettings(userID)
+	return &settings, nil
+}
+
+func TestGetTransactionsPage(t *testing.T) {
+	date := time.Date(2024, 3, 10, 12, 0, 0, 123000000, time.UTC)
+	repo := &listRepo{transactions: []entity.Transaction{
+		{ID: "tx3", Date: date, Amount: 30},
+		{ID: "tx2", Date: date, Amount: 20},
+		{ID: "tx1", Date: date.Add(-time.Hour), Amount: 10},
+	}}
+	s := &transactionService{repo: repo}
+
+	page, err := s.GetTransactions(TransactionListRequest{UserID: "user123", PageSize: 2, IncludeTotal: true})
+	assert.NoError(t, err)
+	assert.Len(t, page.Transactions, 2)
+	assert.Equal(t, int64(3), *page.Total)
+	assert.NotEmpty(t, page.NextCursor)
+	assert.Equal(t, db.TransactionQuery{UserID: "user123", SortField: db.SortByDate, Descending: true, Limit: 3}, repo.queries[0])
+
+	// 下一頁從上一頁最後一筆之後開始
+	repo.transactions = repo.transactions[2:]
+	page, err = s.GetTransactions(TransactionListRequest{UserID: "user123", PageSize: 2, Cursor: page.NextCursor})
+	assert.NoError(t, err)
+	assert.Len(t, page.Transactions, 1)
+	assert.Empty(t, page.NextCursor)
+	assert.Nil(t, page.Total)
+	assert.Equal(t, &db.TransactionKey{Value: date, ID: "tx2"}, repo.queries[1].After)
+
+	// 游標只能用於相同的排序
+	_, err = s.GetTransactions(TransactionListRequest{UserID: "user123", Sort: "amount", Cursor: encodeCursor(repo.transactions[0], db.SortByDate, OrderDesc)})
+	assert.ErrorIs(t, err, ErrInvalidTransactionQuery)
+	_, err = s.GetTransactions(TransactionListRequest{UserID: "user123", Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, ErrInvalidTransactionQuery)
+	_, err = s.GetTransactions(TransactionListRequest{UserID: "user123", Sort: "description"})
+	assert.ErrorIs(t, err, ErrInvalidTransactionQuery)
+	_, err = s.GetTransactions(TransactionListRequest{UserID: "user123", PageSize: 500})
+	assert.ErrorIs(t, err, ErrInvalidTransactionQuery)
+}
+
+func TestGetTransactionsFilters(t *testing.T) {
+	repo := &listRepo{}
+	s := &transactionService{repo: repo}
+
+	// 只指定開始日時為開放範圍，來源不分大小寫
+	minAmount := 10.0
+	_, err := s.GetTransactions(TransactionListRequest{UserID: "user123", StartDate: "2024-03-01", MinAmount: &minAmount, Sources: []string{"bank"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-03-01 00:00:00", repo.queries[0].StartDate)
+	assert.Empty(t, repo.queries[0].EndDate)
+	assert.Equal(t, []string{"BANK"}, repo.queries[0].Sources)
+	assert.Equal(t, &minAmount, repo.queries[0].MinAmount)
+
+	maxAmount := 5.0
+	for _, req := range []TransactionListRequest{
+		{UserID: "user123", MinAmount: &minAmount, MaxAmount: &maxAmount},
+		{UserID: "user123", Sources: []string{"CASH"}},
+		{UserID: "user123", StartDate: "2024-03-02", EndDate: "2024-03-01"},
+	} {
+		_, err := s.GetTransactions(req)
+		assert.ErrorIs(t, err, ErrInvalidTransactionQuery)
+	}
+	_, err = s.GetTransactions(TransactionListRequest{UserID: "user123", EndDate: "03/01/2024"})
+	assert.ErrorIs(t, err, ErrInvalidReportRequest)
+}
+
+func TestTransactionCursor(t *testing.T) {
+	tx := entity.Transaction{ID: "tx1", Amount: 99.5, Payee: "IKEA"}
+	for _, sort := range []string{db.SortByAmount, db.SortByPayee} {
+		key, err := decodeCursor(encodeCursor(tx, sort, OrderAsc), sort, OrderAsc)
+		assert.NoError(t, err)
+		assert.Equal(t, "tx1", key.ID)
+	}
+	key, err := decodeCursor(encodeCursor(tx, db.SortByAmount, OrderAsc), db.SortByAmount, OrderAsc)
+	assert.NoError(t, err)
+	assert.Equal(t, 99.5, key.Value)
+	_, err = decodeCursor(encodeCursor(tx, db.SortByAmount, OrderAsc), db.SortByAmount, OrderDesc)
+	assert.ErrorIs(t, err, ErrInvalidTransactionQuery)
+}
+
+func TestSearchTransactions(t *testing.T) {
+	repo := &listRepo{transactions: []entity.Transaction{{ID: "tx1", Payee: "IKEA", Date: time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)}}}
+	s := &transactionService{repo: repo}
+
+	result, err := s.SearchTransactions(TransactionListRequest{UserID: "user123", Categories: []string{"HOME"}}, "  IKEA  ")
+	assert.NoError(t, err)
+	assert.Equal(t, "IKEA", result.Query)
+	assert.Len(t, result.Transactions, 1)
+	assert.Equal(t, 1.0, result.Transactions[0].Score)
+	assert.Equal(t, []string{"HOME"}, repo.queries[0].Categories)
+	assert.Equal(t, defaultSearchSize, repo.queries[0].Limit)
+
+	for _, tc := range []struct {
+		req  TransactionListRequest
+		text string
+	}{
+		{TransactionListRequest{UserID: "user123"}, " "},
+		{TransactionListRequest{UserID: "user123"}, strings.Repeat("沙", maxSearchLength+1)},
+		{TransactionListRequest{UserID: "user123", Sort: "amount"}, "IKEA"},
+		{TransactionListRequest{UserID: "user123", Cursor: "abc"}, "IKEA"},
+	} {
+		_, err := s.SearchTransactions(tc.req, tc.text)
+		assert.ErrorIs(t, err, ErrInvalidTransactionQuery)
+	}
+}
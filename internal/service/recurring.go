@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fintrack/internal/cache"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	recurringLookbackMonths  = 24   // 偵測時回溯的歷史月數
+	minRecurringOccurrences  = 3    // 至少出現幾次才視為週期性
+	recurringAmountTolerance = 0.3  // 金額與中位數的最大偏差比例
+	recurringIntervalRatio   = 0.75 // 間隔符合週期的最低比例
+	priceChangeThreshold     = 0.05 // 最近一次金額變動超過此比例即發出漲價提醒
+)
+
+var (
+	ErrSeriesNotFound  = errors.New("recurring series not found")
+	ErrScheduleExists  = errors.New("recurring schedule already exists for this payee")
+	ErrAmbiguousSeries = errors.New("both an income and an expense series match this payee, type is required")
+)
+
+// RecurringSeries 表示從歷史交易中偵測出的週期性收支
+type RecurringSeries struct {
+	Payee            string            `json:"payee"`
+	Category         string            `json:"category"`
+	Type             string            `json:"type"`
+	Cadence          entity.Cadence    `json:"cadence"`
+	Occurrences      int               `json:"occurrences"`
+	AverageAmount    float64           `json:"average_amount"`
+	LastAmount       float64           `json:"last_amount"`
+	FirstDate        time.Time         `json:"first_date"`
+	LastDate         time.Time         `json:"last_date"`
+	NextExpectedDate time.Time         `json:"next_expected_date"`
+	AccountID        string            `json:"account_id,omitempty"`
+	Managed          bool              `json:"managed"` // 是否已有對應的受管理排程
+	PriceChange      *PriceChangeAlert `json:"price_change,omitempty"`
+}
+
+// PriceChangeAlert 表示週期性扣款金額的變動
+type PriceChangeAlert struct {
+	PreviousAmount float64   `json:"previous_amount"`
+	CurrentAmount  float64   `json:"current_amount"`
+	ChangePercent  float64   `json:"change_percent"`
+	ChangedOn      time.Time `json:"changed_on"`
+}
+
+type RecurringService interface {
+	DetectRecurring(userID string) ([]RecurringSeries, error)
+	PromoteSeries(userID, payee, txType string) (*entity.RecurringSchedule, error)
+	GetSchedules(userID string) ([]entity.RecurringSchedule, error)
+	GetCalendar(userID string, from, to time.Time) (*Calendar, error)
+}
+
+type recurringService struct {
+	repo  db.DBClient
+	cache cache.Cache
+}
+
+func NewRecurringService(repo db.DBClient, cache cache.Cache) RecurringService {
+	return &recurringService{repo: repo, cache: cache}
+}
+
+// DetectRecurring 分析用戶的歷史交易，找出週期性收支（訂閱、薪資等）
+func (s *recurringService) DetectRecurring(userID string) ([]RecurringSeries, error) {
+	series, _, _, err := s.detect(userID, time.Now())
+	return series, err
+}
+
+// detect 載入歷史交易與受管理排程並執行偵測，一併返回兩者供行事曆等功能重用
+func (s *recurringService) detect(userID string, now time.Time) ([]RecurringSeries, []entity.Transaction, []entity.RecurringSchedule, error) {
+	start := now.AddDate(0, -recurringLookbackMonths, 0).Format(dateLayout)
+	end := now.AddDate(0, 0, 1).Format(dateLayout)
+	transactions, err := s.repo.GetTransactions(userID, start, end)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	schedules, err := s.repo.GetRecurringSchedules(userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	managed := make(map[string]bool, len(schedules))
+	for _, schedule := range schedules {
+		managed[seriesKey(schedule.Payee, schedule.IsIncome())] = true
+	}
+
+	series := detectRecurringSeries(transactions, now)
+	for i := range series {
+		series[i].Managed = managed[seriesKey(series[i].Payee, series[i].Type == entity.TypeIncome)]
+	}
+	return series, transactions, schedules, nil
+}
+
+// PromoteSeries 將偵測到的週期性收支轉為受管理的排程。txType 為空時，收款方須只有收入或支出其中一個序列
+func (s *recurringService) PromoteSeries(userID, payee, txType string) (*entity.RecurringSchedule, error) {
+	series, err := s.DetectRecurring(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := normalizePayee(payee)
+	var matches []RecurringSeries
+	for _, ser := range series {
+		if normalizePayee(ser.Payee) == key && (txType == "" || ser.Type == txType) {
+			matches = append(matches, ser)
+		}
+	}
+	switch {
+	case len(matches) == 0:
+		return nil, ErrSeriesNotFound
+	case len(matches) > 1:
+		return nil, ErrAmbiguousSeries
+	}
+
+	ser := matches[0]
+	if ser.Managed {
+		return nil, ErrScheduleExists
+	}
+
+	schedule := entity.RecurringSchedule{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Payee:     ser.Payee,
+		Category:  ser.Category,
+		Type:      ser.Type,
+		Cadence:   ser.Cadence,
+		Amount:    ser.LastAmount, // 以最新金額為準，反映最近的調價
+		StartDate: ser.FirstDate,
+		NextDate:  ser.NextExpectedDate,
+		Active:    true,
+		AccountID: ser.AccountID,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.SaveRecurringSchedule(schedule); err != nil {
+		return nil, err
+	}
+	// 現金流預測依受管理排程推算，新排程須使快取的預測失效
+	if err := bumpReportVersion(context.Background(), s.cache, userID); err != nil {
+		log.Printf("Failed to invalidate cached reports: %v", err)
+	}
+	return &schedule, nil
+}
+
+// GetSchedules 查詢用戶的受管理排程
+func (s *recurringService) GetSchedules(userID string) ([]entity.RecurringSchedule, error) {
+	return s.repo.GetRecurringSchedules(userID)
+}
+
+// detectRecurringSeries 依收款方分組，找出間隔規律且金額相近的交易序列
+func detectRecurringSeries(transactions []entity.Transaction, asOf time.Time) []RecurringSeries {
+	groups := make(map[string][]entity.Transaction)
+	for _, tx := range transactions {
+		if normalizePayee(payeeOf(tx)) == "" {
+			continue
+		}
+		key := seriesKey(payeeOf(tx), tx.IsIncome())
+		groups[key] = append(groups[key], tx)
+	}
+
+	var result []RecurringSeries
+	for _, txs := range groups {
+		if series, ok := analyzeSeries(txs, asOf); ok {
+			result = append(result, series)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].NextExpectedDate.Before(result[j].NextExpectedDate)
+	})
+	return result
+}
+
+// analyzeSeries 判斷同一收款方的交易是否構成週期性序列
+func analyzeSeries(txs []entity.Transaction, asOf time.Time) (RecurringSeries, bool) {
+	if len(txs) < minRecurringOccurrences {
+		return RecurringSeries{}, false
+	}
+	sort.Slice(txs, func(i, j int) bool { return txs[i].Date.Before(txs[j].Date) })
+
+	// 金額需與中位數相近，避免將偶發的大額消費誤判為訂閱
+	amounts := make([]float64, len(txs))
+	for i, tx := range txs {
+		amounts[i] = tx.Amount
+	}
+	medianAmount := median(amounts)
+	for _, amount := range amounts {
+		if math.Abs(amount-medianAmount) > medianAmount*recurringAmountTolerance {
+			return RecurringSeries{}, false
+		}
+	}
+
+	var intervals []float64
+	for i := 1; i < len(txs); i++ {
+		days := txs[i].Date.Sub(txs[i-1].Date).Hours() / 24
+		if days >= 1 {
+			intervals = append(intervals, days)
+		}
+	}
+	if len(intervals) < minRecurringOccurrences-1 {
+		return RecurringSeries{}, false
+	}
+
+	cadence, ok := matchCadence(intervals)
+	if !ok {
+		return RecurringSeries{}, false
+	}
+
+	first, last := txs[0], txs[len(txs)-1]
+	next := cadence.Next(last.Date)
+	// 超過一個完整週期未再出現，視為已停止的訂閱
+	if asOf.After(cadence.Next(next)) {
+		return RecurringSeries{}, false
+	}
+
+	var total float64
+	for _, amount := range amounts {
+		total += amount
+	}
+
+	series := RecurringSeries{
+		Payee:            payeeOf(last),
+		Category:         last.Category,
+		Type:             entity.TypeExpense,
+		Cadence:          cadence,
+		Occurrences:      len(txs),
+		AverageAmount:    roundAmount(total / float64(len(txs))),
+		LastAmount:       last.Amount,
+		FirstDate:        first.Date,
+		LastDate:         last.Date,
+		NextExpectedDate: next,
+		AccountID:        last.AccountID,
+	}
+	if last.IsIncome() {
+		series.Type = entity.TypeIncome
+	}
+
+	previous := txs[len(txs)-2].Amount
+	if change := (last.Amount - previous) / previous; previous > 0 && math.Abs(change) >= priceChangeThreshold {
+		series.PriceChange = &PriceChangeAlert{
+			PreviousAmount: previous,
+			CurrentAmount:  last.Amount,
+			ChangePercent:  roundAmount(change * 100),
+			ChangedOn:      last.Date,
+		}
+	}
+	return series, true
+}
+
+// matchCadence 找出與間隔中位數相符、且多數間隔落在容許範圍內的週期
+func matchCadence(intervals []float64) (entity.Cadence, bool) {
+	medianInterval := median(intervals)
+	for _, cadence := range entity.Cadences {
+		tolerance := cadenceTolerance(cadence)
+		if math.Abs(medianInterval-float64(cadence.Days())) > tolerance {
+			continue
+		}
+
+		matched := 0
+		for _, interval := range intervals {
+			if math.Abs(interval-float64(cadence.Days())) <= tolerance {
+				matched++
+			}
+		}
+		if float64(matched)/float64(len(intervals)) >= recurringIntervalRatio {
+			return cadence, true
+		}
+	}
+	return "", false
+}
+
+// cadenceTolerance 返回各週期允許的天數誤差（如月份天數不同、假日順延）
+func cadenceTolerance(cadence entity.Cadence) float64 {
+	switch cadence {
+	case entity.CadenceWeekly:
+		return 1
+	case entity.CadenceBiweekly:
+		return 2
+	case entity.CadenceMonthly:
+		return 4
+	case entity.CadenceQuarterly:
+		return 10
+	}
+	return 15
+}
+
+// payeeOf 返回交易的收款方，未填寫時以描述代替
+func payeeOf(tx entity.Transaction) string {
+	if tx.Payee != "" {
+		return tx.Payee
+	}
+	return tx.Description
+}
+
+// seriesKey 返回週期性序列的分組鍵，同一收款方的收入與支出為不同序列
+func seriesKey(payee string, income bool) string {
+	key := normalizePayee(payee)
+	if income {
+		key = entity.TypeIncome + ":" + key
+	}
+	return key
+}
+
+// normalizePayee 統一收款方名稱的大小寫與空白，以便分組比對
+func normalizePayee(payee string) string {
+	return strings.ToLower(strings.Join(strings.Fields(payee), " "))
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// roundAmount 將金額四捨五入至小數點後兩位
+func roundAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
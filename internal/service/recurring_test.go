@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fintrack/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func monthlyCharges(payee string, start time.Time, amounts ...float64) []entity.Transaction {
+	var txs []entity.Transaction
+	for i, amount := range amounts {
+		txs = append(txs, entity.Transaction{
+			ID:       payee + string(rune('a'+i)),
+			UserID:   "user123",
+			Date:     start.AddDate(0, i, 0),
+			Amount:   amount,
+			Category: "SUBSCRIPTION",
+			Payee:    payee,
+		})
+	}
+	return txs
+}
+
+func TestDetectRecurringSeries(t *testing.T) {
+	start := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	asOf := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	txs := monthlyCharges("Netflix", start, 390, 390, 390, 390, 430)
+	// 偶發的大額消費不應被視為週期性
+	txs = append(txs,
+		entity.Transaction{ID: "x1", Date: start, Amount: 120, Payee: "IKEA"},
+		entity.Transaction{ID: "x2", Date: start.AddDate(0, 1, 3), Amount: 5600, Payee: "IKEA"},
+		entity.Transaction{ID: "x3", Date: start.AddDate(0, 4, 0), Amount: 80, Payee: "IKEA"},
+	)
+
+	series := detectRecurringSeries(txs, asOf)
+	assert.Len(t, series, 1)
+
+	netflix := series[0]
+	assert.Equal(t, "Netflix", netflix.Payee)
+	assert.Equal(t, entity.CadenceMonthly, netflix.Cadence)
+	assert.Equal(t, 5, netflix.Occurrences)
+	assert.Equal(t, 398.0, netflix.AverageAmount)
+	assert.Equal(t, time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC), netflix.NextExpectedDate)
+	if assert.NotNil(t, netflix.PriceChange) {
+		assert.Equal(t, 390.0, netflix.PriceChange.PreviousAmount)
+		assert.Equal(t, 430.0, netflix.PriceChange.CurrentAmount)
+		assert.Equal(t, 10.26, netflix.PriceChange.ChangePercent)
+	}
+}
+
+func TestDetectRecurringSeriesSkipsStoppedSubscriptions(t *testing.T) {
+	start := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	asOf := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	series := detectRecurringSeries(monthlyCharges("Gym", start, 999, 999, 999), asOf)
+	assert.Empty(t, series)
+}
+
+func TestMatchCadence(t *testing.T) {
+	cadence, ok := matchCadence([]float64{7, 7, 8, 6})
+	assert.True(t, ok)
+	assert.Equal(t, entity.CadenceWeekly, cadence)
+
+	cadence, ok = matchCadence([]float64{365, 366})
+	assert.True(t, ok)
+	assert.Equal(t, entity.CadenceYearly, cadence)
+
+	_, ok = matchCadence([]float64{3, 45, 12})
+	assert.False(t, ok)
+}
+
+// recurringRepo 記錄儲存的排程，供 PromoteSeries 的測試使用
+type recurringRepo struct {
+	stubRepo
+}
+
+func (r *recurringRepo) SaveRecurringSchedule(schedule entity.RecurringSchedule) error {
+	r.schedules = append(r.schedules, schedule)
+	return nil
+}
+
+func TestPromoteSeriesMatchesType(t *testing.T) {
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 5, 0, 0, 0, 0, time.UTC).AddDate(0, -4, 0)
+
+	// 同一收款方每月有會費支出與回饋金收入，兩者為不同序列
+	repo := &recurringRepo{}
+	repo.transactions = monthlyCharges("Costco", start, 120, 120, 120, 120)
+	for _, tx := range monthlyCharges("Costco", start.AddDate(0, 0, 10), 35, 35, 35, 35) {
+		tx.ID += "-income"
+		tx.Type = entity.TypeIncome
+		repo.transactions = append(repo.transactions, tx)
+	}
+	s := &recurringService{repo: repo, cache: &cacheStub{values: map[string]string{}}}
+
+	_, err := s.PromoteSeries("user123", "costco", "")
+	assert.ErrorIs(t, err, ErrAmbiguousSeries)
+
+	schedule, err := s.PromoteSeries("user123", "costco", entity.TypeIncome)
+	if assert.NoError(t, err) {
+		assert.Equal(t, entity.TypeIncome, schedule.Type)
+		assert.Equal(t, 35.0, schedule.Amount)
+	}
+
+	// 只有收入序列變為受管理，支出序列仍可轉為排程
+	series, err := s.DetectRecurring("user123")
+	assert.NoError(t, err)
+	managed := make(map[string]bool)
+	for _, ser := range series {
+		managed[ser.Type] = ser.Managed
+	}
+	assert.Equal(t, map[string]bool{entity.TypeIncome: true, entity.TypeExpense: false}, managed)
+
+	_, err = s.PromoteSeries("user123", "costco", entity.TypeIncome)
+	assert.ErrorIs(t, err, ErrScheduleExists)
+	schedule, err = s.PromoteSeries("user123", "Costco", entity.TypeExpense)
+	if assert.NoError(t, err) {
+		assert.Equal(t, entity.TypeExpense, schedule.Type)
+	}
+}
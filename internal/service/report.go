@@ -0,0 +1,231 @@
+package service
+
+import (
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"sort"
+	"time"
+)
+
+const (
+	topPayeesLimit           = 5   // 每期列出的支出最高收款方數量
+	largestTransactionsLimit = 5   // 每期列出的最大筆支出數量
+	maxReportPeriods         = 120 // 單次報表的最大期數
+)
+
+// PeriodReport 表示月報或年報
+type PeriodReport struct {
+	User       string          `json:"user"`
+	ReportType string          `json:"report_type"`
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	Periods    []PeriodSummary `json:"periods"`
+}
+
+// PeriodSummary 表示單一期間（月或年）的收支彙總
+type PeriodSummary struct {
+	Period              string               `json:"period"` // YYYY-MM 或 YYYY
+	Income              float64              `json:"income"`
+	Expense             float64              `json:"expense"`
+	NetSavings          float64              `json:"net_savings"`
+	SavingsRate         float64              `json:"savings_rate"` // 淨儲蓄佔收入的百分比，無收入時為 0
+	TransactionCount    int                  `json:"transaction_count"`
+	Categories          []CategorySummary    `json:"categories"`
+	TopPayees           []PayeeSummary       `json:"top_payees"`
+	LargestTransactions []entity.Transaction `json:"largest_transactions"`
+	Delta               *PeriodDelta         `json:"delta"` // 與前一期比較
+}
+
+// CategorySummary 表示某分類在期間內的收支
+type CategorySummary struct {
+	Category string  `json:"category"`
+	Type     string  `json:"type"`
+	Amount   float64 `json:"amount"`
+	Count    int     `json:"count"`
+}
+
+// PayeeSummary 表示某收款方在期間內的支出
+type PayeeSummary struct {
+	Payee  string  `json:"payee"`
+	Amount float64 `json:"amount"`
+	Count  int     `json:"count"`
+}
+
+// PeriodDelta 表示本期與比較期間的差異
+type PeriodDelta struct {
+	Previous   string           `json:"previous"`
+	Income     AmountChange     `json:"income"`
+	Expense    AmountChange     `json:"expense"`
+	NetSavings AmountChange     `json:"net_savings"`
+	Categories []CategoryChange `json:"categories"`
+}
+
+// AmountChange 表示金額的變化，比較基準為 0 時不計算百分比
+type AmountChange struct {
+	Previous float64  `json:"previous"`
+	Current  float64  `json:"current"`
+	Change   float64  `json:"change"`
+	Percent  *float64 `json:"percent"`
+}
+
+// CategoryChange 表示某分類的金額變化
+type CategoryChange struct {
+	Category string `json:"category"`
+	Type     string `json:"type"`
+	AmountChange
+	Significant bool `json:"significant,omitempty"` // 比較報表中增加超過門檻的支出分類
+}
+
+// generatePeriodReport 依月或年彙總收支，彙總在資料庫完成，並多查詢一期以計算首期的變化；
+// 期間依用戶時區與會計年度劃分
+func (s *transactionService) generatePeriodReport(cal userCalendar, userID, reportType, startDate, endDate string, period db.Period) (*PeriodReport, error) {
+	from, to, err := cal.periodRange(startDate, endDate, period, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := cal.bucket(period)
+	summaries, err := aggregatePeriods(s.repo, userID, cal, cal.shiftPeriod(from, period, -1), to, bucket)
+	if err != nil {
+		return nil, err
+	}
+	payees, err := s.repo.GetTopPayees(userID, cal.dbTime(from), cal.dbTime(to), bucket, topPayeesLimit)
+	if err != nil {
+		return nil, err
+	}
+	largest, err := s.repo.GetLargestTransactions(userID, cal.dbTime(from), cal.dbTime(to), bucket, largestTransactionsLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PeriodReport{
+		User:       userID,
+		ReportType: reportType,
+		From:       from.Format(dateLayout),
+		To:         to.AddDate(0, 0, -1).Format(dateLayout),
+	}
+	for start := from; start.Before(to); start = cal.shiftPeriod(start, period, 1) {
+		key := cal.periodKey(start, period)
+		summary := summaryFor(summaries, key)
+		for _, payee := range payees {
+			if payee.Period == key {
+				summary.TopPayees = append(summary.TopPayees, PayeeSummary{Payee: payee.Payee, Amount: roundAmount(payee.Total), Count: payee.Count})
+			}
+		}
+		for _, tx := range largest {
+			if cal.periodKey(tx.Date, period) == key {
+				summary.LargestTransactions = append(summary.LargestTransactions, tx)
+			}
+		}
+		sort.SliceStable(summary.LargestTransactions, func(i, j int) bool {
+			return summary.LargestTransactions[i].Amount > summary.LargestTransactions[j].Amount
+		})
+
+		previous := cal.periodKey(cal.shiftPeriod(start, period, -1), period)
+		summary.Delta = comparePeriods(summaryFor(summaries, previous), summary)
+		report.Periods = append(report.Periods, *summary)
+	}
+	return report, nil
+}
+
+// aggregatePeriods 以資料庫的分類彙總計算 [from, to) 內各期間的收支，鍵為期間代碼
+func aggregatePeriods(repo db.DBClient, userID string, cal userCalendar, from, to time.Time, bucket db.Bucket) (map[string]*PeriodSummary, error) {
+	totals, err := repo.GetCategoryTotals(userID, cal.dbTime(from), cal.dbTime(to), bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[string]*PeriodSummary)
+	for _, total := range totals {
+		summary := summaries[total.Period]
+		if summary == nil {
+			summary = newPeriodSummary(total.Period)
+			summaries[total.Period] = summary
+		}
+		if total.Direction == entity.TypeIncome {
+			summary.Income += total.Total
+		} else {
+			summary.Expense += total.Total
+		}
+		summary.TransactionCount += total.Count
+		summary.Categories = append(summary.Categories, CategorySummary{
+			Category: total.Category,
+			Type:     total.Direction,
+			Amount:   roundAmount(total.Total),
+			Count:    total.Count,
+		})
+	}
+
+	for _, summary := range summaries {
+		summary.Income = roundAmount(summary.Income)
+		summary.Expense = roundAmount(summary.Expense)
+		summary.NetSavings = roundAmount(summary.Income - summary.Expense)
+		if summary.Income > 0 {
+			summary.SavingsRate = roundAmount(summary.NetSavings / summary.Income * 100)
+		}
+		sort.SliceStable(summary.Categories, func(i, j int) bool {
+			return summary.Categories[i].Amount > summary.Categories[j].Amount
+		})
+	}
+	return summaries, nil
+}
+
+func newPeriodSummary(key string) *PeriodSummary {
+	return &PeriodSummary{
+		Period:              key,
+		Categories:          []CategorySummary{},
+		TopPayees:           []PayeeSummary{},
+		LargestTransactions: []entity.Transaction{},
+	}
+}
+
+// summaryFor 返回期間的彙總，沒有交易的期間返回空彙總
+func summaryFor(summaries map[string]*PeriodSummary, key string) *PeriodSummary {
+	if summary := summaries[key]; summary != nil {
+		return summary
+	}
+	return newPeriodSummary(key)
+}
+
+// comparePeriods 比較兩期的收支與各分類金額，任一期有金額的分類都會列出
+func comparePeriods(previous, current *PeriodSummary) *PeriodDelta {
+	delta := &PeriodDelta{
+		Previous:   previous.Period,
+		Income:     newAmountChange(previous.Income, current.Income),
+		Expense:    newAmountChange(previous.Expense, current.Expense),
+		NetSavings: newAmountChange(previous.NetSavings, current.NetSavings),
+		Categories: []CategoryChange{},
+	}
+
+	type key struct{ category, txType string }
+	amounts := make(map[key][2]float64)
+	for _, c := range previous.Categories {
+		v := amounts[key{c.Category, c.Type}]
+		v[0] = c.Amount
+		amounts[key{c.Category, c.Type}] = v
+	}
+	for _, c := range current.Categories {
+		v := amounts[key{c.Category, c.Type}]
+		v[1] = c.Amount
+		amounts[key{c.Category, c.Type}] = v
+	}
+	for k, v := range amounts {
+		delta.Categories = append(delta.Categories, CategoryChange{Category: k.category, Type: k.txType, AmountChange: newAmountChange(v[0], v[1])})
+	}
+	sort.Slice(delta.Categories, func(i, j int) bool {
+		if delta.Categories[i].Type != delta.Categories[j].Type {
+			return delta.Categories[i].Type < delta.Categories[j].Type
+		}
+		return delta.Categories[i].Category < delta.Categories[j].Category
+	})
+	return delta
+}
+
+func newAmountChange(previous, current float64) AmountChange {
+	change := AmountChange{Previous: previous, Current: current, Change: roundAmount(current - previous)}
+	if previous != 0 {
+		percent := roundAmount((current - previous) / previous * 100)
+		change.Percent = &percent
+	}
+	return change
+}
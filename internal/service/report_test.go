@@ -0,0 +1,101 @@
+package service
+
+import (
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// reportRepo 返回預先彙總好的資料，模擬資料庫的彙總結果
+type reportRepo struct {
+	db.DBClient
+	totals  []db.CategoryTotal
+	payees  []db.PayeeTotal
+	largest []entity.Transaction
+
+	settings *entity.UserSettings // 未設定時為 UTC 曆年
+	buckets  []db.Bucket          // 記錄彙總查詢使用的期間劃分
+}
+
+func (r *reportRepo) GetUserSettings(userID string) (*entity.UserSettings, error) {
+	if r.settings != nil {
+		return r.settings, nil
+	}
+	settings := entity.DefaultUserSettings(userID)
+	return &settings, nil
+}
+
+func (r *reportRepo) GetCategoryTotals(userID, startDate, endDate string, bucket db.Bucket) ([]db.CategoryTotal, error) {
+	r.buckets = append(r.buckets, bucket)
+	var result []db.CategoryTotal
+	for _, total := range r.totals {
+		n := len(total.Period)
+		if total.Period >= startDate[:n] && total.Period < endDate[:n] {
+			result = append(result, total)
+		}
+	}
+	return result, nil
+}
+
+func (r *reportRepo) GetTopPayees(userID, startDate, endDate string, bucket db.Bucket, limit int) ([]db.PayeeTotal, error) {
+	return r.payees, nil
+}
+
+func (r *reportRepo) GetLargestTransactions(userID, startDate, endDate string, bucket db.Bucket, limit int) ([]entity.Transaction, error) {
+	return r.largest, nil
+}
+
+func TestGeneratePeriodReport(t *testing.T) {
+	repo := &reportRepo{
+		totals: []db.CategoryTotal{
+			{Period: "2024-02", Category: "SALARY", Direction: entity.TypeIncome, Total: 50000, Count: 1},
+			{Period: "2024-02", Category: "FOOD", Direction: entity.TypeExpense, Total: 8000, Count: 20},
+			{Period: "2024-03", Category: "SALARY", Direction: entity.TypeIncome, Total: 50000, Count: 1},
+			{Period: "2024-03", Category: "FOOD", Direction: entity.TypeExpense, Total: 10000, Count: 25},
+			{Period: "2024-03", Category: "TRAVEL", Direction: entity.TypeExpense, Total: 15000, Count: 2},
+		},
+		payees: []db.PayeeTotal{{Period: "2024-03", Payee: "EVA Air", Total: 14000, Count: 1}},
+		largest: []entity.Transaction{
+			{ID: "t1", Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Amount: 1000},
+			{ID: "t2", Date: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), Amount: 14000},
+		},
+	}
+	s := &transactionService{repo: repo}
+
+	report, err := s.generatePeriodReport(utcCalendar, "user123", ReportTypeMonthly, "2024-03-05", "2024-03-20", db.PeriodMonth)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-03-01", report.From)
+	assert.Equal(t, "2024-03-31", report.To)
+	assert.Len(t, report.Periods, 1)
+
+	march := report.Periods[0]
+	assert.Equal(t, 50000.0, march.Income)
+	assert.Equal(t, 25000.0, march.Expense)
+	assert.Equal(t, 25000.0, march.NetSavings)
+	assert.Equal(t, 50.0, march.SavingsRate)
+	assert.Equal(t, 28, march.TransactionCount)
+	assert.Equal(t, "SALARY", march.Categories[0].Category)
+	assert.Equal(t, "EVA Air", march.TopPayees[0].Payee)
+	assert.Equal(t, "t2", march.LargestTransactions[0].ID)
+
+	// 首期的變化與查詢範圍之前的一期比較
+	assert.Equal(t, "2024-02", march.Delta.Previous)
+	assert.Equal(t, 17000.0, march.Delta.Expense.Change)
+	assert.Equal(t, 212.5, *march.Delta.Expense.Percent)
+	assert.Equal(t, CategoryChange{Category: "TRAVEL", Type: entity.TypeExpense, AmountChange: AmountChange{Current: 15000, Change: 15000}}, march.Delta.Categories[1])
+}
+
+func TestReportPeriodRange(t *testing.T) {
+	now := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	from, to, err := utcCalendar.periodRange("", "", db.PeriodYear, now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), to)
+
+	_, _, err = utcCalendar.periodRange("2024-05-01", "2024-04-30", db.PeriodMonth, now)
+	assert.ErrorIs(t, err, ErrInvalidReportRequest)
+}
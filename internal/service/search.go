@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fintrack/internal/entity"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultSearchSize = 20
+	maxSearchLength   = 200 // 搜尋字串的最大字數
+)
+
+// SearchResult 表示全文檢索的結果，依相關度與日期由高至低排序
+type SearchResult struct {
+	Query        string           `json:"query"`
+	Transactions []TransactionHit `json:"transactions"`
+}
+
+// TransactionHit 表示命中的交易與相關度，相關度越高越符合
+type TransactionHit struct {
+	Transaction entity.Transaction `json:"transaction"`
+	Score       float64            `json:"score"`
+}
+
+// SearchTransactions 以全文索引搜尋說明、收款方與備註，可搭配交易列表的篩選條件。
+// 結果依相關度與日期排序，只返回前 page_size 筆，排序與游標參數不適用
+func (s *transactionService) SearchTransactions(req TransactionListRequest, text string) (*SearchResult, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: q is required", ErrInvalidTransactionQuery)
+	}
+	if utf8.RuneCountInString(text) > maxSearchLength {
+		return nil, fmt.Errorf("%w: q must be at most %d characters", ErrInvalidTransactionQuery, maxSearchLength)
+	}
+	if req.Sort != "" || req.Order != "" || req.Cursor != "" {
+		return nil, fmt.Errorf("%w: search results are ordered by relevance and do not support sort, order or cursor", ErrInvalidTransactionQuery)
+	}
+	if req.PageSize == 0 {
+		req.PageSize = defaultSearchSize
+	}
+
+	cal, err := s.calendar(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := cal.dayRange(cal.resolveRange(req.StartDate, req.EndDate, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	query, err := req.listQuery(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := s.repo.SearchTransactions(query, text)
+	if err != nil {
+		return nil, err
+	}
+	result := &SearchResult{Query: text, Transactions: make([]TransactionHit, len(matches))}
+	for i, match := range matches {
+		tx := match.Transaction
+		tx.Date = tx.Date.In(cal.loc)
+		result.Transactions[i] = TransactionHit{Transaction: tx, Score: match.Score}
+	}
+	return result, nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"errors"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSettings 表示用戶偏好設定不正確
+var ErrInvalidSettings = errors.New("invalid settings")
+
+// 報表日期的快捷範圍，以 start_date 傳入並依用戶時區與會計年度解析，此時忽略 end_date
+const (
+	RangeThisMonth = "this_month"
+	RangeLastMonth = "last_month"
+	RangeThisYear  = "this_year" // 本會計年度
+	RangeLastYear  = "last_year" // 上一會計年度
+)
+
+// dbTimeLayout 為資料庫中以 UTC 儲存的交易時間格式
+const dbTimeLayout = "2006-01-02 15:04:05"
+
+type SettingsService interface {
+	GetSettings(userID string) (*entity.UserSettings, error)
+	SaveSettings(settings entity.UserSettings) (*entity.UserSettings, error)
+}
+
+type settingsService struct {
+	repo db.DBClient
+}
+
+func NewSettingsService(repo db.DBClient) SettingsService {
+	return &settingsService{repo: repo}
+}
+
+// GetSettings 返回用戶的時區與會計年度設定，未設定時為 UTC 與曆年
+func (s *settingsService) GetSettings(userID string) (*entity.UserSettings, error) {
+	return s.repo.GetUserSettings(userID)
+}
+
+// SaveSettings 驗證並保存用戶的時區與會計年度設定，未填寫的欄位使用預設值
+func (s *settingsService) SaveSettings(settings entity.UserSettings) (*entity.UserSettings, error) {
+	if settings.UserID == "" {
+		return nil, fmt.Errorf("%w: user_id is required", ErrInvalidSettings)
+	}
+	settings.TimeZone = strings.TrimSpace(settings.TimeZone)
+	if settings.TimeZone == "" {
+		settings.TimeZone = entity.DefaultTimeZone
+	}
+	if _, err := time.LoadLocation(settings.TimeZone); err != nil {
+		return nil, fmt.Errorf("%w: time_zone must be an IANA time zone such as Asia/Taipei", ErrInvalidSettings)
+	}
+	if settings.FiscalYearStart == 0 {
+		settings.FiscalYearStart = 1
+	}
+	if settings.FiscalYearStart < 1 || settings.FiscalYearStart > 12 {
+		return nil, fmt.Errorf("%w: fiscal_year_start must be a month between 1 and 12", ErrInvalidSettings)
+	}
+	settings.UpdatedAt = time.Now()
+
+	if err := s.repo.SaveUserSettings(settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// userCalendar 依用戶的時區與會計年度劃分日期與報表期間，交易時間以 UTC 儲存
+type userCalendar struct {
+	loc         *time.Location
+	fiscalStart time.Month
+}
+
+// utcCalendar 為未設定偏好時使用的 UTC 曆年
+var utcCalendar = userCalendar{loc: time.UTC, fiscalStart: time.January}
+
+// calendar 查詢用戶的報表偏好並建立對應的行事曆
+func (s *transactionService) calendar(userID string) (userCalendar, error) {
+	settings, err := s.repo.GetUserSettings(userID)
+	if err != nil {
+		return userCalendar{}, err
+	}
+	return userCalendar{loc: settings.Location(), fiscalStart: settings.FiscalStartMonth()}, nil
+}
+
+// cacheKey 返回區分時區與會計年度的快取鍵片段
+func (c userCalendar) cacheKey() string {
+	return c.loc.String() + ":" + fmt.Sprint(int(c.fiscalStart))
+}
+
+// bucket 返回資料庫彙總使用的期間劃分，以時區名稱換算使每筆交易依當時的偏移歸入期間
+func (c userCalendar) bucket(period db.Period) db.Bucket {
+	return db.Bucket{Period: period, TimeZone: c.loc.String(), FiscalStartMonth: int(c.fiscalStart)}
+}
+
+// parseDate 將 YYYY-MM-DD 解析為用戶時區當天的開始
+func (c userCalendar) parseDate(value string) (time.Time, error) {
+	return time.ParseInLocation(dateLayout, value, c.loc)
+}
+
+// dbTime 返回與資料庫比較用的 UTC 時間字串
+func (c userCalendar) dbTime(t time.Time) string {
+	return t.UTC().Format(dbTimeLayout)
+}
+
+// dayRange 將用戶時區的起訖日期轉為資料庫的 UTC 時間範圍，結束日包含當天全日；
+// 未指定的一端返回空字串
+func (c userCalendar) dayRange(startDate, endDate string) (string, string, error) {
+	var from, to string
+	if startDate != "" {
+		start, err := c.parseDate(startDate)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: start_date must be in YYYY-MM-DD format", ErrInvalidReportRequest)
+		}
+		from = c.dbTime(start)
+	}
+	if endDate != "" {
+		end, err := c.parseDate(endDate)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: end_date must be in YYYY-MM-DD format", ErrInvalidReportRequest)
+		}
+		to = c.dbTime(end.AddDate(0, 0, 1).Add(-time.Second))
+	}
+	return from, to, nil
+}
+
+// localize 將交易時間轉為用戶時區，使輸出的日期與報表期間一致
+func (c userCalendar) localize(transactions []entity.Transaction) []entity.Transaction {
+	for i := range transactions {
+		transactions[i].Date = transactions[i].Date.In(c.loc)
+	}
+	return transactions
+}
+
+// resolveRange 將快捷範圍解析為用戶時區的起訖日期，其他值原樣返回
+func (c userCalendar) resolveRange(startDate, endDate string, now time.Time) (string, string) {
+	var from, to time.Time
+	switch strings.ToLower(startDate) {
+	case RangeThisMonth:
+		from = c.periodStart(now, db.PeriodMonth)
+		to = c.shiftPeriod(from, db.PeriodMonth, 1)
+	case RangeLastMonth:
+		to = c.periodStart(now, db.PeriodMonth)
+		from = c.shiftPeriod(to, db.PeriodMonth, -1)
+	case RangeThisYear:
+		from = c.periodStart(now, db.PeriodYear)
+		to = c.shiftPeriod(from, db.PeriodYear, 1)
+	case RangeLastYear:
+		to = c.periodStart(now, db.PeriodYear)
+		from = c.shiftPeriod(to, db.PeriodYear, -1)
+	default:
+		return startDate, endDate
+	}
+	return from.Format(dateLayout), to.AddDate(0, 0, -1).Format(dateLayout)
+}
+
+// periodRange 將報表日期範圍對齊至完整期間，返回首期開始與末期結束後一日，未指定時為本期
+func (c userCalendar) periodRange(startDate, endDate string, period db.Period, now time.Time) (time.Time, time.Time, error) {
+	current := c.periodStart(now, period)
+
+	from := current
+	if startDate != "" {
+		start, err := c.parseDate(startDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: start_date must be in YYYY-MM-DD format", ErrInvalidReportRequest)
+		}
+		from = c.periodStart(start, period)
+	}
+
+	to := c.shiftPeriod(current, period, 1)
+	if endDate != "" {
+		end, err := c.parseDate(endDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: end_date must be in YYYY-MM-DD format", ErrInvalidReportRequest)
+		}
+		to = c.shiftPeriod(c.periodStart(end, period), period, 1)
+	}
+
+	if !from.Before(to) || c.shiftPeriod(from, period, maxReportPeriods).Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: report range must end after it starts and span at most %d periods", ErrInvalidReportRequest, maxReportPeriods)
+	}
+	return from, to, nil
+}
+
+// periodStart 返回時間在用戶時區所屬期間的第一天，年度期間從會計年度起始月份開始
+func (c userCalendar) periodStart(t time.Time, period db.Period) time.Time {
+	t = t.In(c.loc)
+	if period == db.PeriodYear {
+		year := t.Year()
+		if t.Month() < c.fiscalStart {
+			year--
+		}
+		return time.Date(year, c.fiscalStart, 1, 0, 0, 0, 0, c.loc)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, c.loc)
+}
+
+// shiftPeriod 將期間開始日前後移動 n 期
+func (c userCalendar) shiftPeriod(start time.Time, period db.Period, n int) time.Time {
+	if period == db.PeriodYear {
+		return start.AddDate(n, 0, 0)
+	}
+	return start.AddDate(0, n, 0)
+}
+
+// periodKey 返回與資料庫彙總一致的期間代碼，會計年度以起始年份表示
+func (c userCalendar) periodKey(t time.Time, period db.Period) string {
+	if period == db.PeriodYear {
+		return c.periodStart(t, period).Format("2006")
+	}
+	return t.In(c.loc).Format("2006-01")
+}
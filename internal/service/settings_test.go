@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// settingsRepo 記錄保存的用戶偏好
+type settingsRepo struct {
+	db.DBClient
+	saved []entity.UserSettings
+}
+
+func (r *settingsRepo) SaveUserSettings(settings entity.UserSettings) error {
+	r.saved = append(r.saved, settings)
+	return nil
+}
+
+func TestUserCalendar(t *testing.T) {
+	taipei, err := time.LoadLocation("Asia/Taipei")
+	assert.NoError(t, err)
+	cal := userCalendar{loc: taipei, fiscalStart: time.July}
+
+	// 台北時間 4 月 1 日 00:30 在 UTC 仍是 3 月 31 日
+	lateNight := time.Date(2024, 3, 31, 16, 30, 0, 0, time.UTC)
+	assert.Equal(t, "2024-04", cal.periodKey(lateNight, db.PeriodMonth))
+	assert.Equal(t, "2024-03", utcCalendar.periodKey(lateNight, db.PeriodMonth))
+
+	// 會計年度自 7 月開始，以起始年份為代碼
+	assert.Equal(t, "2023", cal.periodKey(time.Date(2024, 5, 1, 0, 0, 0, 0, taipei), db.PeriodYear))
+	assert.Equal(t, "2024", cal.periodKey(time.Date(2024, 7, 1, 0, 0, 0, 0, taipei), db.PeriodYear))
+	assert.Equal(t, db.Bucket{Period: db.PeriodYear, TimeZone: "Asia/Taipei", FiscalStartMonth: 7}, cal.bucket(db.PeriodYear))
+
+	from, to, err := cal.dayRange("2024-03-01", "2024-03-31")
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-02-29 16:00:00", from)
+	assert.Equal(t, "2024-03-31 15:59:59", to)
+
+	now := time.Date(2024, 7, 31, 17, 0, 0, 0, time.UTC) // 台北時間 8 月 1 日
+	start, end := cal.resolveRange(RangeLastMonth, "", now)
+	assert.Equal(t, []string{"2024-07-01", "2024-07-31"}, []string{start, end})
+	start, end = cal.resolveRange(RangeLastYear, "", now)
+	assert.Equal(t, []string{"2023-07-01", "2024-06-30"}, []string{start, end})
+	start, end = cal.resolveRange("2024-01-01", "2024-01-31", now)
+	assert.Equal(t, []string{"2024-01-01", "2024-01-31"}, []string{start, end})
+}
+
+func TestCompareReportsInUserTimeZone(t *testing.T) {
+	repo := &reportRepo{settings: &entity.UserSettings{UserID: "user123", TimeZone: "Asia/Taipei", FiscalYearStart: 1}}
+	s := &transactionService{repo: repo}
+
+	report, err := s.CompareReports(context.Background(), "user123", ComparisonRequest{StartDate: "2024-03-01", EndDate: "2024-03-31", Baselines: []string{BaselinePrevious}})
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-03", report.Current.Period)
+	assert.Equal(t, "2024-02-01", report.Comparisons[0].From)
+	assert.Equal(t, "Asia/Taipei", repo.buckets[0].TimeZone)
+}
+
+func TestPeriodReportAcrossDaylightSaving(t *testing.T) {
+	// 紐約 7 月 31 日 23:30（夏令時間）在 UTC 已是 8 月 1 日；範圍開始於 1 月的標準時間，
+	// 以固定偏移換算時 8 月 1 日 00:30 會被歸入 7 月
+	julyNight := time.Date(2024, 8, 1, 3, 30, 0, 0, time.UTC)
+	augustMorning := time.Date(2024, 8, 1, 4, 30, 0, 0, time.UTC)
+	repo := &reportRepo{
+		settings: &entity.UserSettings{UserID: "user123", TimeZone: "America/New_York", FiscalYearStart: 1},
+		largest: []entity.Transaction{
+			{ID: "july", Date: julyNight, Amount: 100},
+			{ID: "august", Date: augustMorning, Amount: 200},
+		},
+	}
+	s := &transactionService{repo: repo}
+	cal, err := s.calendar("user123")
+	assert.NoError(t, err)
+
+	report, err := s.generatePeriodReport(cal, "user123", ReportTypeMonthly, "2024-01-01", "2024-12-31", db.PeriodMonth)
+	assert.NoError(t, err)
+	assert.Equal(t, "America/New_York", repo.buckets[0].TimeZone)
+	if assert.Len(t, report.Periods, 12) {
+		july, august := report.Periods[6], report.Periods[7]
+		assert.Equal(t, "2024-07", july.Period)
+		assert.Len(t, july.LargestTransactions, 1)
+		assert.Equal(t, "july", july.LargestTransactions[0].ID)
+		assert.Len(t, august.LargestTransactions, 1)
+		assert.Equal(t, "august", august.LargestTransactions[0].ID)
+	}
+}
+
+func TestSaveSettings(t *testing.T) {
+	repo := &settingsRepo{}
+	s := NewSettingsService(repo)
+
+	saved, err := s.SaveSettings(entity.UserSettings{UserID: "user123", TimeZone: " Asia/Taipei "})
+	assert.NoError(t, err)
+	assert.Equal(t, "Asia/Taipei", saved.TimeZone)
+	assert.Equal(t, 1, saved.FiscalYearStart)
+	assert.Len(t, repo.saved, 1)
+
+	_, err = s.SaveSettings(entity.UserSettings{UserID: "user123", TimeZone: "Mars/Olympus"})
+	assert.ErrorIs(t, err, ErrInvalidSettings)
+	_, err = s.SaveSettings(entity.UserSettings{UserID: "user123", FiscalYearStart: 13})
+	assert.ErrorIs(t, err, ErrInvalidSettings)
+}
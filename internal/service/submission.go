@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fintrack/internal/cache"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fintrack/internal/validation"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrSubmissionNotFound 表示查詢的提交紀錄不存在或已過期
+var ErrSubmissionNotFound = errors.New("submission not found")
+
+// 提交的處理狀態
+const (
+	SubmissionPending  = "PENDING"  // 已送入隊列，尚未處理
+	SubmissionSaved    = "SAVED"    // 已寫入資料庫
+	SubmissionRejected = "REJECTED" // 處理失敗，原因記錄於 Reason
+)
+
+const (
+	submissionTTL          = 24 * time.Hour         // 提交紀錄在 Redis 保留的時間
+	submissionWaitTimeout  = 5 * time.Second        // wait=true 時最長等待時間
+	submissionPollInterval = 100 * time.Millisecond // 等待時查詢狀態的間隔
+)
+
+// Submission 表示一次非同步的交易異動及其處理狀態
+type Submission struct {
+	ID            string                  `json:"id"`
+	UserID        string                  `json:"user_id"`
+	Event         string                  `json:"event"`
+	TransactionID string                  `json:"transaction_id"`
+	Transaction   *entity.Transaction     `json:"transaction,omitempty"` // 新增時送出的交易；修改完成後為保存的交易
+	Patch         *TransactionPatch       `json:"patch,omitempty"`       // 修改時送出的欄位
+	Status        string                  `json:"status"`
+	Reason        string                  `json:"reason,omitempty"`
+	Code          string                  `json:"code,omitempty"`    // 處理失敗的錯誤碼，與 HTTP API 相同
+	Details       []validation.FieldError `json:"details,omitempty"` // 驗證失敗的欄位
+	CreatedAt     time.Time               `json:"created_at"`
+	UpdatedAt     time.Time               `json:"updated_at"`
+}
+
+// Done 判斷提交是否已處理完成
+func (s *Submission) Done() bool {
+	return s.Status != SubmissionPending
+}
+
+func submissionKey(id string) string {
+	return "submission:" + id
+}
+
+// newSubmission 為交易事件建立待處理的提交紀錄
+func newSubmission(event TransactionEvent) *Submission {
+	now := time.Now()
+	submission := &Submission{
+		ID:            uuid.NewString(),
+		UserID:        event.UserID,
+		Event:         event.Event,
+		TransactionID: event.Transaction.ID,
+		Status:        SubmissionPending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	switch event.Event {
+	case EventTransactionCreated:
+		tx := event.Transaction
+		submission.Transaction = &tx
+	case EventTransactionUpdated:
+		submission.Patch = event.Patch
+	}
+	return submission
+}
+
+// saveSubmission 將提交紀錄寫入 Redis
+func saveSubmission(ctx context.Context, c cache.Cache, submission *Submission) error {
+	data, err := json.Marshal(submission)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, submissionKey(submission.ID), data, submissionTTL)
+}
+
+// loadSubmission 從 Redis 讀取提交紀錄
+func loadSubmission(ctx context.Context, c cache.Cache, id string) (*Submission, error) {
+	data, err := c.Get(ctx, submissionKey(id))
+	if errors.Is(err, cache.ErrNotFound) {
+		return nil, ErrSubmissionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var submission Submission
+	if err := json.Unmarshal([]byte(data), &submission); err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// completeSubmission 記錄消費者的處理結果與錯誤碼，saved 不為 nil 時記錄保存後的交易；
+// 只有驗證失敗或交易不存在時才返回錯誤原因，其他錯誤以通用訊息代替
+func completeSubmission(ctx context.Context, c cache.Cache, id string, saved *entity.Transaction, processErr error) error {
+	submission, err := loadSubmission(ctx, c, id)
+	if err != nil {
+		return err
+	}
+	submission.Status = SubmissionSaved
+	if saved != nil {
+		submission.Transaction = saved
+	}
+	if processErr != nil {
+		resp := validation.NewResponse(validation.CodeInternal, errors.New("failed to save transaction"))
+		switch {
+		case errors.Is(processErr, ErrInvalidTransaction):
+			resp = validation.NewResponse(validation.CodeInvalidRequest, processErr)
+		case errors.Is(processErr, db.ErrTransactionNotFound):
+			resp = validation.NewResponse(validation.CodeNotFound, processErr)
+		}
+		submission.Status = SubmissionRejected
+		submission.Reason, submission.Code, submission.Details = resp.Error, resp.Code, resp.Details
+	}
+	submission.UpdatedAt = time.Now()
+	return saveSubmission(ctx, c, submission)
+}
+
+// GetSubmission 查詢用戶的提交狀態，wait 為 true 時等待至處理完成或逾時，逾時仍返回 PENDING
+func (s *transactionService) GetSubmission(ctx context.Context, userID, submissionID string, wait bool) (*Submission, error) {
+	timeout := time.NewTimer(submissionWaitTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(submissionPollInterval)
+	defer ticker.Stop()
+	for {
+		submission, err := loadSubmission(ctx, s.cache, submissionID)
+		if err != nil {
+			return nil, err
+		}
+		if submission.UserID != userID {
+			return nil, ErrSubmissionNotFound
+		}
+		if !wait || submission.Done() {
+			return submission, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout.C:
+			return submission, nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fintrack/internal/export"
+	"fintrack/internal/mail"
+	"fmt"
+	"log"
+	netmail "net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidSubscription 表示報表訂閱資料不正確
+var ErrInvalidSubscription = errors.New("invalid subscription")
+
+const (
+	formatJSON             = "json"
+	defaultDeliveryFormat  = export.FormatPDF
+	maxDeliveryAttempts    = 5
+	deliveryRetryBaseDelay = 5 * time.Minute // 第 n 次失敗後等待 5 分鐘 × 2^(n-1)
+	deliveryBatchSize      = 50              // 每次排程最多處理的寄送數量
+)
+
+// subscribableReports 為可訂閱的報表類型
+var subscribableReports = map[string]bool{
+	ReportTypeMonthly:  true,
+	ReportTypeAnnual:   true,
+	ReportTypeNetWorth: true,
+}
+
+type SubscriptionService interface {
+	CreateSubscription(subscription entity.ReportSubscription) (*entity.ReportSubscription, error)
+	GetSubscriptions(userID string) ([]entity.ReportSubscription, error)
+	DeleteSubscription(userID, subscriptionID string) error
+	GetDeliveries(userID, subscriptionID string) ([]entity.ReportDelivery, error)
+	RunDue(ctx context.Context, now time.Time) error
+}
+
+type subscriptionService struct {
+	repo    db.DBClient
+	reports TransactionService
+	sender  mail.Sender
+}
+
+func NewSubscriptionService(repo db.DBClient, reports TransactionService, sender mail.Sender) SubscriptionService {
+	return &subscriptionService{repo: repo, reports: reports, sender: sender}
+}
+
+// CreateSubscription 驗證並建立報表訂閱，未指定首次寄送時間時為下一個週期的第一天
+func (s *subscriptionService) CreateSubscription(subscription entity.ReportSubscription) (*entity.ReportSubscription, error) {
+	if subscription.UserID == "" {
+		return nil, fmt.Errorf("%w: user_id is required", ErrInvalidSubscription)
+	}
+	if _, err := netmail.ParseAddress(subscription.Recipient); err != nil {
+		return nil, fmt.Errorf("%w: recipient must be an email address", ErrInvalidSubscription)
+	}
+
+	subscription.ReportType = strings.ToLower(subscription.ReportType)
+	if subscription.ReportType == "" {
+		subscription.ReportType = ReportTypeMonthly
+	}
+	if !subscribableReports[subscription.ReportType] {
+		return nil, fmt.Errorf("%w: report_type must be monthly, annual or net_worth", ErrInvalidSubscription)
+	}
+	subscription.Format = strings.ToLower(subscription.Format)
+	if subscription.Format == "" {
+		subscription.Format = defaultDeliveryFormat
+	}
+	if subscription.Format != formatJSON && !export.Supported(subscription.Format) {
+		return nil, fmt.Errorf("%w: format must be json, csv, xlsx or pdf", ErrInvalidSubscription)
+	}
+	if subscription.Cadence == "" {
+		subscription.Cadence = entity.CadenceMonthly
+	}
+	if !subscription.Cadence.Valid() {
+		return nil, fmt.Errorf("%w: cadence must be WEEKLY, BIWEEKLY, MONTHLY, QUARTERLY or YEARLY", ErrInvalidSubscription)
+	}
+
+	now := time.Now().UTC()
+	if subscription.NextRunAt.IsZero() {
+		subscription.NextRunAt = firstRunAt(subscription.Cadence, now)
+	}
+	subscription.ID = uuid.NewString()
+	subscription.Active = true
+	subscription.CreatedAt = now
+	if err := s.repo.SaveSubscription(subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// GetSubscriptions 查詢用戶的報表訂閱
+func (s *subscriptionService) GetSubscriptions(userID string) ([]entity.ReportSubscription, error) {
+	return s.repo.GetSubscriptions(userID)
+}
+
+// DeleteSubscription 取消用戶的報表訂閱
+func (s *subscriptionService) DeleteSubscription(userID, subscriptionID string) error {
+	if _, err := s.subscription(userID, subscriptionID); err != nil {
+		return err
+	}
+	return s.repo.DeleteSubscription(subscriptionID)
+}
+
+// GetDeliveries 查詢訂閱的寄送紀錄
+func (s *subscriptionService) GetDeliveries(userID, subscriptionID string) ([]entity.ReportDelivery, error) {
+	if _, err := s.subscription(userID, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDeliveries(subscriptionID)
+}
+
+// RunDue 為到期的訂閱建立寄送紀錄，再寄送所有到期或待重試的報表。
+// 停機期間錯過多個週期時只寄送最近一期；同一期間的寄送紀錄 ID 相同，重複執行不會重複寄送
+func (s *subscriptionService) RunDue(ctx context.Context, now time.Time) error {
+	subscriptions, err := s.repo.GetDueSubscriptions(now)
+	if err != nil {
+		return err
+	}
+	for _, subscription := range subscriptions {
+		runAt := subscription.NextRunAt
+		for !subscription.Cadence.Next(runAt).After(now) {
+			runAt = subscription.Cadence.Next(runAt)
+		}
+		start, end := subscription.ReportPeriod(runAt)
+		delivery := entity.ReportDelivery{
+			ID:             subscription.ID + "-" + start.Format("20060102"),
+			SubscriptionID: subscription.ID,
+			UserID:         subscription.UserID,
+			Recipient:      subscription.Recipient,
+			PeriodStart:    start,
+			PeriodEnd:      end,
+			Status:         entity.DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		}
+		if err := s.repo.CreateDeliveries([]entity.ReportDelivery{delivery}); err != nil {
+			return err
+		}
+		subscription.NextRunAt = subscription.Cadence.Next(runAt)
+		if err := s.repo.SaveSubscription(subscription); err != nil {
+			return err
+		}
+	}
+
+	deliveries, err := s.repo.GetPendingDeliveries(now, deliveryBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, delivery := range deliveries {
+		if err := s.deliver(ctx, delivery, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver 產生並寄送一份報表，失敗時以指數退避安排重試，超過次數後標記為失敗
+func (s *subscriptionService) deliver(ctx context.Context, delivery entity.ReportDelivery, now time.Time) error {
+	delivery.Attempts++
+	sendErr := s.send(ctx, delivery)
+	switch {
+	case sendErr == nil:
+		delivery.Status = entity.DeliverySent
+		delivery.SentAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= maxDeliveryAttempts || errors.Is(sendErr, db.ErrSubscriptionNotFound):
+		delivery.Status = entity.DeliveryFailed
+		delivery.LastError = sendErr.Error()
+	default:
+		delivery.LastError = sendErr.Error()
+		delivery.NextAttemptAt = now.Add(deliveryRetryBaseDelay << (delivery.Attempts - 1))
+	}
+	if sendErr != nil {
+		log.Printf("Failed to deliver report %s (attempt %d): %v", delivery.ID, delivery.Attempts, sendErr)
+	}
+	return s.repo.SaveDelivery(delivery)
+}
+
+func (s *subscriptionService) send(ctx context.Context, delivery entity.ReportDelivery) error {
+	subscription, err := s.repo.GetSubscriptionByID(delivery.SubscriptionID)
+	if err != nil {
+		return err
+	}
+	start, end := delivery.PeriodStart.Format(dateLayout), delivery.PeriodEnd.Format(dateLayout)
+
+	var data []byte
+	contentType := "application/json"
+	if subscription.Format == formatJSON {
+		report, err := s.reports.GenerateReport(ctx, subscription.UserID, subscription.ReportType, start, end)
+		if err != nil {
+			return err
+		}
+		if data, err = json.MarshalIndent(report, "", "  "); err != nil {
+			return err
+		}
+	} else {
+		doc, err := s.reports.ExportReport(ctx, subscription.UserID, subscription.ReportType, start, end)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := export.Write(&buf, doc, subscription.Format); err != nil {
+			return err
+		}
+		data, contentType = buf.Bytes(), export.ContentType(subscription.Format)
+	}
+
+	title := fmt.Sprintf("FinTrack %s report %s - %s", subscription.ReportType, start, end)
+	return s.sender.Send(mail.Message{
+		To:      []string{delivery.Recipient},
+		Subject: title,
+		Body:    title + "\r\n\r\nThe report is attached.",
+		Attachments: []mail.Attachment{{
+			Filename:    fmt.Sprintf("report-%s-%s-%s.%s", subscription.ReportType, start, end, subscription.Format),
+			ContentType: contentType,
+			Data:        data,
+		}},
+	})
+}
+
+// subscription 查詢屬於該用戶的訂閱
+func (s *subscriptionService) subscription(userID, subscriptionID string) (*entity.ReportSubscription, error) {
+	subscription, err := s.repo.GetSubscriptionByID(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.UserID != userID {
+		return nil, db.ErrSubscriptionNotFound
+	}
+	return subscription, nil
+}
+
+// firstRunAt 返回下一個週期的第一天：週報為下週一，月報、季報與年報為下個月、季或年的第一天
+func firstRunAt(cadence entity.Cadence, now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch cadence {
+	case entity.CadenceMonthly:
+		return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	case entity.CadenceQuarterly:
+		quarter := (int(now.Month()) - 1) / 3
+		return time.Date(now.Year(), time.Month(quarter*3+4), 1, 0, 0, 0, 0, time.UTC)
+	case entity.CadenceYearly:
+		return time.Date(now.Year()+1, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := (8 - int(today.Weekday())) % 7
+	if days == 0 {
+		days = 7
+	}
+	return today.AddDate(0, 0, days)
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fintrack/internal/export"
+	"fintrack/internal/mail"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// subscriptionRepo 以記憶體保存訂閱與寄送紀錄
+type subscriptionRepo struct {
+	db.DBClient
+	subscriptions map[string]entity.ReportSubscription
+	deliveries    map[string]entity.ReportDelivery
+}
+
+func (r *subscriptionRepo) SaveSubscription(subscription entity.ReportSubscription) error {
+	r.subscriptions[subscription.ID] = subscription
+	return nil
+}
+
+func (r *subscriptionRepo) GetSubscriptionByID(subscriptionID string) (*entity.ReportSubscription, error) {
+	subscription, ok := r.subscriptions[subscriptionID]
+	if !ok {
+		return nil, db.ErrSubscriptionNotFound
+	}
+	return &subscription, nil
+}
+
+func (r *subscriptionRepo) GetDueSubscriptions(now time.Time) ([]entity.ReportSubscription, error) {
+	var due []entity.ReportSubscription
+	for _, subscription := range r.subscriptions {
+		if subscription.Active && !subscription.NextRunAt.After(now) {
+			due = append(due, subscription)
+		}
+	}
+	return due, nil
+}
+
+func (r *subscriptionRepo) CreateDeliveries(deliveries []entity.ReportDelivery) error {
+	for _, delivery := range deliveries {
+		if _, ok := r.deliveries[delivery.ID]; !ok {
+			r.deliveries[delivery.ID] = delivery
+		}
+	}
+	return nil
+}
+
+func (r *subscriptionRepo) SaveDelivery(delivery entity.ReportDelivery) error {
+	r.deliveries[delivery.ID] = delivery
+	return nil
+}
+
+func (r *subscriptionRepo) GetPendingDeliveries(now time.Time, limit int) ([]entity.ReportDelivery, error) {
+	var pending []entity.ReportDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status == entity.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			pending = append(pending, delivery)
+		}
+	}
+	return pending, nil
+}
+
+// reportStub 返回固定的報表並記錄查詢的期間
+type reportStub struct {
+	TransactionService
+	periods []string
+}
+
+func (r *reportStub) ExportReport(ctx context.Context, userID, reportType, startDate, endDate string) (*export.Document, error) {
+	r.periods = append(r.periods, startDate+"/"+endDate)
+	return &export.Document{Tables: []export.Table{{Name: "Summary", Header: []string{"Period"}, Rows: [][]interface{}{{"2024-03"}}}}}, nil
+}
+
+// senderStub 記錄寄出的郵件，err 不為 nil 時模擬寄送失敗
+type senderStub struct {
+	sent []mail.Message
+	err  error
+}
+
+func (s *senderStub) Send(msg mail.Message) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func TestRunDueDeliversLastPeriod(t *testing.T) {
+	repo := &subscriptionRepo{
+		subscriptions: map[string]entity.ReportSubscription{
+			"sub1": {ID: "sub1", UserID: "user123", ReportType: ReportTypeMonthly, Format: export.FormatCSV, Cadence: entity.CadenceMonthly,
+				Recipient: "user@example.com", NextRunAt: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), Active: true},
+		},
+		deliveries: map[string]entity.ReportDelivery{},
+	}
+	reports, sender := &reportStub{}, &senderStub{}
+	s := NewSubscriptionService(repo, reports, sender)
+
+	now := time.Date(2024, 4, 1, 0, 1, 0, 0, time.UTC)
+	assert.NoError(t, s.RunDue(context.Background(), now))
+	// 同一時間再次執行不會重複寄送
+	assert.NoError(t, s.RunDue(context.Background(), now))
+
+	assert.Equal(t, []string{"2024-03-01/2024-03-31"}, reports.periods)
+	assert.Len(t, sender.sent, 1)
+	assert.Equal(t, []string{"user@example.com"}, sender.sent[0].To)
+	assert.Equal(t, "report-monthly-2024-03-01-2024-03-31.csv", sender.sent[0].Attachments[0].Filename)
+
+	delivery := repo.deliveries["sub1-20240301"]
+	assert.Equal(t, entity.DeliverySent, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), repo.subscriptions["sub1"].NextRunAt)
+
+	// 停機錯過兩期時只寄送最近一期
+	now = time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, s.RunDue(context.Background(), now))
+	assert.Equal(t, "2024-05-01/2024-05-31", reports.periods[1])
+	assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), repo.subscriptions["sub1"].NextRunAt)
+}
+
+func TestRunDueRetriesFailedDelivery(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	repo := &subscriptionRepo{
+		subscriptions: map[string]entity.ReportSubscription{
+			"sub1": {ID: "sub1", UserID: "user123", ReportType: ReportTypeMonthly, Format: export.FormatPDF, Cadence: entity.CadenceMonthly,
+				Recipient: "user@example.com", NextRunAt: time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC), Active: true},
+		},
+		deliveries: map[string]entity.ReportDelivery{
+			"sub1-20240301": {ID: "sub1-20240301", SubscriptionID: "sub1", Recipient: "user@example.com", PeriodStart: start,
+				PeriodEnd: start.AddDate(0, 1, -1), Status: entity.DeliveryPending, NextAttemptAt: start},
+		},
+	}
+	sender := &senderStub{err: errors.New("connection refused")}
+	s := NewSubscriptionService(repo, &reportStub{}, sender)
+
+	now := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, s.RunDue(context.Background(), now))
+	delivery := repo.deliveries["sub1-20240301"]
+	assert.Equal(t, entity.DeliveryPending, delivery.Status)
+	assert.Equal(t, "connection refused", delivery.LastError)
+	assert.Equal(t, now.Add(5*time.Minute), delivery.NextAttemptAt)
+
+	// 重試間隔以指數增加，超過次數後標記為失敗
+	for attempt := 2; attempt <= maxDeliveryAttempts; attempt++ {
+		now = repo.deliveries["sub1-20240301"].NextAttemptAt
+		assert.NoError(t, s.RunDue(context.Background(), now))
+	}
+	delivery = repo.deliveries["sub1-20240301"]
+	assert.Equal(t, entity.DeliveryFailed, delivery.Status)
+	assert.Equal(t, maxDeliveryAttempts, delivery.Attempts)
+	assert.Empty(t, sender.sent)
+}
+
+func TestCreateSubscriptionDefaults(t *testing.T) {
+	repo := &subscriptionRepo{subscriptions: map[string]entity.ReportSubscription{}}
+	s := NewSubscriptionService(repo, &reportStub{}, &senderStub{})
+
+	created, err := s.CreateSubscription(entity.ReportSubscription{UserID: "user123", Recipient: "user@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, ReportTypeMonthly, created.ReportType)
+	assert.Equal(t, export.FormatPDF, created.Format)
+	assert.Equal(t, entity.CadenceMonthly, created.Cadence)
+	assert.Equal(t, 1, created.NextRunAt.Day())
+	assert.True(t, created.NextRunAt.After(time.Now()))
+
+	_, err = s.CreateSubscription(entity.ReportSubscription{UserID: "user123", Recipient: "not-an-email"})
+	assert.ErrorIs(t, err, ErrInvalidSubscription)
+	_, err = s.CreateSubscription(entity.ReportSubscription{UserID: "user123", Recipient: "user@example.com", Format: "docx"})
+	assert.ErrorIs(t, err, ErrInvalidSubscription)
+}
+
+func TestFirstRunAt(t *testing.T) {
+	now := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC) // 星期三
+	assert.Equal(t, time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC), firstRunAt(entity.CadenceWeekly, now))
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), firstRunAt(entity.CadenceMonthly, now))
+	assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), firstRunAt(entity.CadenceQuarterly, now))
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), firstRunAt(entity.CadenceYearly, now))
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"errors"
+	"fintrack/internal/db"
+	"fintrack/internal/entity"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidTaxMapping 表示稅務類別對應不正確
+var ErrInvalidTaxMapping = errors.New("invalid tax mapping")
+
+// TaxSummaryReport 表示某稅務年度（1 月 1 日至 12 月 31 日）各所得類別與扣除項目的合計
+type TaxSummaryReport struct {
+	User            string             `json:"user"`
+	TaxYear         int                `json:"tax_year"`
+	From            string             `json:"from"`
+	To              string             `json:"to"`
+	Income          []TaxBucketSummary `json:"income"`
+	Deductions      []TaxBucketSummary `json:"deductions"`
+	TotalIncome     float64            `json:"total_income"`
+	TotalDeductions float64            `json:"total_deductions"`
+}
+
+// TaxBucketSummary 表示單一類別的合計與佐證交易
+type TaxBucketSummary struct {
+	Bucket       entity.TaxBucket     `json:"bucket"`
+	Name         string               `json:"name"`
+	Total        float64              `json:"total"`
+	Count        int                  `json:"count"`
+	Transactions []entity.Transaction `json:"transactions"`
+	Dividends    []entity.Trade       `json:"dividends,omitempty"` // 投資帳戶的現金股利
+}
+
+type TaxService interface {
+	GetTaxMappings(userID string) ([]entity.TaxMapping, error)
+	SaveTaxMappings(userID string, mappings []entity.TaxMapping) ([]entity.TaxMapping, error)
+}
+
+type taxService struct {
+	repo db.DBClient
+}
+
+func NewTaxService(repo db.DBClient) TaxService {
+	return &taxService{repo: repo}
+}
+
+// GetTaxMappings 返回用戶生效中的稅務類別對應，未設定時為預設對應
+func (s *taxService) GetTaxMappings(userID string) ([]entity.TaxMapping, error) {
+	return taxMappings(s.repo, userID)
+}
+
+// SaveTaxMappings 驗證並取代用戶的稅務類別對應，傳入空陣列時恢復預設對應
+func (s *taxService) SaveTaxMappings(userID string, mappings []entity.TaxMapping) ([]entity.TaxMapping, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("%w: user_id is required", ErrInvalidTaxMapping)
+	}
+	seen := make(map[string]bool)
+	for i := range mappings {
+		m := &mappings[i]
+		m.UserID = userID
+		m.Match = strings.ToUpper(m.Match)
+		if m.Match == "" {
+			m.Match = entity.TaxMatchCategory
+		}
+		m.Value = strings.TrimSpace(m.Value)
+		if m.Match != entity.TaxMatchCategory && m.Match != entity.TaxMatchTag {
+			return nil, fmt.Errorf("%w: match must be CATEGORY or TAG", ErrInvalidTaxMapping)
+		}
+		if m.Value == "" || !m.Bucket.Valid() {
+			return nil, fmt.Errorf("%w: value is required and bucket must be a supported tax bucket", ErrInvalidTaxMapping)
+		}
+		key := m.Match + ":" + m.Value
+		if seen[key] {
+			return nil, fmt.Errorf("%w: %s %s is mapped more than once", ErrInvalidTaxMapping, m.Match, m.Value)
+		}
+		seen[key] = true
+	}
+
+	if err := s.repo.ReplaceTaxMappings(userID, mappings); err != nil {
+		return nil, err
+	}
+	return taxMappings(s.repo, userID)
+}
+
+// taxMappings 查詢用戶的稅務類別對應，未設定時返回預設對應
+func taxMappings(repo db.DBClient, userID string) ([]entity.TaxMapping, error) {
+	mappings, err := repo.GetTaxMappings(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(mappings) > 0 {
+		return mappings, nil
+	}
+	defaults := make([]entity.TaxMapping, len(entity.DefaultTaxMappings))
+	for i, m := range entity.DefaultTaxMappings {
+		m.UserID = userID
+		defaults[i] = m
+	}
+	return defaults, nil
+}
+
+// generateTaxSummary 依稅務類別對應彙總稅務年度的交易，標籤的對應優先於分類。
+// 所得類別中的支出與扣除項目中的收入（如退款）會抵減合計；投資帳戶的現金股利計入股利所得。
+// 稅務年度固定為曆年，但交易依用戶時區歸入年度
+func (s *transactionService) generateTaxSummary(cal userCalendar, userID, startDate string) (*TaxSummaryReport, error) {
+	year, err := taxYear(startDate, time.Now().In(cal.loc))
+	if err != nil {
+		return nil, err
+	}
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, cal.loc)
+	to := time.Date(year, 12, 31, 0, 0, 0, 0, cal.loc)
+
+	mappings, err := taxMappings(s.repo, userID)
+	if err != nil {
+		return nil, err
+	}
+	byCategory := make(map[string]entity.TaxBucket)
+	byTag := make(map[string]entity.TaxBucket)
+	for _, m := range mappings {
+		if m.Match == entity.TaxMatchTag {
+			byTag[m.Value] = m.Bucket
+		} else {
+			byCategory[m.Value] = m.Bucket
+		}
+	}
+
+	transactions, err := s.repo.GetTransactions(userID, cal.dbTime(from), cal.dbTime(to.AddDate(0, 0, 1).Add(-time.Second)))
+	if err != nil {
+		return nil, err
+	}
+	transactions = cal.localize(transactions)
+	sort.SliceStable(transactions, func(i, j int) bool { return transactions[i].Date.Before(transactions[j].Date) })
+
+	buckets := make(map[entity.TaxBucket]*TaxBucketSummary)
+	for _, t := range entity.TaxBuckets {
+		buckets[t.Bucket] = &TaxBucketSummary{Bucket: t.Bucket, Name: t.Name, Transactions: []entity.Transaction{}}
+	}
+	for _, tx := range transactions {
+		bucket, ok := byCategory[tx.Category]
+		for _, tag := range tx.TagList() {
+			if b, found := byTag[tag]; found {
+				bucket, ok = b, true
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		summary := buckets[bucket]
+		amount := tx.SignedAmount()
+		if !bucket.IsIncome() {
+			amount = -amount
+		}
+		summary.Total += amount
+		summary.Count++
+		summary.Transactions = append(summary.Transactions, tx)
+	}
+
+	trades, err := s.repo.GetTrades(userID, to.AddDate(0, 0, 1).Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+	// 投資交易只記錄日期，以 UTC 日期比較
+	dividends := buckets[entity.TaxDividendIncome]
+	for _, trade := range trades {
+		if trade.Type == entity.TradeDividend && trade.Date.Year() == year {
+			dividends.Total += trade.Amount
+			dividends.Count++
+			dividends.Dividends = append(dividends.Dividends, trade)
+		}
+	}
+
+	report := &TaxSummaryReport{
+		User:       userID,
+		TaxYear:    year,
+		From:       from.Format(dateLayout),
+		To:         to.Format(dateLayout),
+		Income:     []TaxBucketSummary{},
+		Deductions: []TaxBucketSummary{},
+	}
+	for _, t := range entity.TaxBuckets {
+		summary := buckets[t.Bucket]
+		summary.Total = roundAmount(summary.Total)
+		if t.Bucket.IsIncome() {
+			report.Income = append(report.Income, *summary)
+			report.TotalIncome += summary.Total
+		} else {
+			report.Deductions = append(report.Deductions, *summary)
+			report.TotalDeductions += summary.Total
+		}
+	}
+	report.TotalIncome = roundAmount(report.TotalIncome)
+	report.TotalDeductions = roundAmount(report.TotalDeductions)
+	return report, nil
+}
+
+// taxYear 返回 start_date 所屬的年度，未指定時為去年（五月申報上一年度的所得）
+func taxYear(startDate string, now time.Time) (int, error) {
+	if startDate == "" {
+		return now.Year() - 1, nil
+	}
+	start, err := time.Parse(dateLayout, startDate)
+	if err != nil {
+		return 0, fmt.Errorf("%w: start_date must be in YYYY-MM-DD format", ErrInvalidReportRequest)
+	}
+	return start.Year(), nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"bytes"
+	"fintrack/internal/entity"
+	"fintrack/internal/export"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// taxRepo 在 stubRepo 之上提供稅務類別對應與投資交易
+type taxRepo struct {
+	*stubRepo
+	mappings []entity.TaxMapping
+	trades   []entity.Trade
+}
+
+func (r *taxRepo) GetTaxMappings(userID string) ([]entity.TaxMapping, error) {
+	return r.mappings, nil
+}
+
+func (r *taxRepo) GetTrades(userID, before string) ([]entity.Trade, error) {
+	return r.trades, nil
+}
+
+func TestGenerateTaxSummary(t *testing.T) {
+	day := func(month, d int) time.Time { return time.Date(2024, time.Month(month), d, 0, 0, 0, 0, time.UTC) }
+	repo := &taxRepo{
+		stubRepo: &stubRepo{transactions: []entity.Transaction{
+			{ID: "t1", UserID: "user123", Date: day(1, 5), Amount: 60000, Category: "SALARY", Type: entity.TypeIncome},
+			{ID: "t2", UserID: "user123", Date: day(3, 1), Amount: 12000, Category: "INSURANCE"},
+			{ID: "t3", UserID: "user123", Date: day(4, 2), Amount: 3000, Category: "MEDICAL"},
+			{ID: "t4", UserID: "user123", Date: day(4, 9), Amount: 500, Category: "MEDICAL", Type: entity.TypeIncome}, // 退款
+			{ID: "t5", UserID: "user123", Date: day(5, 1), Amount: 1000, Category: "FOOD", Tags: "charity, 捐款"},
+			{ID: "t6", UserID: "user123", Date: day(6, 1), Amount: 800, Category: "FOOD"},
+			{ID: "t7", UserID: "user123", Date: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), Amount: 9999, Category: "MEDICAL"},
+		}},
+		mappings: []entity.TaxMapping{
+			{Match: entity.TaxMatchCategory, Value: "SALARY", Bucket: entity.TaxSalary},
+			{Match: entity.TaxMatchCategory, Value: "INSURANCE", Bucket: entity.TaxInsurance},
+			{Match: entity.TaxMatchCategory, Value: "MEDICAL", Bucket: entity.TaxMedical},
+			{Match: entity.TaxMatchTag, Value: "charity", Bucket: entity.TaxDonation},
+		},
+		trades: []entity.Trade{
+			{ID: "d1", Symbol: "2330", Type: entity.TradeDividend, Date: day(7, 11), Amount: 450},
+			{ID: "d0", Symbol: "2330", Type: entity.TradeDividend, Date: time.Date(2023, 7, 11, 0, 0, 0, 0, time.UTC), Amount: 400},
+		},
+	}
+
+	s := &transactionService{repo: repo}
+	report, err := s.generateTaxSummary(utcCalendar, "user123", "2024-06-01")
+	assert.NoError(t, err)
+	assert.Equal(t, 2024, report.TaxYear)
+	assert.Equal(t, "2024-01-01", report.From)
+
+	buckets := make(map[entity.TaxBucket]TaxBucketSummary)
+	for _, b := range append(report.Income, report.Deductions...) {
+		buckets[b.Bucket] = b
+	}
+	assert.Len(t, buckets, len(entity.TaxBuckets))
+	assert.Equal(t, 60000.0, buckets[entity.TaxSalary].Total)
+	assert.Equal(t, 450.0, buckets[entity.TaxDividendIncome].Total)
+	assert.Len(t, buckets[entity.TaxDividendIncome].Dividends, 1)
+	assert.Equal(t, 12000.0, buckets[entity.TaxInsurance].Total)
+	assert.Equal(t, 2500.0, buckets[entity.TaxMedical].Total)
+	assert.Equal(t, 2, buckets[entity.TaxMedical].Count)
+	assert.Equal(t, "t5", buckets[entity.TaxDonation].Transactions[0].ID)
+	assert.Equal(t, 60450.0, report.TotalIncome)
+	assert.Equal(t, 15500.0, report.TotalDeductions)
+
+	var buf bytes.Buffer
+	assert.NoError(t, export.WriteCSV(&buf, taxSummaryDocument(report)))
+	assert.Contains(t, buf.String(), "DEDUCTION,MEDICAL,醫藥及生育費,2500,2")
+	assert.Contains(t, buf.String(), "DONATION,2024-05-01,FOOD")
+}
+
+func TestTaxMappingsDefaults(t *testing.T) {
+	mappings, err := taxMappings(&taxRepo{}, "user123")
+	assert.NoError(t, err)
+	assert.Len(t, mappings, len(entity.DefaultTaxMappings))
+	assert.Equal(t, "user123", mappings[0].UserID)
+	assert.Empty(t, entity.DefaultTaxMappings[0].UserID)
+
+	s := NewTaxService(&taxRepo{})
+	_, err = s.SaveTaxMappings("user123", []entity.TaxMapping{{Value: "GYM", Bucket: "FITNESS"}})
+	assert.ErrorIs(t, err, ErrInvalidTaxMapping)
+	_, err = s.SaveTaxMappings("user123", []entity.TaxMapping{{Value: "RENT", Bucket: entity.TaxRent}, {Match: "category", Value: "RENT", Bucket: entity.TaxRent}})
+	assert.ErrorIs(t, err, ErrInvalidTaxMapping)
+}
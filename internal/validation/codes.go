@@ -0,0 +1,41 @@
+package validation
+
+// 錯誤碼目錄，說明見 README 的 Error Catalog。錯誤碼一經公開便不再更改，客戶端應依錯誤碼而非訊息判斷
+
+// 回應的錯誤碼
+const (
+	CodeValidationFailed      = "VALIDATION_FAILED"       // 欄位驗證失敗，details 列出每個欄位
+	CodeInvalidRequest        = "INVALID_REQUEST"         // 請求格式或參數不正確
+	CodeNotFound              = "NOT_FOUND"               // 資源不存在或屬於其他用戶
+	CodeConflict              = "CONFLICT"                // 與現有資料衝突
+	CodeIdempotencyKeyReused  = "IDEMPOTENCY_KEY_REUSED"  // 冪等鍵已用於不同內容的請求
+	CodeIdempotencyInProgress = "IDEMPOTENCY_IN_PROGRESS" // 相同冪等鍵的請求仍在處理
+	CodePayloadTooLarge       = "PAYLOAD_TOO_LARGE"       // 請求內容超過上限
+	CodeInternal              = "INTERNAL_ERROR"          // 伺服器錯誤，可稍後重試
+)
+
+// 欄位的錯誤碼
+const (
+	CodeRequired       = "REQUIRED"         // 未提供或為空
+	CodeMustBePositive = "MUST_BE_POSITIVE" // 必須大於零
+	CodeInvalidValue   = "INVALID_VALUE"    // 不是允許的值，或與其他欄位矛盾
+	CodeTooLong        = "TOO_LONG"         // 超過長度上限
+	CodeInvalidFormat  = "INVALID_FORMAT"   // 無法解析，例如日期或數字格式錯誤
+	CodeInvalidType    = "INVALID_TYPE"     // JSON 值的型別不正確
+)
+
+// Response 為 HTTP API 與提交紀錄共用的錯誤格式，Details 只在欄位驗證失敗時提供
+type Response struct {
+	Error   string       `json:"error"`
+	Code    string       `json:"code"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// NewResponse 以錯誤碼與錯誤建立回應，錯誤鏈中有欄位錯誤時錯誤碼為 VALIDATION_FAILED
+func NewResponse(code string, err error) Response {
+	details := Details(err)
+	if details != nil {
+		code = CodeValidationFailed
+	}
+	return Response{Error: err.Error(), Code: code, Details: details}
+}
@@ -0,0 +1,15 @@
+package validation
+
+import "fintrack/internal/entity"
+
+// MaxExternalIDLength 為客戶端外部參照 ID 的最大長度
+const MaxExternalIDLength = 100
+
+// Transaction 檢查交易的共用規則。新增、修改、批次新增與消費者都以此檢查，各自需要的欄位由呼叫端另外檢查
+func Transaction(v *Validator, tx entity.Transaction) {
+	v.Required("UserID", "user ID", tx.UserID)
+	v.Positive("Amount", "amount", tx.Amount)
+	v.RequiredTime("Date", "date", tx.Date)
+	v.OneOf("Type", "type", tx.Type, entity.TypeIncome, entity.TypeExpense)
+	v.MaxLength("ExternalID", "external ID", tx.ExternalID, MaxExternalIDLength)
+}
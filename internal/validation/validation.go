@@ -0,0 +1,144 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// FieldError 表示單一欄位的驗證錯誤，Value 為被拒絕的值
+type FieldError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Field   string      `json:"field"`
+	Value   interface{} `json:"rejected_value"`
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// Errors 表示一次驗證的所有欄位錯誤，順序與檢查順序相同
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Details 取出錯誤鏈中的欄位錯誤，沒有時返回 nil
+func Details(err error) Errors {
+	var errs Errors
+	if errors.As(err, &errs) {
+		return errs
+	}
+	var fe FieldError
+	if errors.As(err, &fe) {
+		return Errors{fe}
+	}
+	return nil
+}
+
+// Validator 收集欄位錯誤，檢查全部完成後以 Err 取得結果
+type Validator struct {
+	errs Errors
+}
+
+// Add 記錄一個欄位錯誤
+func (v *Validator) Add(field, code string, value interface{}, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Code: code, Message: fmt.Sprintf(format, args...), Field: field, Value: value})
+}
+
+// Required 檢查字串欄位不為空，name 為訊息中的欄位名稱
+func (v *Validator) Required(field, name, value string) {
+	if value == "" {
+		v.Add(field, CodeRequired, nil, "%s is required", name)
+	}
+}
+
+// RequiredTime 檢查時間欄位已提供
+func (v *Validator) RequiredTime(field, name string, value time.Time) {
+	if value.IsZero() {
+		v.Add(field, CodeRequired, nil, "%s is required", name)
+	}
+}
+
+// Positive 檢查數值大於零
+func (v *Validator) Positive(field, name string, value float64) {
+	if value <= 0 {
+		v.Add(field, CodeMustBePositive, value, "%s must be greater than zero", name)
+	}
+}
+
+// OneOf 檢查字串為允許的值之一，空字串不檢查
+func (v *Validator) OneOf(field, name, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.Add(field, CodeInvalidValue, value, "%s must be %s", name, Alternatives(allowed...))
+}
+
+// MaxLength 檢查字串的長度不超過上限
+func (v *Validator) MaxLength(field, name, value string, max int) {
+	if len(value) > max {
+		v.Add(field, CodeTooLong, value, "%s must be at most %d characters", name, max)
+	}
+}
+
+// Err 返回收集到的錯誤，沒有錯誤時為 nil
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// Alternatives 將允許的值組成 "A, B or C" 的形式
+func Alternatives(values ...string) string {
+	if len(values) <= 1 {
+		return strings.Join(values, "")
+	}
+	return strings.Join(values[:len(values)-1], ", ") + " or " + values[len(values)-1]
+}
+
+// DecodeError 將 JSON 型別不符的解碼錯誤轉換為欄位錯誤，其他錯誤原樣返回
+func DecodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field == "" {
+		return err
+	}
+	return FieldError{
+		Code:    CodeInvalidType,
+		Message: fmt.Sprintf("%s must be %s, not %s", typeErr.Field, jsonKind(typeErr.Type), typeErr.Value),
+		Field:   typeErr.Field,
+	}
+}
+
+// jsonKind 返回 Go 型別對應的 JSON 值類型
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,63 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fintrack/internal/entity"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransaction(t *testing.T) {
+	var v Validator
+	Transaction(&v, entity.Transaction{UserID: "user123", Amount: 100, Date: time.Now(), Type: entity.TypeIncome})
+	assert.NoError(t, v.Err())
+
+	v = Validator{}
+	Transaction(&v, entity.Transaction{Amount: -5, Type: "TRANSFER", ExternalID: strings.Repeat("x", MaxExternalIDLength+1)})
+	err := fmt.Errorf("invalid transaction: %w", v.Err())
+	assert.Equal(t, "invalid transaction: user ID is required; amount must be greater than zero; date is required; type must be INCOME or EXPENSE; external ID must be at most 100 characters", err.Error())
+
+	details := Details(err)
+	assert.Len(t, details, 5)
+	assert.Equal(t, FieldError{Code: CodeRequired, Message: "user ID is required", Field: "UserID"}, details[0])
+	assert.Equal(t, FieldError{Code: CodeMustBePositive, Message: "amount must be greater than zero", Field: "Amount", Value: -5.0}, details[1])
+	assert.Equal(t, CodeRequired, details[2].Code)
+	assert.Nil(t, details[2].Value)
+	assert.Equal(t, CodeInvalidValue, details[3].Code)
+	assert.Equal(t, CodeTooLong, details[4].Code)
+}
+
+func TestNewResponse(t *testing.T) {
+	resp := NewResponse(CodeNotFound, errors.New("transaction not found"))
+	assert.Equal(t, Response{Error: "transaction not found", Code: CodeNotFound}, resp)
+
+	// 包含欄位錯誤時一律為 VALIDATION_FAILED
+	var v Validator
+	v.Required("UserID", "user ID", "")
+	resp = NewResponse(CodeInvalidRequest, fmt.Errorf("invalid batch item: %w", v.Err()))
+	assert.Equal(t, CodeValidationFailed, resp.Code)
+	data, err := json.Marshal(resp)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"error":"invalid batch item: user ID is required","code":"VALIDATION_FAILED","details":[{"code":"REQUIRED","message":"user ID is required","field":"UserID","rejected_value":null}]}`, string(data))
+}
+
+func TestDecodeError(t *testing.T) {
+	var tx entity.Transaction
+	err := DecodeError(json.Unmarshal([]byte(`{"Amount":"ten"}`), &tx))
+	assert.Equal(t, FieldError{Code: CodeInvalidType, Message: "Amount must be a number, not string", Field: "Amount"}, err)
+
+	syntaxErr := json.Unmarshal([]byte(`{`), &tx)
+	assert.Equal(t, syntaxErr, DecodeError(syntaxErr))
+	assert.Nil(t, Details(syntaxErr))
+}
+
+func TestAlternatives(t *testing.T) {
+	assert.Equal(t, "asc", Alternatives("asc"))
+	assert.Equal(t, "asc or desc", Alternatives("asc", "desc"))
+	assert.Equal(t, "MANUAL, BANK or CREDIT_CARD", Alternatives("MANUAL", "BANK", "CREDIT_CARD"))
+}